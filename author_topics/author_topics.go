@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"strconv"
+	"strings"
+)
+
+var (
+	author_model_file = flag.String("author_model_file", "",
+		"The author-topic model file saved by train-lda --author_corpus")
+	model_file = flag.String("model_file", "",
+		"If specified, the word-topic model file saved along with author_model_file, " +
+		"from which topic names and top words are shown")
+	authors = flag.String("authors", "",
+		"Comma separated author IDs to show; all authors in author_model_file if empty")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	num_top_topics = flag.Int("num_top_topics", 5, "The number of top topics shown per author")
+	num_top_words = flag.Int("num_top_words", 5,
+		"The number of top words shown per topic, if model_file is specified")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*author_model_file) == 0 {
+		fmt.Println("author_model_file must be specified")
+		valid = false
+	}
+	if *topic_prior <= 0 {
+		fmt.Println("topic_prior must be positive")
+		valid = false
+	}
+	if *num_top_topics <= 0 {
+		fmt.Println("num_top_topics must be positive")
+		valid = false
+	}
+	if *num_top_words < 0 {
+		fmt.Println("num_top_words must be non-negative")
+		valid = false
+	}
+	return valid
+}
+
+// Output the top topics of each author, one author per paragraph:
+//
+// author
+//	topic	P(topic|author)	top_word_1 top_word_2 ...
+//	...
+//
+// where topic is its name, if named in model_file, or its index.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+
+	author_model, err := lda.LoadModel(*author_model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *author_model_file + ", due to " + err.String())
+		return
+	}
+	var model *lda.Model
+	if len(*model_file) > 0 {
+		if model, err = lda.LoadModel(*model_file); err != nil {
+			fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+			return
+		}
+		if model.NumTopics() != author_model.NumTopics() {
+			fmt.Printf("model_file has %d topics, but author_model_file has %d topics\n",
+				model.NumTopics(), author_model.NumTopics())
+			return
+		}
+	}
+
+	var author_ids []string
+	if len(*authors) > 0 {
+		author_ids = strings.Split(*authors, ",", -1)
+	} else {
+		author_ids = author_model.Words()
+	}
+
+	for _, author := range author_ids {
+		fmt.Println(author)
+		dist := lda.AuthorTopicDistribution(author_model, author, *topic_prior)
+		for _, t := range dist.TopTopics(*num_top_topics) {
+			fmt.Printf("\t%s\t%.4f", topicName(model, t.Topic), t.Probability)
+			if model != nil && *num_top_words > 0 {
+				words := make([]string, 0)
+				for _, w := range model.TopWords(t.Topic, *num_top_words) {
+					words = append(words, w.Word)
+				}
+				fmt.Printf("\t%s", strings.Join(words, " "))
+			}
+			fmt.Printf("\n")
+		}
+	}
+}
+
+// Returns the name of topic in model, or its index if model is nil or
+// does not name topics.
+func topicName(model *lda.Model, topic int) string {
+	if model != nil && model.TopicNames() != nil {
+		return model.TopicNames()[topic]
+	}
+	return strconv.Itoa(topic)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"strings"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file saved by train-lda")
+	corpus_file = flag.String("corpus_file", "",
+		"If specified, the labeled documents to train a classifier, one per line as a label, " +
+		"a tab, and the text")
+	classifier_file = flag.String("classifier_file", "",
+		"The classifier file saved alongside model_file; model_file.classifier if not specified")
+	num_folds = flag.Int("num_folds", 5,
+		"The number of folds to cross-validate the classifier on corpus_file; 0 to skip")
+	regularizer = flag.Float64("regularizer", 0.01, "The L2 regularizer of classifier weights")
+	train_iterations = flag.Int("train_iterations", 500,
+		"The number of gradient ascent iterations to train the classifier")
+	text = flag.String("text", "", "If specified, the text to classify, words separated by whitespaces")
+	predict_file = flag.String("predict_file", "",
+		"If specified, the documents to classify, one per line")
+	phrase_file = flag.String("phrase_file", "",
+		"If specified, the phrase table saved by train-lda, whose phrases are merged in the " +
+		"documents of corpus_file, text and predict_file")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	burn_in_iterations = flag.Int("burn_in_iterations", 20,
+		"The number of Gibbs sampling iterations for burning in the inference of each document")
+	accumulate_iterations = flag.Int("accumulate_iterations", 10,
+		"The number of Gibbs sampling iterations for accumulating the inferred topic distribution")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 {
+		fmt.Println("model_file must be specified")
+		valid = false
+	}
+	if len(*corpus_file) == 0 && len(*text) == 0 && len(*predict_file) == 0 {
+		fmt.Println("At least one of corpus_file, text and predict_file must be specified")
+		valid = false
+	}
+	if *num_folds < 0 || *num_folds == 1 {
+		fmt.Println("num_folds must be 0 or at least 2")
+		valid = false
+	}
+	if *regularizer < 0 {
+		fmt.Println("regularizer must be non-negative")
+		valid = false
+	}
+	if *train_iterations <= 0 {
+		fmt.Println("train_iterations must be positive")
+		valid = false
+	}
+	if *topic_prior <= 0 {
+		fmt.Println("topic_prior must be positive")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *burn_in_iterations < 0 {
+		fmt.Println("burn_in_iterations must be non-negative")
+		valid = false
+	}
+	if *accumulate_iterations <= 0 {
+		fmt.Println("accumulate_iterations must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Print the predicted label of doc and the probabilities of all labels as
+//
+// label<TAB>label_0:p_0 label_1:p_1 ...
+func printPrediction(classifier *lda.Classifier, sampler *lda.Sampler, doc *lda.Document) {
+	label, probabilities := classifier.PredictDocument(sampler, doc,
+		*burn_in_iterations, *accumulate_iterations)
+	strs := make([]string, len(probabilities))
+	for l, p := range probabilities {
+		strs[l] = fmt.Sprintf("%s:%f", classifier.Labels()[l], p)
+	}
+	fmt.Printf("%s\t%s\n", label, strings.Join(strs, " "))
+}
+
+// Classify documents by their topic distributions inferred by model_file.
+// If corpus_file is specified, a classifier is cross-validated and trained
+// on it, and saved into classifier_file, whose evaluation is printed;
+// otherwise the classifier is loaded from classifier_file.  Then text and
+// the documents of predict_file are classified.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+	if len(*classifier_file) == 0 {
+		*classifier_file = *model_file + ".classifier"
+	}
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+	sampler := lda.NewSampler(*topic_prior, *word_prior, model, nil)
+	var table *lda.PhraseTable
+	if len(*phrase_file) > 0 {
+		if table, err = lda.LoadPhraseTable(*phrase_file); err != nil {
+			fmt.Printf("Error in loading: " + *phrase_file + ", due to " + err.String())
+			return
+		}
+	}
+
+	var classifier *lda.Classifier
+	if len(*corpus_file) > 0 {
+		corpus, labels, err := lda.LoadClassificationCorpus(*corpus_file, model.NumTopics(), table)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+			return
+		}
+		features := make([]lda.Distribution, len(*corpus))
+		for d, doc := range *corpus {
+			features[d] = sampler.InferTopicDistribution(doc,
+				*burn_in_iterations, *accumulate_iterations)
+		}
+		if *num_folds > 0 {
+			evaluation, err := lda.CrossValidateClassifier(features, labels, *num_folds,
+				*regularizer, *train_iterations, nil)
+			if err != nil {
+				fmt.Printf("Cannot cross-validate due to " + err.String())
+				return
+			}
+			fmt.Print(evaluation)
+		}
+		classifier, err = lda.TrainClassifier(features, labels, *regularizer, *train_iterations)
+		if err != nil {
+			fmt.Printf("Cannot train classifier due to " + err.String())
+			return
+		}
+		if err := classifier.SaveClassifier(*classifier_file); err != nil {
+			fmt.Printf("Error in saving: " + *classifier_file + ", due to " + err.String())
+			return
+		}
+	} else {
+		classifier, err = lda.LoadClassifier(*classifier_file)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *classifier_file + ", due to " + err.String())
+			return
+		}
+	}
+	if classifier.NumTopics() != model.NumTopics() {
+		fmt.Printf("The classifier has %d topics; the model has %d topics.\n",
+			classifier.NumTopics(), model.NumTopics())
+		return
+	}
+
+	if len(*text) > 0 {
+		var doc *lda.Document
+		if table != nil {
+			doc, err = table.NewDocument(*text, model.NumTopics())
+		} else {
+			doc, err = lda.NewDocument(*text, model.NumTopics())
+		}
+		if err != nil {
+			fmt.Printf("Cannot create document from text due to " + err.String())
+			return
+		}
+		printPrediction(classifier, sampler, doc)
+	}
+	if len(*predict_file) > 0 {
+		var corpus *lda.Corpus
+		if table != nil {
+			corpus, err = lda.LoadPhrasedCorpus(*predict_file, model.NumTopics(), table)
+		} else {
+			corpus, err = lda.LoadCorpus(*predict_file, model.NumTopics())
+		}
+		if err != nil {
+			fmt.Printf("Error in loading: " + *predict_file + ", due to " + err.String())
+			return
+		}
+		for _, doc := range *corpus {
+			printPrediction(classifier, sampler, doc)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file saved by train-lda")
+	text = flag.String("text", "", "The document to explain, words separated by whitespaces")
+	corpus_file = flag.String("corpus_file", "", "The documents to explain, one per line")
+	phrase_file = flag.String("phrase_file", "",
+		"If specified, the phrase table saved by train-lda, whose phrases are merged in documents")
+	format = flag.String("format", "ansi",
+		"The output format: json (an array of tokens per document), ansi (words colored by " +
+		"topics) or html (a page of words highlighted by topics)")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	burn_in_iterations = flag.Int("burn_in_iterations", 20,
+		"The number of Gibbs sampling iterations for burning in the inference of each document")
+	accumulate_iterations = flag.Int("accumulate_iterations", 10,
+		"The number of Gibbs sampling iterations over which the modal topics are taken")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 {
+		fmt.Println("model_file must be specified")
+		valid = false
+	}
+	if (len(*text) == 0) == (len(*corpus_file) == 0) {
+		fmt.Println("Either text or corpus_file must be specified")
+		valid = false
+	}
+	if *format != "json" && *format != "ansi" && *format != "html" {
+		fmt.Println("format must be json, ansi or html")
+		valid = false
+	}
+	if *topic_prior <= 0 {
+		fmt.Println("topic_prior must be positive")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *burn_in_iterations < 0 {
+		fmt.Println("burn_in_iterations must be non-negative")
+		valid = false
+	}
+	if *accumulate_iterations <= 0 {
+		fmt.Println("accumulate_iterations must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Infer the topics of every token of text, or of the documents in
+// corpus_file, by model_file, and output them in their original order,
+// with their posterior distributions over topics, in format.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+	var table *lda.PhraseTable
+	if len(*phrase_file) > 0 {
+		if table, err = lda.LoadPhraseTable(*phrase_file); err != nil {
+			fmt.Printf("Error in loading: " + *phrase_file + ", due to " + err.String())
+			return
+		}
+	}
+	corpus := lda.NewCorpus()
+	if len(*corpus_file) > 0 {
+		if table != nil {
+			corpus, err = lda.LoadPhrasedCorpus(*corpus_file, model.NumTopics(), table)
+		} else {
+			corpus, err = lda.LoadCorpus(*corpus_file, model.NumTopics())
+		}
+		if err != nil {
+			fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+			return
+		}
+	} else {
+		var doc *lda.Document
+		if table != nil {
+			doc, err = table.NewDocument(*text, model.NumTopics())
+		} else {
+			doc, err = lda.NewDocument(*text, model.NumTopics())
+		}
+		if err != nil {
+			fmt.Printf("Cannot create document from: " + *text + " due to " + err.String())
+			return
+		}
+		*corpus = append(*corpus, doc)
+	}
+
+	sampler := lda.NewSampler(*topic_prior, *word_prior, model, nil)
+	explanations := make([][]lda.TokenTopic, len(*corpus))
+	for d, doc := range *corpus {
+		explanations[d] = sampler.InferTokenTopics(doc, *burn_in_iterations, *accumulate_iterations)
+	}
+	output, err := lda.FormatExplanations(explanations, *format, model.TopicNames())
+	if err != nil {
+		fmt.Printf("Cannot format explanations due to " + err.String())
+		return
+	}
+	fmt.Print(output)
+}
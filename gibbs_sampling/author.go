@@ -0,0 +1,277 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"rand"
+)
+
+// AuthorDocument is a Document written by one or more authors, for the
+// author-topic model (Rosen-Zvi et al., 2004).  Besides a topic, each
+// word occurrence is assigned one of the authors, who is supposed to
+// have chosen the topic.  word_authors runs parallel to doc.wordtopics,
+// and indexes authors.
+type AuthorDocument struct {
+	doc          *Document
+	authors      []string
+	word_authors []int
+}
+
+type AuthorCorpus []*AuthorDocument
+
+// Create an AuthorDocument from text and the IDs of its authors, which
+// must be non-empty, distinct and contain no whitespaces.  All words are
+// assigned the first author and topic 0.
+func NewAuthorDocument(text string, authors []string, num_topics int) (
+	doc *AuthorDocument, err os.Error) {
+	if len(authors) == 0 {
+		return nil, os.NewError("Document has no authors: " + text)
+	}
+	d, err := NewDocument(text, num_topics)
+	if err != nil {
+		return nil, err
+	}
+	doc = &AuthorDocument{d, make([]string, len(authors)), make([]int, d.Length())}
+	copy(doc.authors, authors)
+	return doc, nil
+}
+
+// Returns the underlying Document, whose topic assignments are shared
+// with the AuthorDocument.
+func (doc *AuthorDocument) Document() *Document {
+	return doc.doc
+}
+
+func (doc *AuthorDocument) Authors() []string {
+	return doc.authors
+}
+
+// Assign every word occurrence an author and a topic drawn uniformly at
+// random.  A nil rng uses the global random source.
+func (doc *AuthorDocument) RandomizeAssignments(rng *rand.Rand) {
+	doc.doc.RandomizeTopics(rng)
+	for i := range doc.word_authors {
+		doc.word_authors[i] = randIntn(rng, len(doc.authors))
+	}
+}
+
+// Load a corpus for the author-topic model, where each line has the form
+//
+// author_1,author_2,...<TAB>text
+//
+// Every document must have at least one author.
+func LoadAuthorCorpus(filename string, num_topics int) (corpus *AuthorCorpus, err os.Error) {
+	corpus = &AuthorCorpus{}
+	err = readLines(filename, func(line string) os.Error {
+		metadata, text, err := splitMetadata(line)
+		if err != nil {
+			return err
+		}
+		authors, err := parseLabels(metadata)
+		if err != nil {
+			return err
+		}
+		doc, err := NewAuthorDocument(text, authors, num_topics)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+// Returns the Documents of corpus, sharing topic assignments with it.
+func (corpus *AuthorCorpus) Documents() *Corpus {
+	docs := make(Corpus, len(*corpus))
+	for i, doc := range *corpus {
+		docs[i] = doc.doc
+	}
+	return &docs
+}
+
+// Create an author model, i.e., a Model keyed by authors instead of
+// words, by counting the topics that authors are assigned in corpus.
+// The word-topic model of corpus is CreateModel(num_topics,
+// corpus.Documents()).
+func CreateAuthorModel(num_topics int, corpus *AuthorCorpus) *Model {
+	model := NewModel(num_topics)
+	for _, doc := range *corpus {
+		for iter, _ := NewWordIterator(doc.doc); !iter.Done(); iter.Next() {
+			model.IncrementTopic(doc.authors[doc.word_authors[iter.word_topic_index]],
+				iter.Topic(), 1)
+		}
+	}
+	return model
+}
+
+// Returns the topic distribution of author, i.e., P(topic|author),
+// estimated from author_model with the symmetric Dirichlet prior
+// topic_prior.  An author not in author_model gets the uniform
+// distribution.
+func AuthorTopicDistribution(author_model *Model, author string, topic_prior float64) Distribution {
+	hist := author_model.GetWordTopicHistogram(author)
+	num_topics := author_model.NumTopics()
+	total := 0
+	for _, c := range hist {
+		total += c
+	}
+	dist := NewDistribution(num_topics)
+	for k, c := range hist {
+		dist[k] = (float64(c) + topic_prior) / (float64(total) + float64(num_topics)*topic_prior)
+	}
+	return dist
+}
+
+// AuthorTopicSampler trains the author-topic model by collapsed Gibbs
+// sampling, which jointly samples the author and the topic of each word
+// occurrence.  model counts the topics of words as in Sampler, and
+// author_model counts the topics of authors.
+type AuthorTopicSampler struct {
+	topic_prior        float64
+	word_prior         *WordPrior
+	model              *Model
+	author_model       *Model
+	accum_model        *Model
+	accum_author_model *Model
+	rng                *rand.Rand // nil means the global random source
+}
+
+func NewAuthorTopicSampler(topic_prior float64, word_prior float64,
+	model *Model, author_model *Model,
+	accum_model *Model, accum_author_model *Model) *AuthorTopicSampler {
+	if model.NumTopics() != author_model.NumTopics() {
+		panic(fmt.Sprintf("model has (%d) topics; author_model has (%d) topics.",
+			model.NumTopics(), author_model.NumTopics()))
+	}
+	return &AuthorTopicSampler{topic_prior, NewWordPrior(word_prior, model.NumTopics()),
+		model, author_model, accum_model, accum_author_model, nil}
+}
+
+// Make the sampler draw from rng instead of the global random source.
+func (sampler *AuthorTopicSampler) SetRand(rng *rand.Rand) {
+	sampler.rng = rng
+}
+
+// Returns the (non-normalized) joint distribution of the author and the
+// topic of an occurrence of word in doc, currently assigned author
+// target_author and topic target_topic, which are excluded from the
+// counts.  The probability of author a and topic k is at a*K+k.
+func (sampler *AuthorTopicSampler) generateAuthorTopicDistribution(doc *AuthorDocument,
+	word string, target_author int, target_topic int) Distribution {
+	num_topics := sampler.model.NumTopics()
+	word_histogram := sampler.model.GetWordTopicHistogram(word)
+	global_histogram := sampler.model.GetGlobalTopicHistogram()
+
+	// P(word|topic), the same for all authors.
+	prob_word := NewDistribution(num_topics)
+	for k := range prob_word {
+		adjustment := 0
+		if k == target_topic {
+			adjustment = -1
+		}
+		prob_word[k] = (float64(word_histogram[k]+adjustment) + sampler.word_prior.Get(word, k)) /
+			(float64(global_histogram[k]+adjustment) + sampler.word_prior.Sum(k, sampler.model))
+	}
+
+	distribution := NewDistribution(len(doc.authors) * num_topics)
+	for a, author := range doc.authors {
+		author_histogram := sampler.author_model.GetWordTopicHistogram(author)
+		author_total := 0
+		for _, c := range author_histogram {
+			author_total += c
+		}
+		if a == target_author {
+			author_total--
+		}
+		normalizer := float64(author_total) + float64(num_topics)*sampler.topic_prior
+		for k := range prob_word {
+			c := author_histogram[k]
+			if a == target_author && k == target_topic {
+				c--
+			}
+			distribution[a*num_topics+k] =
+				(float64(c) + sampler.topic_prior) / normalizer * prob_word[k]
+		}
+	}
+	return distribution
+}
+
+func (sampler *AuthorTopicSampler) DocumentGibbsSampling(doc *AuthorDocument) {
+	num_topics := sampler.model.NumTopics()
+	for iter, _ := NewWordIterator(doc.doc); !iter.Done(); iter.Next() {
+		i := iter.word_topic_index
+		old_author := doc.word_authors[i]
+		distribution := sampler.generateAuthorTopicDistribution(
+			doc, iter.Word(), old_author, iter.Topic())
+		sample := GetAccumulativeSampleWithRand(distribution, sampler.rng)
+		if sample == -1 {
+			panic(fmt.Sprintf("Cannot sample from: %v", distribution))
+		}
+		new_author, new_topic := sample/num_topics, sample%num_topics
+		sampler.model.ReassignTopic(iter.Word(), iter.Topic(), new_topic)
+		sampler.author_model.IncrementTopic(doc.authors[old_author], iter.Topic(), -1)
+		sampler.author_model.IncrementTopic(doc.authors[new_author], new_topic, 1)
+		iter.SetTopic(new_topic)
+		doc.word_authors[i] = new_author
+	}
+}
+
+func (sampler *AuthorTopicSampler) CorpusGibbsSampling(corpus *AuthorCorpus, burn_in bool) {
+	for _, doc := range *corpus {
+		sampler.DocumentGibbsSampling(doc)
+	}
+
+	if !burn_in {
+		if sampler.accum_model != nil {
+			sampler.accum_model.AccumulateModel(sampler.model)
+		}
+		if sampler.accum_author_model != nil {
+			sampler.accum_author_model.AccumulateModel(sampler.author_model)
+		}
+	}
+}
+
+// Returns the log-likelihood of doc, where each word is written by one
+// of its authors chosen uniformly at random, i.e.,
+//
+//   P(word|doc) = 1/A sum_author sum_topic P(word|topic) P(topic|author)
+func (sampler *AuthorTopicSampler) DocumentLogLikelihood(doc *AuthorDocument) float64 {
+	num_topics := sampler.model.NumTopics()
+	global_histogram := sampler.model.GetGlobalTopicHistogram()
+
+	// P(topic|doc), averaged over authors.
+	prob_topic := NewDistribution(num_topics)
+	for _, author := range doc.authors {
+		dist := AuthorTopicDistribution(sampler.author_model, author, sampler.topic_prior)
+		for k, p := range dist {
+			prob_topic[k] += p / float64(len(doc.authors))
+		}
+	}
+
+	log_likelihood := 0.0
+	for iter, _ := NewWordIterator(doc.doc); !iter.Done(); iter.Next() {
+		word_histogram := sampler.model.GetWordTopicHistogram(iter.Word())
+		prob_word := 0.0
+		for k := 0; k < num_topics; k++ {
+			prob_word += prob_topic[k] *
+				(float64(word_histogram[k]) + sampler.word_prior.Get(iter.Word(), k)) /
+				(float64(global_histogram[k]) + sampler.word_prior.Sum(k, sampler.model))
+		}
+		log_likelihood += math.Log(prob_word)
+	}
+	return log_likelihood
+}
+
+func (sampler *AuthorTopicSampler) CorpusLogLikelihood(corpus *AuthorCorpus) float64 {
+	total_log_likelihood := 0.0
+	for _, doc := range *corpus {
+		total_log_likelihood += sampler.DocumentLogLikelihood(doc)
+	}
+	return total_log_likelihood
+}
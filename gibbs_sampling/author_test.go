@@ -0,0 +1,85 @@
+package lda
+
+import (
+	"fmt"
+	"rand"
+	"testing"
+)
+
+const kAuthorCorpusFile = "testdata/author_corpus.txt"
+
+func TestLoadAuthorCorpus(t *testing.T) {
+	corpus, err := LoadAuthorCorpus(kAuthorCorpusFile, 2)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kAuthorCorpusFile + " : " + err.String())
+	}
+	if len(*corpus) != 3 {
+		t.Fatalf("Expecting 3 documents, but got %d", len(*corpus))
+	}
+	const kAuthorDocumentGoFmt = "[alice bob] {[apple jagar orange zebra] [0 1 2 3] [0 0 0 0] [0 3 2 1] [0 3 2 1] [4 0] [] []}"
+	doc := (*corpus)[2]
+	if s := fmt.Sprintf("%v %v", doc.Authors(), *doc.Document()); s != kAuthorDocumentGoFmt {
+		t.Errorf("Expecting: " + kAuthorDocumentGoFmt + ", but got: " + s)
+	}
+
+	author_model := CreateAuthorModel(2, corpus)
+	if s := fmt.Sprintf("%v %v", author_model.GetWordTopicHistogram("alice"),
+		author_model.GetWordTopicHistogram("bob")); s != "[8 0] [4 0]" {
+		t.Errorf("Unexpected author model: %s", s)
+	}
+}
+
+func TestAuthorTopicGibbsSampling(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := &AuthorCorpus{}
+	for d := 0; d < 40; d++ {
+		text, authors := "", []string{"alice"}
+		for i := 0; i < 8; i++ {
+			if d%2 == 0 {
+				text += kFruits[rng.Intn(len(kFruits))] + " "
+			} else {
+				text += kAnimals[rng.Intn(len(kAnimals))] + " "
+			}
+		}
+		if d%2 == 1 {
+			authors[0] = "bob"
+		}
+		if d%5 == 0 {
+			authors = append(authors, "carol")
+		}
+		doc, _ := NewAuthorDocument(text, authors, 2)
+		doc.RandomizeAssignments(rng)
+		*corpus = append(*corpus, doc)
+	}
+
+	model := CreateModel(2, corpus.Documents())
+	author_model := CreateAuthorModel(2, corpus)
+	accum_author_model := NewModel(2)
+	sampler := NewAuthorTopicSampler(0.1, 0.01, model, author_model, nil, accum_author_model)
+	sampler.SetRand(rng)
+	for iter := 0; iter < 60; iter++ {
+		sampler.CorpusGibbsSampling(corpus, iter < 50)
+	}
+
+	checkTwoTopicModel(t, model)
+	if fmt.Sprint(author_model.GetGlobalTopicHistogram()) != fmt.Sprint(model.GetGlobalTopicHistogram()) {
+		t.Errorf("Inconsistent topic counts: %v vs. %v",
+			author_model.GetGlobalTopicHistogram(), model.GetGlobalTopicHistogram())
+	}
+	fruit_topic := dominantTopic(model, kFruits[0])
+	alice := AuthorTopicDistribution(accum_author_model, "alice", 0.1)
+	bob := AuthorTopicDistribution(accum_author_model, "bob", 0.1)
+	if alice[fruit_topic] < 0.9 || bob[fruit_topic] > 0.1 {
+		t.Errorf("Unexpected author topics: alice %v, bob %v", alice, bob)
+	}
+	if d := AuthorTopicDistribution(author_model, "dave", 0.1); d[0] != 0.5 {
+		t.Errorf("Expecting uniform topics of an unknown author, but got %v", d)
+	}
+}
+
+func TestTopTopics(t *testing.T) {
+	d := Distribution{0.2, 0.5, 0.1, 0.2}
+	if s := fmt.Sprintf("%v", d.TopTopics(3)); s != "[{1 0.5} {0 0.2} {3 0.2}]" {
+		t.Errorf("Unexpected top topics: %s", s)
+	}
+}
@@ -0,0 +1,244 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"rand"
+	"strings"
+)
+
+// A biterm is an unordered pair of words co-occurring in a short text.
+type biterm struct {
+	word1 string
+	word2 string
+}
+
+// Load short texts, e.g., tweets or titles, one per line with words
+// separated by whitespaces.  Unlike LoadCorpus, texts of a single word
+// are accepted; they have no biterms, but their topics can be inferred.
+func LoadShortTexts(filename string) (texts [][]string, err os.Error) {
+	texts = make([][]string, 0)
+	err = readLines(filename, func(line string) os.Error {
+		if words := strings.Fields(line); len(words) > 0 {
+			texts = append(texts, words)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+// Returns the biterms of words, i.e., all pairs of words at most window
+// positions apart, or all pairs if window is 0.  Pairs of a word with
+// itself are included.
+func extractBiterms(words []string, window int) []biterm {
+	biterms := make([]biterm, 0)
+	for i := range words {
+		for j := i + 1; j < len(words) && (window <= 0 || j-i <= window); j++ {
+			biterms = append(biterms, biterm{words[i], words[j]})
+		}
+	}
+	return biterms
+}
+
+// BitermSampler trains the biterm topic model (BTM; Yan, Guo, Lan and
+// Cheng, 2013) for short texts by collapsed Gibbs sampling.  Instead of
+// documents, it models the biterms of the whole corpus: each biterm is
+// assigned a topic drawn from a corpus-wide topic distribution, and both
+// of its words are drawn from that topic, which overcomes the sparsity of
+// word co-occurrences within short texts.
+//
+// The learned topics are in a Model, counting the words of biterms
+// assigned each topic; its global histogram is thus twice the number of
+// biterms of each topic.
+type BitermSampler struct {
+	topic_prior float64
+	word_prior  float64
+	num_words   int // The size of the vocabulary of biterms.
+	biterms     []biterm
+	topics      []int // topics[i] is the topic of biterms[i].
+	model       *Model
+	accum_model *Model
+	rng         *rand.Rand // nil means the global random source
+}
+
+// Create a BitermSampler over the biterms of texts, with words at most
+// window positions apart, or any words if window is 0, and assign them
+// random topics from rng, or the global random source if rng is nil.
+// Models of samples after burn-in are accumulated into accum_model, if
+// not nil.
+func NewBitermSampler(num_topics int, topic_prior float64, word_prior float64,
+	texts [][]string, window int, accum_model *Model, rng *rand.Rand) *BitermSampler {
+	if num_topics <= 1 {
+		panic("num_topics must be >= 2")
+	}
+	sampler := &BitermSampler{
+		topic_prior: topic_prior,
+		word_prior:  word_prior,
+		biterms:     make([]biterm, 0),
+		model:       NewModel(num_topics),
+		accum_model: accum_model,
+		rng:         rng,
+	}
+	for _, words := range texts {
+		sampler.biterms = append(sampler.biterms, extractBiterms(words, window)...)
+	}
+	sampler.topics = make([]int, len(sampler.biterms))
+	for i, b := range sampler.biterms {
+		k := randIntn(rng, num_topics)
+		sampler.topics[i] = k
+		sampler.model.IncrementTopic(b.word1, k, 1)
+		sampler.model.IncrementTopic(b.word2, k, 1)
+	}
+	sampler.num_words = sampler.model.NumWords()
+	return sampler
+}
+
+func (sampler *BitermSampler) NumBiterms() int {
+	return len(sampler.biterms)
+}
+
+// Returns the model of the current sample.  It is modified by Iterate.
+func (sampler *BitermSampler) Model() *Model {
+	return sampler.model
+}
+
+// Run a Gibbs sampling sweep over all biterms, and accumulate the model
+// unless burn_in.
+func (sampler *BitermSampler) Iterate(burn_in bool) {
+	distribution := NewDistribution(sampler.model.NumTopics())
+	for i, b := range sampler.biterms {
+		old_topic := sampler.topics[i]
+		sampler.model.IncrementTopic(b.word1, old_topic, -1)
+		sampler.model.IncrementTopic(b.word2, old_topic, -1)
+
+		sampler.bitermTopicDistribution(b, distribution)
+		new_topic := GetAccumulativeSampleWithRand(distribution, sampler.rng)
+		if new_topic == -1 {
+			panic(fmt.Sprintf("Cannot sample from: %v", distribution))
+		}
+		sampler.topics[i] = new_topic
+		sampler.model.IncrementTopic(b.word1, new_topic, 1)
+		sampler.model.IncrementTopic(b.word2, new_topic, 1)
+	}
+
+	if sampler.accum_model != nil && !burn_in {
+		sampler.accum_model.AccumulateModel(sampler.model)
+	}
+}
+
+// Set distribution to the unnormalized probabilities of topics of biterm
+// b given the current sample without b.  Drawing both words of b from a
+// topic, the second word also counts the first if they are the same.
+func (sampler *BitermSampler) bitermTopicDistribution(b biterm, distribution Distribution) {
+	v := float64(sampler.num_words)
+	hist1 := sampler.model.GetWordTopicHistogram(b.word1)
+	hist2 := sampler.model.GetWordTopicHistogram(b.word2)
+	global := sampler.model.GetGlobalTopicHistogram()
+	same := 0.0
+	if b.word1 == b.word2 {
+		same = 1
+	}
+	for k := range distribution {
+		// global[k] counts two words per biterm.
+		n := float64(global[k])
+		distribution[k] = (n/2 + sampler.topic_prior) *
+			(float64(hist1[k]) + sampler.word_prior) *
+			(float64(hist2[k]) + same + sampler.word_prior) /
+			((n + v*sampler.word_prior) * (n + 1 + v*sampler.word_prior))
+	}
+}
+
+// Returns the log-likelihood of all biterms given the current sample.
+func (sampler *BitermSampler) BitermLogLikelihood() float64 {
+	topic_distribution, word_given_topic := bitermDistributions(
+		sampler.model, sampler.topic_prior, sampler.word_prior)
+	log_likelihood := 0.0
+	for _, b := range sampler.biterms {
+		p1, p2 := word_given_topic(b.word1), word_given_topic(b.word2)
+		prob := 0.0
+		for k, p := range topic_distribution {
+			prob += p * p1[k] * p2[k]
+		}
+		log_likelihood += math.Log(prob)
+	}
+	return log_likelihood
+}
+
+// Infer the topic distribution of a short text from a model trained by
+// BitermSampler, as the average of P(topic|biterm) over the biterms of
+// words, at most window positions apart.  A text of a single word gets
+// P(topic|word).  Words not in model are ignored, and a text without
+// known words gets the corpus-wide topic distribution.
+func InferBitermTopicDistribution(model *Model, words []string, window int,
+	topic_prior float64, word_prior float64) Distribution {
+	topic_distribution, word_given_topic := bitermDistributions(model, topic_prior, word_prior)
+	known := make([]string, 0, len(words))
+	for _, w := range words {
+		if _, present := model.topic_histograms[w]; present {
+			known = append(known, w)
+		}
+	}
+
+	result := NewDistribution(model.NumTopics())
+	if len(known) == 0 {
+		copy(result, topic_distribution)
+		return result
+	}
+	biterms := extractBiterms(known, window)
+	if len(biterms) == 0 {
+		// A single known word: P(topic|word) is proportional to
+		// P(topic) P(word|topic).
+		p := word_given_topic(known[0])
+		for k := range result {
+			result[k] = topic_distribution[k] * p[k]
+		}
+		sum := result.Sum()
+		for k := range result {
+			result[k] /= sum
+		}
+		return result
+	}
+	posterior := NewDistribution(model.NumTopics())
+	for _, b := range biterms {
+		p1, p2 := word_given_topic(b.word1), word_given_topic(b.word2)
+		for k, p := range topic_distribution {
+			posterior[k] = p * p1[k] * p2[k]
+		}
+		sum := posterior.Sum()
+		for k := range result {
+			result[k] += posterior[k] / sum / float64(len(biterms))
+		}
+	}
+	return result
+}
+
+// Returns the corpus-wide topic distribution of biterms in model, and a
+// function returning P(word|topic) by topic.
+func bitermDistributions(model *Model, topic_prior float64, word_prior float64) (
+	Distribution, func(word string) Distribution) {
+	num_topics := model.NumTopics()
+	global := model.GetGlobalTopicHistogram()
+	num_biterms := 0.0
+	for _, c := range global {
+		num_biterms += float64(c) / 2
+	}
+	topic_distribution := NewDistribution(num_topics)
+	for k, c := range global {
+		topic_distribution[k] = (float64(c)/2 + topic_prior) /
+			(num_biterms + float64(num_topics)*topic_prior)
+	}
+	v := float64(model.NumWords())
+	word_given_topic := func(word string) Distribution {
+		hist := model.GetWordTopicHistogram(word)
+		d := NewDistribution(num_topics)
+		for k, c := range hist {
+			d[k] = (float64(c) + word_prior) / (float64(global[k]) + v*word_prior)
+		}
+		return d
+	}
+	return topic_distribution, word_given_topic
+}
@@ -0,0 +1,110 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"rand"
+	"testing"
+)
+
+const kShortTextsFile = "testdata/short_texts.txt"
+
+func TestLoadShortTexts(t *testing.T) {
+	texts, err := LoadShortTexts(kShortTextsFile)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kShortTextsFile + " : " + err.String())
+	}
+	if s := fmt.Sprintf("%v", texts); s != "[[apple orange] [zebra] [monky zebra jagar]]" {
+		t.Errorf("Unexpected texts: %s", s)
+	}
+}
+
+func TestExtractBiterms(t *testing.T) {
+	words := []string{"a", "b", "c", "a"}
+	if s := fmt.Sprintf("%v", extractBiterms(words, 0)); s != "[{a b} {a c} {a a} {b c} {b a} {c a}]" {
+		t.Errorf("Unexpected biterms: %s", s)
+	}
+	if s := fmt.Sprintf("%v", extractBiterms(words, 1)); s != "[{a b} {b c} {c a}]" {
+		t.Errorf("Unexpected biterms within window 1: %s", s)
+	}
+	if b := extractBiterms(words[0:1], 0); len(b) != 0 {
+		t.Errorf("Expecting no biterms of a single word, but got %v", b)
+	}
+}
+
+func TestBitermSampler(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	texts := make([][]string, 0)
+	for d := 0; d < 60; d++ {
+		vocabulary := kFruits
+		if d%2 == 1 {
+			vocabulary = kAnimals
+		}
+		words := make([]string, 2+d%3)
+		for i := range words {
+			words[i] = vocabulary[rng.Intn(len(vocabulary))]
+		}
+		texts = append(texts, words)
+	}
+
+	accum_model := NewModel(2)
+	sampler := NewBitermSampler(2, 0.5, 0.01, texts, 0, accum_model, rng)
+	for iter := 0; iter < 50; iter++ {
+		sampler.Iterate(iter < 40)
+	}
+	checkTwoTopicModel(t, sampler.Model())
+	num_words := 0
+	for _, c := range sampler.Model().GetGlobalTopicHistogram() {
+		num_words += c
+	}
+	if num_words != 2*sampler.NumBiterms() {
+		t.Errorf("Expecting two words per biterm: %v", sampler.Model().GetGlobalTopicHistogram())
+	}
+	accum_model.Scale(0.1)
+	checkTwoTopicModel(t, accum_model)
+
+	fruit_topic := dominantTopic(accum_model, kFruits[0])
+	for _, words := range [][]string{{"apple"}, {"apple", "orange", "unknown"}} {
+		d := InferBitermTopicDistribution(accum_model, words, 0, 0.5, 0.01)
+		if !d.IsValid() || d[fruit_topic] < 0.9 {
+			t.Errorf("Unexpected topic distribution of %v: %v", words, d)
+		}
+	}
+	if d := InferBitermTopicDistribution(accum_model, []string{"unknown"}, 0, 0.5, 0.01); !d.IsValid() {
+		t.Errorf("Invalid topic distribution of unknown words: %v", d)
+	}
+}
+
+func TestBitermTopicDistributionOfRepeatedWord(t *testing.T) {
+	texts := [][]string{{"a", "b"}, {"a", "a"}, {"b", "c"}}
+	sampler := NewBitermSampler(2, 0.5, 0.1, texts, 0, nil, rand.New(rand.NewSource(1)))
+	hist := sampler.Model().GetWordTopicHistogram("a")
+	global := sampler.Model().GetGlobalTopicHistogram()
+	distribution := NewDistribution(2)
+	sampler.bitermTopicDistribution(biterm{"a", "a"}, distribution)
+	for k, p := range distribution {
+		n, c := float64(global[k]), float64(hist[k])
+		expected := (n/2 + 0.5) * (c + 0.1) * (c + 1 + 0.1) / ((n + 0.3) * (n + 1 + 0.3))
+		if math.Fabs(p-expected) > 1e-12 {
+			t.Errorf("Expecting %f of topic %d, but got %f", expected, k, p)
+		}
+	}
+}
+
+func TestInferBitermTopicDistributionOfSingleWord(t *testing.T) {
+	model := NewModel(2)
+	model.IncrementTopic("apple", 0, 6)
+	model.IncrementTopic("apple", 1, 2)
+	model.IncrementTopic("zebra", 1, 4)
+	// P(topic) = (3.5, 3.5) / 7; P(apple|topic) = (6.5 / 7, 2.5 / 7).
+	d := InferBitermTopicDistribution(model, []string{"apple", "unknown"}, 0, 0.5, 0.5)
+	if math.Fabs(d[0]-6.5/9) > 1e-12 || math.Fabs(d[1]-2.5/9) > 1e-12 {
+		t.Errorf("Expecting P(topic|apple) [%f %f], but got %v", 6.5/9, 2.5/9, d)
+	}
+	// The biterm of apple twice draws apple from a topic twice.
+	d = InferBitermTopicDistribution(model, []string{"apple", "apple"}, 0, 0.5, 0.5)
+	expected := 6.5 * 6.5 / (6.5*6.5 + 2.5*2.5)
+	if math.Fabs(d[0]-expected) > 1e-12 || math.Fabs(d[0]+d[1]-1) > 1e-12 {
+		t.Errorf("Expecting %f of topic 0, but got %v", expected, d)
+	}
+}
@@ -0,0 +1,404 @@
+package lda
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The first field of the lines of labels in a classifier file.
+const kClassifierLabelsKeyword = "labels"
+
+// Classifier predicts labels of documents from their topic distributions
+// by multinomial logistic regression, i.e., P(label c|theta) is
+// proportional to exp(w_c . theta + b_c).
+type Classifier struct {
+	labels  []string    // Sorted.
+	weights [][]float64 // [label][topic], followed by the bias.
+}
+
+func (c *Classifier) Labels() []string {
+	return c.labels
+}
+
+func (c *Classifier) NumTopics() int {
+	return len(c.weights[0]) - 1
+}
+
+// Returns the distribution over labels of a document of topic
+// distribution theta.
+func (c *Classifier) probabilities(theta Distribution) Distribution {
+	if len(theta) != c.NumTopics() {
+		panic(fmt.Sprintf("theta has (%d) topics; classifier has (%d) topics.",
+			len(theta), c.NumTopics()))
+	}
+	p := NewDistribution(len(c.labels))
+	max_score := math.Inf(-1)
+	for l, w := range c.weights {
+		p[l] = w[len(theta)]
+		for k, t := range theta {
+			p[l] += w[k] * t
+		}
+		max_score = math.Fmax(max_score, p[l])
+	}
+	sum := 0.0
+	for l := range p {
+		p[l] = math.Exp(p[l] - max_score)
+		sum += p[l]
+	}
+	for l := range p {
+		p[l] /= sum
+	}
+	return p
+}
+
+// Returns the most probable label of a document of topic distribution
+// theta, and the distribution over labels, in the order of Labels().
+func (c *Classifier) Predict(theta Distribution) (label string, probabilities Distribution) {
+	probabilities = c.probabilities(theta)
+	best := 0
+	for l, p := range probabilities {
+		if p > probabilities[best] {
+			best = l
+		}
+	}
+	return c.labels[best], probabilities
+}
+
+// Predict the label of doc from its topic distribution, inferred as by
+// Sampler.InferTopicDistribution.
+func (c *Classifier) PredictDocument(sampler *Sampler, doc *Document,
+	burn_in_iterations int, accumulate_iterations int) (label string, probabilities Distribution) {
+	return c.Predict(sampler.InferTopicDistribution(doc, burn_in_iterations, accumulate_iterations))
+}
+
+// Train a classifier of labels of documents from their topic
+// distributions features, by maximizing the average log-likelihood minus
+// regularizer / 2 times the squared L2 norm of weights (not biases), by
+// iterations iterations of gradient ascent.  There must be at least two
+// distinct labels.
+func TrainClassifier(features []Distribution, labels []string, regularizer float64,
+	iterations int) (*Classifier, os.Error) {
+	if len(features) != len(labels) {
+		panic(fmt.Sprintf("%d features for %d labels", len(features), len(labels)))
+	}
+	if len(features) == 0 {
+		return nil, os.NewError("No documents to train a classifier")
+	}
+	if regularizer < 0 {
+		return nil, os.NewError("The regularizer must be non-negative")
+	}
+	num_topics := len(features[0])
+	index := make(map[string]int)
+	for d, label := range labels {
+		if len(features[d]) != num_topics {
+			return nil, os.NewError(fmt.Sprintf("Document %d has %d topics; expecting %d",
+				d, len(features[d]), num_topics))
+		}
+		index[label] = 0
+	}
+	if len(index) < 2 {
+		return nil, os.NewError("At least 2 distinct labels are required")
+	}
+	c := &Classifier{make([]string, 0, len(index)), make([][]float64, len(index))}
+	for label := range index {
+		c.labels = append(c.labels, label)
+	}
+	sort.SortStrings(c.labels)
+	for l, label := range c.labels {
+		index[label] = l
+		c.weights[l] = make([]float64, num_topics+1)
+	}
+
+	// As topic distributions and the bias feature have squared norms of at
+	// most 2, the Hessian of the average log-likelihood is bounded by 1, so
+	// that this step size always increases the objective.
+	step := 1 / (1 + regularizer)
+	gradient := make([][]float64, len(c.labels))
+	for l := range gradient {
+		gradient[l] = make([]float64, num_topics+1)
+	}
+	for iter := 0; iter < iterations; iter++ {
+		for l, w := range c.weights {
+			for k := 0; k < num_topics; k++ {
+				gradient[l][k] = -regularizer * w[k]
+			}
+			gradient[l][num_topics] = 0
+		}
+		for d, theta := range features {
+			for l, p := range c.probabilities(theta) {
+				residual := -p
+				if l == index[labels[d]] {
+					residual += 1
+				}
+				residual /= float64(len(features))
+				for k, t := range theta {
+					gradient[l][k] += residual * t
+				}
+				gradient[l][num_topics] += residual
+			}
+		}
+		change := 0.0
+		for l, w := range c.weights {
+			for k := range w {
+				w[k] += step * gradient[l][k]
+				change += math.Fabs(step * gradient[l][k])
+			}
+		}
+		if change < 1e-10 {
+			break
+		}
+	}
+	return c, nil
+}
+
+// The confusion matrix of predictions of labels, and metrics of it.
+type Evaluation struct {
+	labels    []string
+	index     map[string]int
+	confusion [][]int // [true label][predicted label]
+}
+
+// Create an empty evaluation of labels.
+func NewEvaluation(labels []string) *Evaluation {
+	e := &Evaluation{labels, make(map[string]int), make([][]int, len(labels))}
+	for l, label := range labels {
+		e.index[label] = l
+		e.confusion[l] = make([]int, len(labels))
+	}
+	return e
+}
+
+func (e *Evaluation) Labels() []string {
+	return e.labels
+}
+
+// Returns the confusion matrix, where element [i][j] is the number of
+// documents of label i predicted as label j.
+func (e *Evaluation) ConfusionMatrix() [][]int {
+	return e.confusion
+}
+
+// Count a document of label truth predicted as label predicted.  Both
+// labels must be of the evaluation.
+func (e *Evaluation) Add(truth string, predicted string) {
+	i, ok1 := e.index[truth]
+	j, ok2 := e.index[predicted]
+	if !ok1 || !ok2 {
+		panic(fmt.Sprintf("Unknown label %s or %s", truth, predicted))
+	}
+	e.confusion[i][j]++
+}
+
+func (e *Evaluation) NumDocuments() int {
+	total := 0
+	for _, row := range e.confusion {
+		for _, c := range row {
+			total += c
+		}
+	}
+	return total
+}
+
+// Returns the proportion of correctly predicted documents.
+func (e *Evaluation) Accuracy() float64 {
+	if e.NumDocuments() == 0 {
+		return 0
+	}
+	correct := 0
+	for l := range e.confusion {
+		correct += e.confusion[l][l]
+	}
+	return float64(correct) / float64(e.NumDocuments())
+}
+
+// Returns the F1 scores of labels averaged with equal weights.  The F1
+// score of a label that is neither true nor predicted for any document is
+// 0.
+func (e *Evaluation) MacroF1() float64 {
+	if len(e.labels) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for l := range e.labels {
+		true_positives, positives, predicted := e.confusion[l][l], 0, 0
+		for m := range e.labels {
+			positives += e.confusion[l][m]
+			predicted += e.confusion[m][l]
+		}
+		if positives+predicted > 0 {
+			sum += 2 * float64(true_positives) / float64(positives+predicted)
+		}
+	}
+	return sum / float64(len(e.labels))
+}
+
+// Returns accuracy, macro F1 and the confusion matrix, whose rows are
+// true labels and columns predicted ones, as lines of text.
+func (e *Evaluation) String() string {
+	result := fmt.Sprintf("documents %d\naccuracy %f\nmacro_f1 %f\n",
+		e.NumDocuments(), e.Accuracy(), e.MacroF1())
+	result += "true\\predicted"
+	for _, label := range e.labels {
+		result += "\t" + label
+	}
+	result += "\n"
+	for l, row := range e.confusion {
+		result += e.labels[l]
+		for _, c := range row {
+			result += fmt.Sprintf("\t%d", c)
+		}
+		result += "\n"
+	}
+	return result
+}
+
+// Evaluate classifiers trained by TrainClassifier by num_folds-fold
+// cross-validation: documents are randomly divided into num_folds folds,
+// and the labels of every fold are predicted by a classifier trained on
+// the others.  A nil rng uses the global random source.
+func CrossValidateClassifier(features []Distribution, labels []string, num_folds int,
+	regularizer float64, iterations int, rng *rand.Rand) (*Evaluation, os.Error) {
+	if len(features) != len(labels) {
+		panic(fmt.Sprintf("%d features for %d labels", len(features), len(labels)))
+	}
+	if num_folds < 2 || num_folds > len(features) {
+		return nil, os.NewError(fmt.Sprintf("The number of folds must be in [2, %d]",
+			len(features)))
+	}
+	distinct := make(map[string]bool)
+	all_labels := make([]string, 0)
+	for _, label := range labels {
+		if !distinct[label] {
+			distinct[label] = true
+			all_labels = append(all_labels, label)
+		}
+	}
+	sort.SortStrings(all_labels)
+	evaluation := NewEvaluation(all_labels)
+
+	var order []int
+	if rng == nil {
+		order = rand.Perm(len(features))
+	} else {
+		order = rng.Perm(len(features))
+	}
+	for fold := 0; fold < num_folds; fold++ {
+		train_features := make([]Distribution, 0, len(features))
+		train_labels := make([]string, 0, len(features))
+		test := make([]int, 0)
+		for i, d := range order {
+			if i%num_folds == fold {
+				test = append(test, d)
+			} else {
+				train_features = append(train_features, features[d])
+				train_labels = append(train_labels, labels[d])
+			}
+		}
+		c, err := TrainClassifier(train_features, train_labels, regularizer, iterations)
+		if err != nil {
+			return nil, os.NewError(fmt.Sprintf("Cannot train fold %d due to %s",
+				fold, err.String()))
+		}
+		for _, d := range test {
+			predicted, _ := c.Predict(features[d])
+			evaluation.Add(labels[d], predicted)
+		}
+	}
+	return evaluation, nil
+}
+
+// Load a corpus for classification, where each line has the form
+//
+// label<TAB>text
+//
+// and label contains no whitespaces, with phrases merged by table if not
+// nil.  Returns the corpus and the labels of its documents.
+func LoadClassificationCorpus(filename string, num_topics int, table *PhraseTable) (
+	corpus *Corpus, labels []string, err os.Error) {
+	corpus = NewCorpus()
+	labels = make([]string, 0)
+	err = readLines(filename, func(line string) os.Error {
+		label, text, err := splitMetadata(line)
+		if err != nil {
+			return err
+		}
+		label = strings.TrimSpace(label)
+		if len(label) == 0 || len(strings.Fields(label)) != 1 {
+			return os.NewError("Invalid label: " + line)
+		}
+		doc, err := newPhrasedDocument(text, num_topics, table)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		labels = append(labels, label)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return corpus, labels, nil
+}
+
+// Load a classifier saved by SaveClassifier.
+func LoadClassifier(filename string) (c *Classifier, err os.Error) {
+	err = readLines(filename, func(line string) os.Error {
+		fields := strings.Fields(line)
+		if c == nil {
+			if len(fields) < 3 || fields[0] != kClassifierLabelsKeyword {
+				return os.NewError("Invalid classifier header: " + line)
+			}
+			c = &Classifier{fields[1:], make([][]float64, 0, len(fields)-1)}
+			return nil
+		}
+		if len(c.weights) == len(c.labels) {
+			return os.NewError("Too many weights: " + line)
+		}
+		if len(fields) < 2 || (len(c.weights) > 0 && len(fields) != len(c.weights[0])) {
+			return os.NewError("Invalid weights: " + line)
+		}
+		w := make([]float64, len(fields))
+		for k, f := range fields {
+			if w[k], err = strconv.Atof64(f); err != nil {
+				return os.NewError("Invalid weight: " + line)
+			}
+		}
+		c.weights = append(c.weights, w)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || len(c.weights) != len(c.labels) {
+		return nil, os.NewError("Invalid classifier file: " + filename)
+	}
+	return c, nil
+}
+
+// Save the classifier in the format of
+//
+// labels label_0 label_1 ...
+// w_00 w_01 ... w_0{K-1} b_0
+// w_10 w_11 ... w_1{K-1} b_1
+// ...
+//
+// with a line of weights per label.
+func (c *Classifier) SaveClassifier(filename string) os.Error {
+	return writeFile(filename, func(writer *bufio.Writer) {
+		fmt.Fprintf(writer, "%s %s\n", kClassifierLabelsKeyword, strings.Join(c.labels, " "))
+		for _, w := range c.weights {
+			strs := make([]string, len(w))
+			for k, x := range w {
+				strs[k] = fmt.Sprintf("%g", x)
+			}
+			fmt.Fprintf(writer, "%s\n", strings.Join(strs, " "))
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"rand"
+	"testing"
+)
+
+const kClassificationCorpusFile = "testdata/classification_corpus.txt"
+const kTmpClassifierFile = "/tmp/classifier.txt"
+
+// Returns noisy topic distributions of three topics, where documents of
+// label "k<i>" are mostly of topic i.
+func newLabeledDistributions(rng *rand.Rand, num_docs int) ([]Distribution, []string) {
+	features := make([]Distribution, num_docs)
+	labels := make([]string, num_docs)
+	for d := range features {
+		features[d] = randomTopicDistribution(rng, 3)
+		features[d][d%3] += 2
+		for k := range features[d] {
+			features[d][k] /= 3
+		}
+		labels[d] = fmt.Sprintf("k%d", d%3)
+	}
+	return features, labels
+}
+
+func TestLoadClassificationCorpus(t *testing.T) {
+	corpus, labels, err := LoadClassificationCorpus(kClassificationCorpusFile, 2, nil)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kClassificationCorpusFile + " : " + err.String())
+	}
+	if len(*corpus) != 2 || fmt.Sprintf("%v", labels) != "[fruit animal]" {
+		t.Errorf("Unexpected corpus %v with labels %v", *corpus, labels)
+	}
+
+	table := NewPhraseTable()
+	table.Add([]string{"apple", "banana"}, 1)
+	corpus, _, err = LoadClassificationCorpus(kClassificationCorpusFile, 2, table)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kClassificationCorpusFile + " : " + err.String())
+	}
+	if s := fmt.Sprint((*corpus)[0].unique_words); s != "[apple apple_banana orange]" {
+		t.Errorf("Expecting words with phrases merged, but got %s", s)
+	}
+}
+
+func TestTrainClassifier(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	features, labels := newLabeledDistributions(rng, 60)
+	if _, err := TrainClassifier(features[0:1], labels[0:1], 0.01, 100); err == nil {
+		t.Errorf("Expecting an error for a single label")
+	}
+	c, err := TrainClassifier(features, labels, 0.01, 500)
+	if err != nil {
+		t.Fatalf("Cannot train classifier due to " + err.String())
+	}
+	if fmt.Sprintf("%v", c.Labels()) != "[k0 k1 k2]" {
+		t.Errorf("Expecting labels [k0 k1 k2], but got %v", c.Labels())
+	}
+	for k := 0; k < 3; k++ {
+		theta := NewDistribution(3)
+		theta[k] = 1
+		label, probabilities := c.Predict(theta)
+		if label != fmt.Sprintf("k%d", k) || probabilities[k] < 0.5 {
+			t.Errorf("Expecting k%d for %v, but got %s with %v", k, theta, label, probabilities)
+		}
+		if math.Fabs(probabilities.Sum()-1) > 1e-9 {
+			t.Errorf("Probabilities do not sum to 1: %v", probabilities)
+		}
+	}
+}
+
+func TestEvaluation(t *testing.T) {
+	e := NewEvaluation([]string{"a", "b", "c"})
+	e.Add("a", "a")
+	e.Add("a", "b")
+	e.Add("b", "b")
+	e.Add("b", "b")
+	if e.NumDocuments() != 4 || e.Accuracy() != 0.75 {
+		t.Errorf("Expecting 4 documents of accuracy 0.75, but got %d of %f",
+			e.NumDocuments(), e.Accuracy())
+	}
+	// F1 of a is 2/3, of b 4/5, and of c 0.
+	if f1 := e.MacroF1(); math.Fabs(f1-(2.0/3+0.8)/3) > 1e-9 {
+		t.Errorf("Unexpected macro F1: %f", f1)
+	}
+	if fmt.Sprintf("%v", e.ConfusionMatrix()) != "[[1 1 0] [0 2 0] [0 0 0]]" {
+		t.Errorf("Unexpected confusion matrix: %v", e.ConfusionMatrix())
+	}
+}
+
+func TestCrossValidateClassifier(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	features, labels := newLabeledDistributions(rng, 60)
+	if _, err := CrossValidateClassifier(features, labels, 1, 0.01, 100, rng); err == nil {
+		t.Errorf("Expecting an error for a single fold")
+	}
+	e, err := CrossValidateClassifier(features, labels, 5, 0.01, 500, rng)
+	if err != nil {
+		t.Fatalf("Cannot cross-validate due to " + err.String())
+	}
+	if e.NumDocuments() != 60 {
+		t.Errorf("Expecting 60 evaluated documents, but got %d", e.NumDocuments())
+	}
+	if e.Accuracy() < 0.9 || e.MacroF1() < 0.9 {
+		t.Errorf("Accuracy %f or macro F1 %f is too low:\n%s", e.Accuracy(), e.MacroF1(), e)
+	}
+}
+
+func TestSaveLoadClassifier(t *testing.T) {
+	c := &Classifier{[]string{"animal", "fruit"}, [][]float64{{1.5, -2, 0.25}, {-1, 2, 0}}}
+	if err := c.SaveClassifier(kTmpClassifierFile); err != nil {
+		t.Fatalf("Cannot write to: " + kTmpClassifierFile + " due to " + err.String())
+	}
+	loaded, err := LoadClassifier(kTmpClassifierFile)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kTmpClassifierFile + " : " + err.String())
+	}
+	if fmt.Sprintf("%v", *loaded) != fmt.Sprintf("%v", *c) {
+		t.Errorf("Expecting %v, but got %v", *c, *loaded)
+	}
+}
+
+func TestClassifierPredictDocument(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 40, 2)
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(rng)
+	}
+	model := CreateModel(2, corpus)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	sampler.SetRand(rng)
+	for iter := 0; iter < 30; iter++ {
+		sampler.CorpusGibbsSampling(corpus, true, true)
+	}
+	features := make([]Distribution, len(*corpus))
+	labels := make([]string, len(*corpus))
+	for d, doc := range *corpus {
+		features[d] = sampler.InferTopicDistribution(doc, 10, 10)
+		labels[d] = []string{"fruit", "animal"}[d%2]
+	}
+	c, err := TrainClassifier(features, labels, 0.01, 500)
+	if err != nil {
+		t.Fatalf("Cannot train classifier due to " + err.String())
+	}
+	for i, text := range []string{"apple orange banana", "zebra lion tiger"} {
+		doc, _ := NewDocument(text, 2)
+		if label, _ := c.PredictDocument(sampler, doc, 10, 10); label != labels[i] {
+			t.Errorf("Expecting %s for %s, but got %s", labels[i], text, label)
+		}
+	}
+}
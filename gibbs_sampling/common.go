@@ -1,10 +1,29 @@
 package lda
 
-import "rand"
+import (
+	"rand"
+	"sort"
+)
 
 type Distribution []float64
 type Histogram    []int
 
+type TopicProbability struct {
+	Topic       int
+	Probability float64
+}
+
+type topicProbabilityArray []TopicProbability
+
+func (a topicProbabilityArray) Len() int      { return len(a) }
+func (a topicProbabilityArray) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a topicProbabilityArray) Less(i, j int) bool {
+	if a[i].Probability != a[j].Probability {
+		return a[i].Probability > a[j].Probability
+	}
+	return a[i].Topic < a[j].Topic
+}
+
 func NewDistribution(dim int) Distribution {
 	return make(Distribution, dim)
 }
@@ -13,6 +32,28 @@ func NewHistogram(dim int) Histogram {
 	return make(Histogram, dim)
 }
 
+func (d Distribution) Sum() float64 {
+	var sum float64 = 0.0
+	for _, v := range d {
+		sum += v
+	}
+	return sum
+}
+
+// Returns at most n topics with the largest probabilities in d, in
+// descending order of probabilities.
+func (d Distribution) TopTopics(n int) []TopicProbability {
+	topics := make(topicProbabilityArray, len(d))
+	for k, p := range d {
+		topics[k] = TopicProbability{k, p}
+	}
+	sort.Sort(topics)
+	if len(topics) > n {
+		topics = topics[0:n]
+	}
+	return topics
+}
+
 func (d Distribution) IsValid() bool {
 	var sum float64 = 0.0
 	for _, v := range d {
@@ -22,11 +63,18 @@ func (d Distribution) IsValid() bool {
 }
 
 func GetAccumulativeSample(distribution Distribution) int {
+	return GetAccumulativeSampleWithRand(distribution, nil)
+}
+
+// Like GetAccumulativeSample, but draws the random choice from rng, so
+// that concurrent samplers need not share the global random source.  A
+// nil rng falls back to the global source.
+func GetAccumulativeSampleWithRand(distribution Distribution, rng *rand.Rand) int {
 	distribution_sum := 0.0
 	for _, v := range distribution {
 		distribution_sum += v
 	}
-	choice := rand.Float64() * float64(distribution_sum)
+	choice := randFloat64(rng) * float64(distribution_sum)
 
 	sum_so_far := 0.0
 	for i, v := range distribution {
@@ -37,3 +85,24 @@ func GetAccumulativeSample(distribution Distribution) int {
 	}
 	return -1;
 }
+
+func randFloat64(rng *rand.Rand) float64 {
+	if rng == nil {
+		return rand.Float64()
+	}
+	return rng.Float64()
+}
+
+func randIntn(rng *rand.Rand, n int) int {
+	if rng == nil {
+		return rand.Intn(n)
+	}
+	return rng.Intn(n)
+}
+
+func randNormFloat64(rng *rand.Rand) float64 {
+	if rng == nil {
+		return rand.NormFloat64()
+	}
+	return rng.NormFloat64()
+}
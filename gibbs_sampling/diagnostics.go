@@ -0,0 +1,265 @@
+package lda
+
+import (
+	"fmt"
+	"json"
+	"math"
+	"os"
+	"utf8"
+)
+
+// Formats of diagnostics by FormatTopicDiagnostics.
+const (
+	kXMLDiagnostics  = "xml"  // A topic element per topic, as by MALLET.
+	kJSONDiagnostics = "json" // A JSON object per topic per line.
+)
+
+// A top word of a topic in TopicDiagnostics.
+type WordDiagnostics struct {
+	Word        string
+	Count       int     // Tokens of Word assigned to the topic.
+	Probability float64 // Count over the tokens of the topic.
+	Documents   int     // Documents with tokens of Word assigned to the topic.
+}
+
+// Per-topic diagnostics of a trained model, after those of MALLET
+// (Mimno, 2012).  Bad topics tend to have few tokens, few rank-1
+// documents, low coherence, short words, and word distributions close to
+// uniform or to that of the corpus.
+type TopicDiagnostics struct {
+	Topic int
+	// The number of tokens assigned to the topic by the model.
+	Tokens int
+	// The entropy of the distribution of tokens of the topic over
+	// documents, in nats; low for topics of few documents.
+	DocumentEntropy float64
+	// The average number of characters of the top words.
+	WordLength float64
+	// The coherence of Mimno et al. (2011): the sum over pairs of top words
+	// w_i ranked below w_j of log((D(w_i, w_j) + 1) / D(w_j)), where D
+	// counts documents containing all of the words.  Higher is better.
+	Coherence float64
+	// KL(P(word|topic) || uniform over words of the model), in nats.
+	UniformDistance float64
+	// KL(P(word|topic) || P(word) of the model), in nats.
+	CorpusDistance float64
+	// 1 / sum of P(word|topic)^2, the number of equally likely words with
+	// the same concentration.
+	EffectiveNumWords float64
+	// The number of documents in which the topic has the most tokens.
+	Rank1Documents int
+	// The average over top words of P(word|topic) / sum of P(word|k) over
+	// all topics k, smoothed by word_prior; 1 if they occur in no other
+	// topic.
+	Exclusivity float64
+	TopWords    []WordDiagnostics
+}
+
+// Returns the diagnostics of every topic of model, computed from its
+// counts and the topic assignments of corpus, e.g., the final state of
+// training, with the num_top_words most frequent words of topics.
+// word_prior must be positive.
+func ComputeTopicDiagnostics(model *Model, corpus *Corpus, word_prior float64,
+	num_top_words int) []TopicDiagnostics {
+	num_topics := model.NumTopics()
+	diagnostics := make([]TopicDiagnostics, num_topics)
+	top_words := make([][]WordCount, num_topics)
+	is_top_word := make(map[string]bool)
+	for k := range diagnostics {
+		top_words[k] = model.TopWords(k, num_top_words)
+		for _, w := range top_words[k] {
+			is_top_word[w.Word] = true
+		}
+		diagnostics[k].Topic = k
+		diagnostics[k].Tokens = model.global_histogram[k]
+	}
+
+	// Statistics of corpus: tokens of every topic in every document, and
+	// documents containing top words, alone or in pairs.
+	topic_tokens := make([]int, num_topics)
+	doc_frequency := make(map[string]int)
+	co_doc_frequency := make(map[string]map[string]int)
+	topic_doc_frequency := make([]map[string]int, num_topics)
+	for k := range topic_doc_frequency {
+		topic_doc_frequency[k] = make(map[string]int)
+	}
+	for _, doc := range *corpus {
+		if len(doc.topic_histogram) != num_topics {
+			panic(fmt.Sprintf("doc has (%d) topics; model has (%d) topics.",
+				len(doc.topic_histogram), num_topics))
+		}
+		rank1 := -1
+		for k, c := range doc.topic_histogram {
+			topic_tokens[k] += c
+			if c > 0 && (rank1 < 0 || c > doc.topic_histogram[rank1]) {
+				rank1 = k
+			}
+		}
+		if rank1 >= 0 {
+			diagnostics[rank1].Rank1Documents++
+		}
+
+		present := make([]string, 0)
+		seen := make(map[string]bool)
+		seen_in_topic := make(map[string]bool)
+		for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+			w := iter.Word()
+			if !is_top_word[w] {
+				continue
+			}
+			if !seen[w] {
+				seen[w] = true
+				present = append(present, w)
+			}
+			key := fmt.Sprintf("%d %s", iter.Topic(), w)
+			if !seen_in_topic[key] {
+				seen_in_topic[key] = true
+				topic_doc_frequency[iter.Topic()][w]++
+			}
+		}
+		for _, w1 := range present {
+			doc_frequency[w1]++
+			if co_doc_frequency[w1] == nil {
+				co_doc_frequency[w1] = make(map[string]int)
+			}
+			for _, w2 := range present {
+				co_doc_frequency[w1][w2]++
+			}
+		}
+	}
+	for _, doc := range *corpus {
+		for k, c := range doc.topic_histogram {
+			if c > 0 {
+				p := float64(c) / float64(topic_tokens[k])
+				diagnostics[k].DocumentEntropy -= p * math.Log(p)
+			}
+		}
+	}
+
+	// Statistics of model: word distributions of topics and the corpus.
+	total := 0
+	for _, c := range model.global_histogram {
+		total += c
+	}
+	num_words := float64(model.NumWords())
+	for _, hist := range model.topic_histograms {
+		word_total := 0
+		for _, c := range hist {
+			word_total += c
+		}
+		p_word := float64(word_total) / float64(total)
+		for k, c := range hist {
+			if c == 0 {
+				continue
+			}
+			p := float64(c) / float64(model.global_histogram[k])
+			diagnostics[k].UniformDistance += p * math.Log(p*num_words)
+			diagnostics[k].CorpusDistance += p * math.Log(p/p_word)
+			diagnostics[k].EffectiveNumWords += p * p
+		}
+	}
+
+	for k := range diagnostics {
+		d := &diagnostics[k]
+		if d.EffectiveNumWords > 0 {
+			d.EffectiveNumWords = 1 / d.EffectiveNumWords
+		}
+		d.TopWords = make([]WordDiagnostics, len(top_words[k]))
+		for i, w := range top_words[k] {
+			d.TopWords[i] = WordDiagnostics{w.Word, w.Count,
+				float64(w.Count) / float64(model.global_histogram[k]),
+				topic_doc_frequency[k][w.Word]}
+			d.WordLength += float64(utf8.RuneCountInString(w.Word))
+
+			hist := model.GetWordTopicHistogram(w.Word)
+			sum := 0.0
+			for j, c := range hist {
+				sum += (float64(c) + word_prior) /
+					(float64(model.global_histogram[j]) + num_words*word_prior)
+			}
+			d.Exclusivity += (float64(w.Count) + word_prior) /
+				(float64(model.global_histogram[k]) + num_words*word_prior) / sum
+
+			for _, higher := range top_words[k][0:i] {
+				if n := doc_frequency[higher.Word]; n > 0 {
+					d.Coherence += math.Log(
+						float64(co_doc_frequency[w.Word][higher.Word]+1) / float64(n))
+				}
+			}
+		}
+		if len(top_words[k]) > 0 {
+			d.WordLength /= float64(len(top_words[k]))
+			d.Exclusivity /= float64(len(top_words[k]))
+		}
+	}
+	return diagnostics
+}
+
+// Returns the diagnostics in format (kXMLDiagnostics or kJSONDiagnostics),
+// with topics named by topic_names if not nil.  XML diagnostics are a
+// model element of a topic element per topic, whose attributes are the
+// diagnostics and whose word elements are the top words, as MALLET
+// writes them.  JSON diagnostics have an object per topic per line, of
+// the diagnostics in snake case, and top_words, objects of word, count,
+// probability and documents.
+func FormatTopicDiagnostics(diagnostics []TopicDiagnostics, format string,
+	topic_names []string) (string, os.Error) {
+	result := ""
+	switch format {
+	case kXMLDiagnostics:
+		result += "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<model>\n"
+		for _, d := range diagnostics {
+			result += fmt.Sprintf("<topic id=\"%d\"", d.Topic)
+			if topic_names != nil {
+				result += fmt.Sprintf(" name=\"%s\"", escapeHTML(topic_names[d.Topic]))
+			}
+			result += fmt.Sprintf(" tokens=\"%d\" document_entropy=\"%.4f\" word-length=\"%.4f\""+
+				" coherence=\"%.4f\" uniform_dist=\"%.4f\" corpus_dist=\"%.4f\""+
+				" eff_num_words=\"%.4f\" rank_1_docs=\"%d\" exclusivity=\"%.4f\">\n",
+				d.Tokens, d.DocumentEntropy, d.WordLength, d.Coherence, d.UniformDistance,
+				d.CorpusDistance, d.EffectiveNumWords, d.Rank1Documents, d.Exclusivity)
+			for i, w := range d.TopWords {
+				result += fmt.Sprintf("<word rank=\"%d\" count=\"%d\" prob=\"%.5f\" docs=\"%d\">%s</word>\n",
+					i+1, w.Count, w.Probability, w.Documents, escapeHTML(w.Word))
+			}
+			result += "</topic>\n"
+		}
+		result += "</model>\n"
+	case kJSONDiagnostics:
+		for _, d := range diagnostics {
+			words := make([]map[string]interface{}, len(d.TopWords))
+			for i, w := range d.TopWords {
+				words[i] = map[string]interface{}{
+					"word":        w.Word,
+					"count":       w.Count,
+					"probability": w.Probability,
+					"documents":   w.Documents,
+				}
+			}
+			object := map[string]interface{}{
+				"topic":               d.Topic,
+				"tokens":              d.Tokens,
+				"document_entropy":    d.DocumentEntropy,
+				"word_length":         d.WordLength,
+				"coherence":           d.Coherence,
+				"uniform_distance":    d.UniformDistance,
+				"corpus_distance":     d.CorpusDistance,
+				"effective_num_words": d.EffectiveNumWords,
+				"rank_1_documents":    d.Rank1Documents,
+				"exclusivity":         d.Exclusivity,
+				"top_words":           words,
+			}
+			if topic_names != nil {
+				object["name"] = topic_names[d.Topic]
+			}
+			line, err := json.Marshal(object)
+			if err != nil {
+				return "", err
+			}
+			result += string(line) + "\n"
+		}
+	default:
+		return "", os.NewError("Unknown diagnostics format: " + format)
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Returns a corpus of documents whose words are all assigned topics[d],
+// and the model of it.
+func newDiagnosticsTestCorpus(texts []string, topics []int) (*Corpus, *Model) {
+	corpus := NewCorpus()
+	for d, text := range texts {
+		doc, _ := NewDocument(text, 2)
+		doc.SetAllowedTopics([]int{topics[d]})
+		*corpus = append(*corpus, doc)
+	}
+	return corpus, CreateModel(2, corpus)
+}
+
+func TestComputeTopicDiagnostics(t *testing.T) {
+	corpus, model := newDiagnosticsTestCorpus(
+		[]string{"apple orange apple", "apple banana", "zebra lion zebra", "lion lion", "orange orange"},
+		[]int{0, 0, 1, 1, 0})
+	diagnostics := ComputeTopicDiagnostics(model, corpus, 0.01, 3)
+	check := func(topic int, name string, value float64, expected float64) {
+		if math.Fabs(value-expected) > 1e-4 {
+			t.Errorf("Expecting %s %f of topic %d, but got %f", name, expected, topic, value)
+		}
+	}
+
+	d := diagnostics[0]
+	if d.Tokens != 7 || d.Rank1Documents != 3 {
+		t.Errorf("Expecting 7 tokens and 3 rank-1 documents of topic 0, but got %v", d)
+	}
+	check(0, "document entropy", d.DocumentEntropy, 1.0790)
+	check(0, "word length", d.WordLength, 17.0/3)
+	// Only banana and orange do not co-occur: log((0 + 1) / 2).
+	check(0, "coherence", d.Coherence, math.Log(0.5))
+	check(0, "effective number of words", d.EffectiveNumWords, 49.0/19)
+	if d.Exclusivity < 0.99 {
+		t.Errorf("Expecting exclusivity of topic 0 about 1, but got %f", d.Exclusivity)
+	}
+	if fmt.Sprintf("%v", d.TopWords) != "[{apple 3 0.42857142857142855 2} {orange 3 0.42857142857142855 2} "+
+		"{banana 1 0.14285714285714285 1}]" {
+		t.Errorf("Unexpected top words of topic 0: %v", d.TopWords)
+	}
+
+	d = diagnostics[1]
+	if d.Tokens != 5 || d.Rank1Documents != 2 {
+		t.Errorf("Expecting 5 tokens and 2 rank-1 documents of topic 1, but got %v", d)
+	}
+	check(1, "document entropy", d.DocumentEntropy, 0.6730)
+	check(1, "word length", d.WordLength, 4.5)
+	check(1, "coherence", d.Coherence, 0)
+	check(1, "uniform distance", d.UniformDistance, 0.6*math.Log(3)+0.4*math.Log(2))
+	// P(lion) and P(zebra) are 0.6 and 0.4 in topic 1, and 1/4 and 1/6 in
+	// the model.
+	check(1, "corpus distance", d.CorpusDistance, math.Log(2.4))
+	check(1, "effective number of words", d.EffectiveNumWords, 1/0.52)
+}
+
+func TestFormatTopicDiagnostics(t *testing.T) {
+	corpus, model := newDiagnosticsTestCorpus([]string{"apple apple", "zebra lion"}, []int{0, 1})
+	diagnostics := ComputeTopicDiagnostics(model, corpus, 0.01, 2)
+
+	xml_text, err := FormatTopicDiagnostics(diagnostics, "xml", []string{"fruit", "animal"})
+	if err != nil {
+		t.Fatalf("Cannot format XML diagnostics due to " + err.String())
+	}
+	for _, s := range []string{"<model>", "<topic id=\"1\" name=\"animal\" tokens=\"2\"",
+		"rank_1_docs=\"1\"", "<word rank=\"2\" count=\"1\" prob=\"0.50000\" docs=\"1\">zebra</word>",
+		"</model>"} {
+		if strings.Index(xml_text, s) < 0 {
+			t.Errorf("Expecting %s in XML diagnostics:\n%s", s, xml_text)
+		}
+	}
+
+	json_text, err := FormatTopicDiagnostics(diagnostics, "json", nil)
+	if err != nil {
+		t.Fatalf("Cannot format JSON diagnostics due to " + err.String())
+	}
+	lines := strings.Split(strings.TrimSpace(json_text), "\n", -1)
+	if len(lines) != 2 || strings.Index(lines[0], "\"tokens\":2") < 0 ||
+		strings.Index(lines[1], "{\"count\":1,\"documents\":1,\"probability\":0.5,\"word\":\"lion\"}") < 0 {
+		t.Errorf("Unexpected JSON diagnostics:\n%s", json_text)
+	}
+
+	if _, err := FormatTopicDiagnostics(diagnostics, "csv", nil); err == nil {
+		t.Errorf("Expecting an error for an unknown format")
+	}
+}
@@ -0,0 +1,65 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+)
+
+// Returns the Kullback-Leibler divergence KL(p || q), in nats.  Entries
+// where p is 0 contribute nothing; q must be positive wherever p is.
+func KLDivergence(p Distribution, q Distribution) float64 {
+	if len(p) != len(q) {
+		panic(fmt.Sprintf("Distributions of different dimensions: %d vs. %d", len(p), len(q)))
+	}
+	divergence := 0.0
+	for i, v := range p {
+		if v > 0 {
+			divergence += v * math.Log(v/q[i])
+		}
+	}
+	return divergence
+}
+
+// Returns the Jensen-Shannon divergence of p and q, in nats, i.e., the
+// average KL divergence of p and q from their mean.  It is symmetric and
+// bounded by log 2.
+func JensenShannonDivergence(p Distribution, q Distribution) float64 {
+	if len(p) != len(q) {
+		panic(fmt.Sprintf("Distributions of different dimensions: %d vs. %d", len(p), len(q)))
+	}
+	m := NewDistribution(len(p))
+	for i := range m {
+		m[i] = (p[i] + q[i]) / 2
+	}
+	return (KLDivergence(p, m) + KLDivergence(q, m)) / 2
+}
+
+// Returns the Hellinger distance between p and q, in [0, 1].
+func HellingerDistance(p Distribution, q Distribution) float64 {
+	if len(p) != len(q) {
+		panic(fmt.Sprintf("Distributions of different dimensions: %d vs. %d", len(p), len(q)))
+	}
+	sum := 0.0
+	for i, v := range p {
+		d := math.Sqrt(v) - math.Sqrt(q[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum / 2)
+}
+
+// Returns the cosine similarity of p and q, or 0 if either is all zeros.
+func CosineSimilarity(p Distribution, q Distribution) float64 {
+	if len(p) != len(q) {
+		panic(fmt.Sprintf("Distributions of different dimensions: %d vs. %d", len(p), len(q)))
+	}
+	dot, norm_p, norm_q := 0.0, 0.0, 0.0
+	for i, v := range p {
+		dot += v * q[i]
+		norm_p += v * v
+		norm_q += q[i] * q[i]
+	}
+	if norm_p == 0 || norm_q == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(norm_p*norm_q)
+}
@@ -0,0 +1,43 @@
+package lda
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJensenShannonDivergence(t *testing.T) {
+	p := Distribution{0.5, 0.5, 0}
+	q := Distribution{0, 0.5, 0.5}
+	if d := JensenShannonDivergence(p, p); d != 0 {
+		t.Errorf("Expecting 0 divergence from itself, but got %f", d)
+	}
+	if d1, d2 := JensenShannonDivergence(p, q), JensenShannonDivergence(q, p); d1 != d2 {
+		t.Errorf("Asymmetric divergence: %f vs. %f", d1, d2)
+	}
+	if d := JensenShannonDivergence(p, q); math.Fabs(d-math.Log(2)/2) > 1e-9 {
+		t.Errorf("Expecting log(2)/2, but got %f", d)
+	}
+	if d := JensenShannonDivergence(Distribution{1, 0}, Distribution{0, 1}); math.Fabs(d-math.Log(2)) > 1e-9 {
+		t.Errorf("Expecting log(2) between disjoint distributions, but got %f", d)
+	}
+}
+
+func TestHellingerDistanceAndCosineSimilarity(t *testing.T) {
+	p := Distribution{0.5, 0.5, 0}
+	q := Distribution{0, 0.5, 0.5}
+	if d := HellingerDistance(p, p); d != 0 {
+		t.Errorf("Expecting 0 distance from itself, but got %f", d)
+	}
+	if d := HellingerDistance(Distribution{1, 0}, Distribution{0, 1}); math.Fabs(d-1) > 1e-9 {
+		t.Errorf("Expecting 1 between disjoint distributions, but got %f", d)
+	}
+	if d := HellingerDistance(p, q); math.Fabs(d-math.Sqrt(0.5)) > 1e-9 {
+		t.Errorf("Expecting sqrt(0.5), but got %f", d)
+	}
+	if s := CosineSimilarity(p, q); math.Fabs(s-0.5) > 1e-9 {
+		t.Errorf("Expecting 0.5, but got %f", s)
+	}
+	if s := CosineSimilarity(p, Distribution{0, 0, 0}); s != 0 {
+		t.Errorf("Expecting 0 with a zero vector, but got %f", s)
+	}
+}
@@ -0,0 +1,247 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The feature every document has, with value 1, whose weights are the
+// log topic priors of documents without other features.
+const kDMRDefaultFeature = "(default)"
+
+// The number of gradient steps in every DMRModel.Optimize.
+const kDMROptimizeIterations = 20
+
+// A covariate of a document: the value of a feature, indexed in the
+// features of DMRModel.
+type Covariate struct {
+	Feature int
+	Value   float64
+}
+
+// The weight of a feature for a topic in DMRModel.
+type FeatureWeight struct {
+	Feature string
+	Weight  float64
+}
+
+type featureWeightArray []FeatureWeight
+
+func (a featureWeightArray) Len() int      { return len(a) }
+func (a featureWeightArray) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a featureWeightArray) Less(i, j int) bool {
+	if a[i].Weight != a[j].Weight {
+		return a[i].Weight > a[j].Weight
+	}
+	return a[i].Feature < a[j].Feature
+}
+
+// Load a corpus for Dirichlet-multinomial regression (DMR), where each
+// line has the form
+//
+// feature_1,feature_2:value_2,...<TAB>text
+//
+// A feature without a value, e.g., a category like source=web, has value
+// 1.  Every document also has kDMRDefaultFeature.  Returns the corpus,
+// the covariates of its documents, and the names of all features, with
+// kDMRDefaultFeature first and the others in order of appearance.
+func LoadDMRCorpus(filename string, num_topics int) (corpus *Corpus,
+	covariates [][]Covariate, features []string, err os.Error) {
+	corpus = NewCorpus()
+	covariates = make([][]Covariate, 0)
+	features = []string{kDMRDefaultFeature}
+	feature_index := map[string]int{kDMRDefaultFeature: 0}
+	err = readLines(filename, func(line string) os.Error {
+		metadata, text, err := splitMetadata(line)
+		if err != nil {
+			return err
+		}
+		doc_covariates := []Covariate{{0, 1}}
+		for _, field := range strings.Split(metadata, ",", -1) {
+			field = strings.TrimSpace(field)
+			if len(field) == 0 {
+				continue
+			}
+			name, value := field, 1.0
+			if i := strings.LastIndex(field, ":"); i >= 0 {
+				if value, err = strconv.Atof64(field[i+1:]); err != nil {
+					return os.NewError("Invalid feature value: " + field)
+				}
+				name = field[0:i]
+			}
+			if len(strings.Fields(name)) != 1 || name == kDMRDefaultFeature {
+				return os.NewError("Invalid feature: \"" + name + "\"")
+			}
+			index, present := feature_index[name]
+			if !present {
+				index = len(features)
+				feature_index[name] = index
+				features = append(features, name)
+			}
+			doc_covariates = append(doc_covariates, Covariate{index, value})
+		}
+		doc, err := NewDocument(text, num_topics)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		covariates = append(covariates, doc_covariates)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return corpus, covariates, features, nil
+}
+
+// DMRModel is the regression of Dirichlet-multinomial regression topic
+// models (Mimno and McCallum, 2008), in which the Dirichlet prior on
+// topics of document d is
+//
+//   alpha_d[k] = exp(sum_f x_d[f] lambda[k][f])
+//
+// where x_d are the covariates of d, so that document metadata shifts
+// which topics appear.  lambda has a Gaussian prior of mean 0.
+type DMRModel struct {
+	features []string
+	lambda   [][]float64 // lambda[topic][feature]
+	variance float64     // The variance of the Gaussian prior on lambda.
+	step     float64     // The step size of the last gradient ascent.
+}
+
+// Create a DMRModel of features and num_topics topics, where all
+// documents initially have the symmetric topic_prior.
+func NewDMRModel(features []string, num_topics int, topic_prior float64,
+	variance float64) *DMRModel {
+	if len(features) == 0 || features[0] != kDMRDefaultFeature {
+		panic("The first feature must be " + kDMRDefaultFeature)
+	}
+	if topic_prior <= 0 || variance <= 0 {
+		panic("topic_prior and variance must be positive")
+	}
+	m := &DMRModel{features, make([][]float64, num_topics), variance, 0}
+	for k := range m.lambda {
+		m.lambda[k] = make([]float64, len(features))
+		m.lambda[k][0] = math.Log(topic_prior)
+	}
+	return m
+}
+
+func (m *DMRModel) NumTopics() int {
+	return len(m.lambda)
+}
+
+func (m *DMRModel) Features() []string {
+	return m.features
+}
+
+// Returns the Dirichlet prior on topics of a document with covariates.
+func (m *DMRModel) TopicPrior(covariates []Covariate) Distribution {
+	prior := NewDistribution(len(m.lambda))
+	for k, l := range m.lambda {
+		x := 0.0
+		for _, c := range covariates {
+			x += l[c.Feature] * c.Value
+		}
+		prior[k] = math.Exp(x)
+	}
+	return prior
+}
+
+// Set the topic prior of every document in corpus from its covariates.
+func (m *DMRModel) SetTopicPriors(corpus *Corpus, covariates [][]Covariate) os.Error {
+	for d, doc := range *corpus {
+		if err := doc.SetTopicPrior(m.TopicPrior(covariates[d])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the log posterior of lambda given the topic histograms of
+// documents in corpus, up to a constant, and its gradient by topic and
+// feature if gradient is not nil.
+func (m *DMRModel) logPosterior(corpus *Corpus, covariates [][]Covariate,
+	gradient [][]float64) float64 {
+	result := 0.0
+	for k, l := range m.lambda {
+		for f, w := range l {
+			result -= w * w / (2 * m.variance)
+			if gradient != nil {
+				gradient[k][f] = -w / m.variance
+			}
+		}
+	}
+	for d, doc := range *corpus {
+		alpha := m.TopicPrior(covariates[d])
+		alpha_sum := alpha.Sum()
+		n := float64(doc.Length())
+		result += logGamma(alpha_sum) - logGamma(alpha_sum+n)
+		common := digamma(alpha_sum) - digamma(alpha_sum+n)
+		for k, a := range alpha {
+			c := float64(doc.topic_histogram[k])
+			result += logGamma(a+c) - logGamma(a)
+			if gradient != nil {
+				g := a * (common + digamma(a+c) - digamma(a))
+				for _, x := range covariates[d] {
+					gradient[k][x.Feature] += g * x.Value
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Optimize lambda given the current topic assignments of corpus by
+// gradient ascent on its log posterior, and set the topic priors of
+// documents accordingly.  Returns the log posterior.
+func (m *DMRModel) Optimize(corpus *Corpus, covariates [][]Covariate) float64 {
+	if len(*corpus) != len(covariates) {
+		panic(fmt.Sprintf("%d documents, but %d covariates", len(*corpus), len(covariates)))
+	}
+	if m.step == 0 {
+		m.step = 1 / float64(len(*corpus)+1)
+	}
+	gradient := make([][]float64, len(m.lambda))
+	for k := range gradient {
+		gradient[k] = make([]float64, len(m.features))
+	}
+	objective := m.logPosterior(corpus, covariates, gradient)
+	for iter := 0; iter < kDMROptimizeIterations; iter++ {
+		old_lambda := m.lambda
+		m.lambda = make([][]float64, len(old_lambda))
+		for k, l := range old_lambda {
+			m.lambda[k] = make([]float64, len(l))
+			for f, w := range l {
+				m.lambda[k][f] = w + m.step*gradient[k][f]
+			}
+		}
+		if o := m.logPosterior(corpus, covariates, nil); o > objective {
+			objective = m.logPosterior(corpus, covariates, gradient)
+			m.step *= 2
+		} else {
+			m.lambda = old_lambda
+			m.step /= 2
+		}
+	}
+	if err := m.SetTopicPriors(corpus, covariates); err != nil {
+		panic("Cannot set topic priors: " + err.String())
+	}
+	return objective
+}
+
+// Returns the features of topic in descending order of their weights
+// lambda[topic], i.e., how much they favor the topic.
+func (m *DMRModel) FeatureWeights(topic int) []FeatureWeight {
+	weights := make(featureWeightArray, len(m.features))
+	for f, name := range m.features {
+		weights[f] = FeatureWeight{name, m.lambda[topic][f]}
+	}
+	sort.Sort(weights)
+	return weights
+}
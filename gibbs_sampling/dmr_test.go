@@ -0,0 +1,95 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"rand"
+	"testing"
+)
+
+const kDMRCorpusFile = "testdata/dmr_corpus.txt"
+
+func TestLoadDMRCorpus(t *testing.T) {
+	corpus, covariates, features, err := LoadDMRCorpus(kDMRCorpusFile, 2)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kDMRCorpusFile + " : " + err.String())
+	}
+	if len(*corpus) != 2 {
+		t.Errorf("Expecting 2 documents, but got %d", len(*corpus))
+	}
+	if s := fmt.Sprintf("%v", features); s != "[(default) source=zoo size]" {
+		t.Errorf("Unexpected features: %s", s)
+	}
+	if s := fmt.Sprintf("%v", covariates); s != "[[{0 1} {1 1} {2 2.5}] [{0 1}]]" {
+		t.Errorf("Unexpected covariates: %s", s)
+	}
+}
+
+func TestDocumentTopicPrior(t *testing.T) {
+	doc, _ := NewDocument("apple orange", 2)
+	for _, prior := range []Distribution{{1}, {1, 0}, {1, -1}} {
+		if err := doc.SetTopicPrior(prior); err == nil {
+			t.Errorf("Expecting an error setting topic prior %v", prior)
+		}
+	}
+	model := NewModel(2)
+	model.IncrementTopic("apple", 0, 1)
+	model.IncrementTopic("apple", 1, 1)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	symmetric := sampler.GenerateTopicDistributionForWord(doc, "apple", 0, false)
+	doc.SetTopicPrior(Distribution{0.1, 10})
+	d := sampler.GenerateTopicDistributionForWord(doc, "apple", 0, false)
+	if d[1]/d[0] <= 10*symmetric[1]/symmetric[0] {
+		t.Errorf("Expecting topic 1 favored by the topic prior, but got %v vs. %v", d, symmetric)
+	}
+}
+
+func TestDMRModel(t *testing.T) {
+	m := NewDMRModel([]string{kDMRDefaultFeature, "x"}, 2, 0.5, 1)
+	m.lambda[1][1] = math.Log(4)
+	prior := m.TopicPrior([]Covariate{{0, 1}, {1, 1}})
+	if math.Fabs(prior[0]-0.5) > 1e-9 || math.Fabs(prior[1]-2) > 1e-9 {
+		t.Errorf("Unexpected topic prior: %v", prior)
+	}
+	if s := fmt.Sprintf("%v", m.FeatureWeights(0)); s != fmt.Sprintf("[{x 0} {(default) %v}]", math.Log(0.5)) {
+		t.Errorf("Unexpected feature weights: %s", s)
+	}
+}
+
+// Fruit documents come from a market and animal documents from a zoo;
+// DMR should learn that the source favors the corresponding topic.
+func TestDMRLearnsCovariateEffects(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 40, 2)
+	covariates := make([][]Covariate, len(*corpus))
+	for d, doc := range *corpus {
+		doc.RandomizeTopics(rng)
+		covariates[d] = []Covariate{{0, 1}, {1 + d%2, 1}}
+	}
+	model := CreateModel(2, corpus)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	sampler.SetRand(rng)
+	dmr := NewDMRModel([]string{kDMRDefaultFeature, "market", "zoo"}, 2, 0.1, 1)
+	previous := math.Inf(-1)
+	for iter := 0; iter < 30; iter++ {
+		sampler.CorpusGibbsSampling(corpus, true, true)
+		if iter >= 10 {
+			objective := dmr.Optimize(corpus, covariates)
+			if iter > 10 && objective < previous-50 {
+				t.Errorf("Log posterior drops from %f to %f", previous, objective)
+			}
+			previous = objective
+		}
+	}
+
+	checkTwoTopicModel(t, model)
+	fruit_topic := dominantTopic(model, kFruits[0])
+	animal_topic := 1 - fruit_topic
+	if dmr.lambda[fruit_topic][1] <= dmr.lambda[animal_topic][1] ||
+		dmr.lambda[animal_topic][2] <= dmr.lambda[fruit_topic][2] {
+		t.Errorf("Unexpected covariate effects: %v", dmr.lambda)
+	}
+	if prior := (*corpus)[0].TopicPrior(); prior[fruit_topic] <= prior[animal_topic] {
+		t.Errorf("Expecting a fruit document to favor topic %d, but got %v", fruit_topic, prior)
+	}
+}
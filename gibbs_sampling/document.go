@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"encoding/line"
 	"os"
+	"rand"
 	"strings"
 	"sort"
 )
@@ -23,19 +24,45 @@ const kMaxCorpusFileLineLength = 1024 * 1024
 // wordtopics_index:      |        |      |
 // wordtopics:            0 3 4 0  0 3    1
 //
+// allowed_topics, if not nil, restricts the topics that words of the
+// document may be assigned, e.g., to the topics of its labels in
+// Labeled LDA.
+//
+// topic_prior, if not nil, is the Dirichlet prior on topics of the
+// document, replacing the symmetric topic_prior of Sampler, e.g., one
+// derived from document covariates in DMR.
+//
+// positions keeps the original order of words in the text, which the
+// grouping loses: positions[i] is the position in the text of the word
+// occurrence of wordtopics[i], and occurrences is its inverse.  For the
+// text "b a b":
+//
+// unique_words:          a  b
+// wordtopics:            0  1 0
+// positions:             1  0 2
+// occurrences:           1  0 2
+//
 type Document struct {
 	unique_words       []string
 	wordtopics_indices []int
 	wordtopics         []int
+	positions          []int
+	occurrences        []int
 	topic_histogram    Histogram
+	allowed_topics     []int
+	topic_prior        Distribution
 }
 
 type Corpus []*Document
 
+// WordIterator visits the word occurrences of a document, grouped by
+// words (as in doc.wordtopics) or, if ordered, in their original order.
 type WordIterator struct {
 	doc               *Document
 	unique_word_index int // Index in doc.unique_words.
 	word_topic_index  int // Index in doc.wordtopics.
+	ordered           bool
+	position          int // Position in the text, if ordered.
 }
 
 func NewWordIterator(d *Document) (iter *WordIterator, err os.Error) {
@@ -46,10 +73,34 @@ func NewWordIterator(d *Document) (iter *WordIterator, err os.Error) {
 		return nil, os.NewError(
 			"NewWordIterator with an invalid Document")
 	}
-	iter = &WordIterator{d, 0, 0}
+	iter = &WordIterator{doc: d}
 	return
 }
 
+// Returns an iterator visiting the word occurrences of d in their
+// original order in the text.
+func NewOrderedWordIterator(d *Document) (iter *WordIterator, err os.Error) {
+	if iter, err = NewWordIterator(d); err != nil {
+		return nil, err
+	}
+	iter.ordered = true
+	iter.seek()
+	return
+}
+
+// Move an ordered iterator to the word occurrence at iter.position.
+func (iter *WordIterator) seek() {
+	doc := iter.doc
+	if iter.position >= len(doc.occurrences) {
+		iter.unique_word_index = len(doc.unique_words)
+		return
+	}
+	iter.word_topic_index = doc.occurrences[iter.position]
+	iter.unique_word_index = sort.Search(len(doc.wordtopics_indices), func(i int) bool {
+		return doc.wordtopics_indices[i] > iter.word_topic_index
+	}) - 1
+}
+
 func (iter WordIterator) Done() bool {
 	if iter.unique_word_index > len(iter.doc.unique_words) {
 		panic(fmt.Sprintf("unique_word_index = %d, len(iter.doc.unique_words) = %d",
@@ -62,10 +113,16 @@ func (iter *WordIterator) Next() {
 	if iter.Done() {
 		panic("Must not call Next() when Done() is true.")
 	}
+	if iter.ordered {
+		iter.position++
+		iter.seek()
+		return
+	}
 	iter.word_topic_index++
 	if iter.word_topic_index >= len(iter.doc.wordtopics) ||
-		iter.word_topic_index >=
-			iter.doc.wordtopics_indices[iter.unique_word_index+1] {
+		(iter.unique_word_index+1 < len(iter.doc.wordtopics_indices) &&
+			iter.word_topic_index >=
+				iter.doc.wordtopics_indices[iter.unique_word_index+1]) {
 		iter.unique_word_index++
 	}
 }
@@ -100,6 +157,19 @@ func (iter WordIterator) Word() string {
 	return iter.doc.unique_words[iter.unique_word_index]
 }
 
+// Returns the position of the current word occurrence in the text.
+func (iter WordIterator) Position() int {
+	if iter.Done() {
+		panic("Must not call Next() when Done() is true.")
+	}
+	return iter.doc.positions[iter.word_topic_index]
+}
+
+// Returns the index of the current word occurrence in doc.wordtopics.
+func (iter WordIterator) occurrence() int {
+	return iter.word_topic_index
+}
+
 
 // Parse a text string, words seprated by whitespaces, and create a
 // Document instance.  In order to initialize topic_histogram, this
@@ -113,10 +183,19 @@ func NewDocument(text string, num_topics int) (doc *Document, err os.Error) {
 	if len(words) <= 1 {
 		return nil, os.NewError("Document less than 2 words:" + text)
 	}
-	sort.SortStrings(words)
+	tokens := &tokenArray{words, make([]int, len(words))}
+	for i := range tokens.positions {
+		tokens.positions[i] = i
+	}
+	sort.Sort(tokens)
 
 	doc = new(Document)
 	doc.wordtopics = make([]int, len(words))
+	doc.positions = tokens.positions
+	doc.occurrences = make([]int, len(words))
+	for i, p := range doc.positions {
+		doc.occurrences[p] = i
+	}
 	doc.unique_words = make([]string, 0)
 	doc.wordtopics_indices = make([]int, 0)
 	doc.topic_histogram = make([]int, num_topics)
@@ -137,52 +216,281 @@ func NewDocument(text string, num_topics int) (doc *Document, err os.Error) {
 	return
 }
 
+// Words sorted with their positions in the text, the latter breaking
+// ties, so that occurrences of a word stay in their original order.
+type tokenArray struct {
+	words     []string
+	positions []int
+}
+
+func (a *tokenArray) Len() int { return len(a.words) }
+func (a *tokenArray) Swap(i, j int) {
+	a.words[i], a.words[j] = a.words[j], a.words[i]
+	a.positions[i], a.positions[j] = a.positions[j], a.positions[i]
+}
+func (a *tokenArray) Less(i, j int) bool {
+	if a.words[i] != a.words[j] {
+		return a.words[i] < a.words[j]
+	}
+	return a.positions[i] < a.positions[j]
+}
+
 func (d Document) IsValid() bool {
 	return len(d.unique_words) >= 1 &&
 		len(d.wordtopics_indices) == len(d.unique_words) &&
 		len(d.wordtopics) >= 2 &&
+		len(d.positions) == len(d.wordtopics) &&
+		len(d.occurrences) == len(d.wordtopics) &&
 		len(d.topic_histogram) >= 2
 }
 
+// Returns the words of the document in their original order.
+func (d *Document) Tokens() []string {
+	tokens := make([]string, 0, d.Length())
+	for iter, _ := NewOrderedWordIterator(d); !iter.Done(); iter.Next() {
+		tokens = append(tokens, iter.Word())
+	}
+	return tokens
+}
+
+// Returns the topics of the words of the document in their original
+// order.
+func (d *Document) TokenTopics() []int {
+	topics := make([]int, d.Length())
+	for p, i := range d.occurrences {
+		topics[p] = d.wordtopics[i]
+	}
+	return topics
+}
+
 func (d Document) Length() int {
 	return len(d.wordtopics)
 }
 
+// Returns the number of occurrences of d.unique_words[i].
+func (d *Document) uniqueWordCount(i int) int {
+	end := len(d.wordtopics)
+	if i+1 < len(d.wordtopics_indices) {
+		end = d.wordtopics_indices[i+1]
+	}
+	return end - d.wordtopics_indices[i]
+}
+
+// Restrict the topics of the document's words to topics, and assign
+// every word the first of them.  A nil topics removes the restriction.
+func (d *Document) SetAllowedTopics(topics []int) os.Error {
+	if topics == nil {
+		d.allowed_topics = nil
+		return nil
+	}
+	if len(topics) == 0 {
+		return os.NewError("No allowed topics")
+	}
+	allowed := make([]int, len(topics))
+	copy(allowed, topics)
+	sort.SortInts(allowed)
+	for i, t := range allowed {
+		if t < 0 || t >= len(d.topic_histogram) {
+			return os.NewError(fmt.Sprintf("Allowed topic %d out of range [0, %d)",
+				t, len(d.topic_histogram)))
+		}
+		if i > 0 && t == allowed[i-1] {
+			return os.NewError(fmt.Sprintf("Duplicated allowed topic %d", t))
+		}
+	}
+
+	d.allowed_topics = allowed
+	for i := range d.topic_histogram {
+		d.topic_histogram[i] = 0
+	}
+	for i := range d.wordtopics {
+		d.wordtopics[i] = allowed[0]
+	}
+	d.topic_histogram[allowed[0]] = len(d.wordtopics)
+	return nil
+}
+
+func (d *Document) IsTopicAllowed(topic int) bool {
+	if d.allowed_topics == nil {
+		return true
+	}
+	for _, t := range d.allowed_topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// Set the Dirichlet prior on topics of the document, whose values must
+// be positive.  A nil prior restores the symmetric prior of Sampler.
+func (d *Document) SetTopicPrior(prior Distribution) os.Error {
+	if prior == nil {
+		d.topic_prior = nil
+		return nil
+	}
+	if len(prior) != len(d.topic_histogram) {
+		return os.NewError(fmt.Sprintf("Topic prior of %d topics for a document of %d topics",
+			len(prior), len(d.topic_histogram)))
+	}
+	for _, a := range prior {
+		if !(a > 0) {
+			return os.NewError(fmt.Sprintf("Non-positive topic prior: %v", prior))
+		}
+	}
+	d.topic_prior = make(Distribution, len(prior))
+	copy(d.topic_prior, prior)
+	return nil
+}
+
+// Returns the Dirichlet prior on topics of the document, or nil if it
+// uses the symmetric prior of Sampler.
+func (d *Document) TopicPrior() Distribution {
+	return d.topic_prior
+}
+
+// Returns the number of topics that words of the document may be
+// assigned.
+func (d *Document) NumAllowedTopics() int {
+	if d.allowed_topics == nil {
+		return len(d.topic_histogram)
+	}
+	return len(d.allowed_topics)
+}
+
+// Assign every word occurrence a topic drawn uniformly at random from the
+// allowed topics, and rebuild topic_histogram accordingly.  A nil rng
+// uses the global random source.
+func (d *Document) RandomizeTopics(rng *rand.Rand) {
+	for i := range d.topic_histogram {
+		d.topic_histogram[i] = 0
+	}
+	for i := range d.wordtopics {
+		if d.allowed_topics == nil {
+			d.wordtopics[i] = randIntn(rng, len(d.topic_histogram))
+		} else {
+			d.wordtopics[i] = d.allowed_topics[randIntn(rng, len(d.allowed_topics))]
+		}
+		d.topic_histogram[d.wordtopics[i]]++
+	}
+}
+
+// Append a topic with no words, after it is added to the model.
+func (d *Document) addTopic() {
+	if d.allowed_topics != nil || d.topic_prior != nil {
+		panic("Cannot add topics to a document with allowed topics or a topic prior")
+	}
+	d.topic_histogram = append(d.topic_histogram, 0)
+}
+
+// Reassign every word from topic k to mapping[k], after topics of the
+// model are compacted; see Model.CompactTopics.  Words must not be
+// assigned removed topics.
+func (d *Document) remapTopics(mapping []int, num_topics int) {
+	if d.allowed_topics != nil || d.topic_prior != nil {
+		panic("Cannot remap topics of a document with allowed topics or a topic prior")
+	}
+	d.topic_histogram = NewHistogram(num_topics)
+	for i, t := range d.wordtopics {
+		if mapping[t] < 0 {
+			panic(fmt.Sprintf("Word is assigned removed topic %d", t))
+		}
+		d.wordtopics[i] = mapping[t]
+		d.topic_histogram[mapping[t]]++
+	}
+}
+
 func NewCorpus() *Corpus {
 	return &Corpus{}
 }
 
 func LoadCorpus(filename string, num_topics int) (corpus *Corpus, err os.Error) {
+	corpus = NewCorpus()
+	err = readLines(filename, func(line string) os.Error {
+		doc, err := NewDocument(line, num_topics)
+		if err != nil {
+			panic("Cannot create document from: " + line + " due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+// Call process with every non-empty line in a file, e.g., a corpus, and
+// returns the first error in reading the file or returned by process.
+func readLines(filename string, process func(line string) os.Error) os.Error {
 	file, err := os.Open(filename, 0, 0)
 	if err != nil {
-		return nil, os.NewError("Cannot open file: " + filename)
+		return os.NewError("Cannot open file: " + filename)
 	}
 	defer file.Close()
 
-	corpus = NewCorpus()
 	reader := line.NewReader(bufio.NewReader(file), kMaxCorpusFileLineLength)
 	l, is_prefix, err := reader.ReadLine()
 	for err == nil {
 		line := string(l)
 
 		if is_prefix {
-			return nil, os.NewError("Encountered a long line:" + line)
+			return os.NewError("Encountered a long line:" + line)
 		}
 
 		if len(l) > 1 {		// skip empty lines
-			doc, err := NewDocument(line, num_topics)
-			if err == nil {
-				*corpus = append(*corpus, doc)
-			} else {
-				panic("Cannot create document from: " + line + " due to " + err.String())
+			if err := process(line); err != nil {
+				return err
 			}
 		}
 
-		l, _, err = reader.ReadLine()
+		l, is_prefix, err = reader.ReadLine()
 	}
 
 	if err != os.EOF {
-		return nil, os.NewError("Error reading: " + filename + err.String())
+		return os.NewError("Error reading: " + filename + err.String())
 	}
-	return corpus, nil
+	return nil
+}
+
+// Write content into filename, replacing the file if it exists.  Unlike
+// deferred Flush and Close, errors of writing are returned.
+func SaveFile(filename string, content string) os.Error {
+	return writeFile(filename, func(writer *bufio.Writer) {
+		fmt.Fprint(writer, content)
+	})
+}
+
+// Write filename by write, replacing the file if it exists, and returns
+// errors of opening, writing or closing it.
+func writeFile(filename string, write func(writer *bufio.Writer)) os.Error {
+	file, err := os.Open(filename, os.O_WRONLY|os.O_CREAT|os.O_TRUNC, 0666)
+	if err != nil {
+		return os.NewError("Cannot open file: " + filename + " " + err.String())
+	}
+
+	writer := bufio.NewWriter(file)
+	write(writer)
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return os.NewError("Cannot write file: " + filename + " " + err.String())
+	}
+	if err := file.Close(); err != nil {
+		return os.NewError("Cannot write file: " + filename + " " + err.String())
+	}
+	return nil
+}
+
+// Split a line of a corpus with document metadata, which has the form
+//
+// metadata<TAB>text
+//
+// where metadata describes the document (e.g., its labels), and text
+// contains its words separated by whitespaces.
+func splitMetadata(line string) (metadata string, text string, err os.Error) {
+	i := strings.Index(line, "\t")
+	if i < 0 {
+		return "", "", os.NewError("Missing tab-separated metadata: " + line)
+	}
+	return line[0:i], line[i+1:], nil
 }
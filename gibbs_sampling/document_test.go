@@ -2,14 +2,17 @@ package lda
 
 import (
 	"fmt"
+	"io/ioutil"
+	"rand"
 	"testing"
 )
 
 const kNumTopics = 3
 const kDocumentContent = "apple orange apple"
-const kDocumentGoFmt = "&{[apple orange] [0 2] [0 0 0] [3 0 0]}"
+const kDocumentGoFmt = "&{[apple orange] [0 2] [0 0 0] [0 2 1] [0 2 1] [3 0 0] [] []}"
 const kCorpusFile = "testdata/corpus.txt"
-const kCorpusGoFmt = "{[apple orange] [0 2] [0 0 0] [3 0]},{[jagar zebra] [0 1] [0 0] [2 0]}"
+const kCorpusGoFmt = "{[apple orange] [0 2] [0 0 0] [0 2 1] [0 2 1] [3 0] [] []}," +
+	"{[jagar zebra] [0 1] [0 0] [1 0] [1 0] [2 0] [] []}"
 
 func TestNewDocument(t *testing.T) {
 	if doc, _ := NewDocument("", kNumTopics); doc != nil {
@@ -63,6 +66,17 @@ func TestWordIterator(t *testing.T) {
 	}
 }
 
+func TestWordIteratorRepeatedLastWord(t *testing.T) {
+	doc, _ := NewDocument("zebra apple zebra", kNumTopics)
+	words := ""
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		words += iter.Word() + " "
+	}
+	if words != "apple zebra zebra " {
+		t.Errorf("Unexpected words: %s", words)
+	}
+}
+
 func TestCorpus(t *testing.T) {
 	corpus := NewCorpus()
 	if len(*corpus) != 0 {
@@ -87,3 +101,69 @@ func TestLoadCorpus(t *testing.T) {
 		}
 	}
 }
+
+func TestOrderedWordIterator(t *testing.T) {
+	doc, _ := NewDocument("zebra apple zebra orange apple", kNumTopics)
+	words := ""
+	for iter, _ := NewOrderedWordIterator(doc); !iter.Done(); iter.Next() {
+		words += fmt.Sprintf("%s:%d ", iter.Word(), iter.Position())
+	}
+	if words != "zebra:0 apple:1 zebra:2 orange:3 apple:4 " {
+		t.Errorf("Unexpected words: %s", words)
+	}
+	// The grouped iterator knows the positions too.
+	words = ""
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		words += fmt.Sprintf("%s:%d ", iter.Word(), iter.Position())
+	}
+	if words != "apple:1 apple:4 orange:3 zebra:0 zebra:2 " {
+		t.Errorf("Unexpected words: %s", words)
+	}
+
+	// Topics set through either iterator are seen by both.
+	iter, _ := NewOrderedWordIterator(doc)
+	for topic := 0; !iter.Done(); iter.Next() {
+		iter.SetTopic(topic % kNumTopics)
+		topic++
+	}
+	if s := fmt.Sprint(doc.Tokens(), doc.TokenTopics(), doc.topic_histogram); s !=
+		"[zebra apple zebra orange apple] [0 1 2 0 1] [2 2 1]" {
+		t.Errorf("Unexpected tokens and topics: %s", s)
+	}
+	topics := ""
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		topics += fmt.Sprintf("%d ", iter.Topic())
+	}
+	if topics != "1 1 0 0 2 " {
+		t.Errorf("Unexpected topics: %s", topics)
+	}
+}
+
+func TestSampleInOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 40, 2)
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(rng)
+	}
+	model := CreateModel(2, corpus)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	sampler.SetRand(rng)
+	sampler.SetSampleInOrder(true)
+	for iter := 0; iter < 50; iter++ {
+		sampler.CorpusGibbsSampling(corpus, true, true)
+	}
+	checkTwoTopicModel(t, model)
+}
+
+func TestSaveFile(t *testing.T) {
+	const kTmpFile = "/tmp/tmp_save_file.txt"
+	if err := SaveFile(kTmpFile, "apple <orange>\n"); err != nil {
+		t.Errorf("Cannot write to: " + kTmpFile + " due to " + err.String())
+	}
+	if content, err := ioutil.ReadFile(kTmpFile); err != nil || string(content) != "apple <orange>\n" {
+		t.Errorf("Unexpected content of %s: %q, %v", kTmpFile, content, err)
+	}
+	if err := SaveFile("/nonexistent/file.txt", "apple"); err == nil {
+		t.Errorf("Expecting an error in saving into a nonexistent directory")
+	}
+}
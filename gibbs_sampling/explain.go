@@ -0,0 +1,216 @@
+package lda
+
+import (
+	"fmt"
+	"json"
+	"os"
+	"strings"
+)
+
+// Formats of explanations by FormatExplanations.
+const (
+	kJSONExplanation = "json" // A JSON array of tokens per line.
+	kANSIExplanation = "ansi" // Words colored by topics on terminals.
+	kHTMLExplanation = "html" // A page of words highlighted by topics.
+)
+
+// The foreground colors of topics in ANSI explanations, cycled over
+// topics.
+var kANSITopicColors = []int{31, 32, 33, 34, 35, 36, 91, 92, 93, 94, 95, 96}
+
+// The background colors of topics in HTML explanations, cycled over
+// topics.
+var kHTMLTopicColors = []string{"#aec7e8", "#ffbb78", "#98df8a", "#ff9896", "#c5b0d5",
+	"#c49c94", "#f7b6d2", "#c7c7c7", "#dbdb8d", "#9edae5"}
+
+// The topic of a token of a document, and why: its posterior distribution
+// over topics given the other tokens.
+type TokenTopic struct {
+	Word      string
+	Topic     int
+	Posterior Distribution
+}
+
+// Returns the posterior distribution over topics of every occurrence of
+// doc, given the topics of the others, in the order of doc.wordtopics.
+// update_model is whether the model counts include doc, as in training.
+func (sampler *Sampler) occurrencePosteriors(doc *Document, update_model bool) []Distribution {
+	posteriors := make([]Distribution, 0, doc.Length())
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		posterior := sampler.GenerateTopicDistributionForWord(
+			doc, iter.Word(), iter.Topic(), update_model)
+		sum := posterior.Sum()
+		for k := range posterior {
+			posterior[k] /= sum
+		}
+		posteriors = append(posteriors, posterior)
+	}
+	return posteriors
+}
+
+// Returns the current topics of the words of doc, in their original
+// order, with their posteriors given the other words, e.g., to explain
+// the final state of training.  update_model is whether the model counts
+// include doc.
+func (sampler *Sampler) TokenTopics(doc *Document, update_model bool) []TokenTopic {
+	posteriors := sampler.occurrencePosteriors(doc, update_model)
+	result := make([]TokenTopic, 0, doc.Length())
+	for iter, _ := NewOrderedWordIterator(doc); !iter.Done(); iter.Next() {
+		result = append(result, TokenTopic{iter.Word(), iter.Topic(), posteriors[iter.occurrence()]})
+	}
+	return result
+}
+
+// Infer the topics of doc as InferTopicDistribution does, and returns the
+// modal topic of every word over the accumulate_iterations samples, in
+// their original order, with its posterior given the other words
+// averaged over the samples.  Ties of modal topics are broken by the
+// posteriors.
+func (sampler *Sampler) InferTokenTopics(doc *Document,
+	burn_in_iterations int, accumulate_iterations int) []TokenTopic {
+	if len(doc.topic_histogram) != sampler.model.NumTopics() {
+		panic(fmt.Sprintf("doc has (%d) topics; model has (%d) topics.",
+			len(doc.topic_histogram), sampler.model.NumTopics()))
+	}
+	if accumulate_iterations <= 0 {
+		panic("accumulate_iterations must be positive")
+	}
+
+	doc.RandomizeTopics(sampler.rng)
+	for iter := 0; iter < burn_in_iterations; iter++ {
+		sampler.DocumentGibbsSampling(doc, false)
+	}
+	votes := make([]Histogram, doc.Length())
+	posteriors := make([]Distribution, doc.Length())
+	for o := range votes {
+		votes[o] = NewHistogram(len(doc.topic_histogram))
+		posteriors[o] = NewDistribution(len(doc.topic_histogram))
+	}
+	for iter := 0; iter < accumulate_iterations; iter++ {
+		sampler.DocumentGibbsSampling(doc, false)
+		for o, posterior := range sampler.occurrencePosteriors(doc, false) {
+			votes[o][doc.wordtopics[o]]++
+			for k, p := range posterior {
+				posteriors[o][k] += p / float64(accumulate_iterations)
+			}
+		}
+	}
+
+	result := make([]TokenTopic, 0, doc.Length())
+	for iter, _ := NewOrderedWordIterator(doc); !iter.Done(); iter.Next() {
+		o := iter.occurrence()
+		mode := 0
+		for k, c := range votes[o] {
+			if c > votes[o][mode] || (c == votes[o][mode] && posteriors[o][k] > posteriors[o][mode]) {
+				mode = k
+			}
+		}
+		result = append(result, TokenTopic{iter.Word(), mode, posteriors[o]})
+	}
+	return result
+}
+
+// Returns text with &, <, > and " escaped, for HTML text and attribute
+// values.
+func EscapeHTML(text string) string {
+	return escapeHTML(text)
+}
+
+func escapeHTML(text string) string {
+	for _, r := range [][]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {"\"", "&quot;"}} {
+		text = strings.Replace(text, r[0], r[1], -1)
+	}
+	return text
+}
+
+// Returns the explanations of documents, i.e., their tokens with topics,
+// in format (kJSONExplanation, kANSIExplanation or kHTMLExplanation).
+// JSON and ANSI explanations have a line per document; JSON tokens are
+// objects of word, topic and posterior.  ANSI and HTML ones color words
+// by topics, and list the colors of topics, named by topic_names if not
+// nil.
+func FormatExplanations(explanations [][]TokenTopic, format string, topic_names []string) (
+	string, os.Error) {
+	label := func(topic int) string {
+		if topic_names != nil {
+			return fmt.Sprintf("%d %s", topic, topic_names[topic])
+		}
+		return fmt.Sprintf("%d", topic)
+	}
+	// The topics of tokens, for the legend.
+	var used []bool
+	for _, tokens := range explanations {
+		for _, t := range tokens {
+			if used == nil {
+				used = make([]bool, len(t.Posterior))
+			}
+			used[t.Topic] = true
+		}
+	}
+
+	result := ""
+	switch format {
+	case kJSONExplanation:
+		for _, tokens := range explanations {
+			objects := make([]map[string]interface{}, len(tokens))
+			for i, t := range tokens {
+				objects[i] = map[string]interface{}{
+					"word":      t.Word,
+					"topic":     t.Topic,
+					"posterior": t.Posterior,
+				}
+			}
+			line, err := json.Marshal(objects)
+			if err != nil {
+				return "", err
+			}
+			result += string(line) + "\n"
+		}
+	case kANSIExplanation:
+		color := func(topic int, text string) string {
+			return fmt.Sprintf("\033[%dm%s\033[0m", kANSITopicColors[topic%len(kANSITopicColors)], text)
+		}
+		for k, u := range used {
+			if u {
+				result += color(k, "topic "+label(k)) + "\n"
+			}
+		}
+		for _, tokens := range explanations {
+			words := make([]string, len(tokens))
+			for i, t := range tokens {
+				words[i] = color(t.Topic, t.Word)
+			}
+			result += strings.Join(words, " ") + "\n"
+		}
+	case kHTMLExplanation:
+		span := func(topic int, text string, title string) string {
+			return fmt.Sprintf("<span style=\"background:%s\" title=\"%s\">%s</span>",
+				kHTMLTopicColors[topic%len(kHTMLTopicColors)], escapeHTML(title), escapeHTML(text))
+		}
+		result += "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">" +
+			"<title>Topics of tokens</title></head>\n<body>\n<p>"
+		for k, u := range used {
+			if u {
+				result += span(k, "topic "+label(k), "") + " "
+			}
+		}
+		result += "</p>\n"
+		for _, tokens := range explanations {
+			words := make([]string, len(tokens))
+			for i, t := range tokens {
+				title := fmt.Sprintf("topic %s: %.3f", label(t.Topic), t.Posterior[t.Topic])
+				for _, tp := range t.Posterior.TopTopics(3) {
+					if tp.Topic != t.Topic {
+						title += fmt.Sprintf(", topic %s: %.3f", label(tp.Topic), tp.Probability)
+					}
+				}
+				words[i] = span(t.Topic, t.Word, title)
+			}
+			result += "<p>" + strings.Join(words, " ") + "</p>\n"
+		}
+		result += "</body></html>\n"
+	default:
+		return "", os.NewError("Unknown explanation format: " + format)
+	}
+	return result, nil
+}
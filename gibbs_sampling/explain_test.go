@@ -0,0 +1,90 @@
+package lda
+
+import (
+	"json"
+	"rand"
+	"strings"
+	"testing"
+)
+
+// Returns a model with fruits in topic 0 and animals in topic 1.
+func newFruitAnimalModel() *Model {
+	model := NewModel(2)
+	for _, w := range kFruits {
+		model.IncrementTopic(w, 0, 100)
+	}
+	for _, w := range kAnimals {
+		model.IncrementTopic(w, 1, 100)
+	}
+	return model
+}
+
+func TestInferTokenTopics(t *testing.T) {
+	sampler := NewSampler(0.1, 0.01, newFruitAnimalModel(), nil)
+	sampler.SetRand(rand.New(rand.NewSource(1)))
+	tokens := strings.Fields("zebra apple zebra orange lion apple")
+	doc, _ := NewDocument(strings.Join(tokens, " "), 2)
+	explanation := sampler.InferTokenTopics(doc, 10, 10)
+	expected := []int{1, 0, 1, 0, 1, 0}
+	for i, e := range explanation {
+		if e.Word != tokens[i] || e.Topic != expected[i] || e.Posterior[e.Topic] < 0.9 ||
+			!e.Posterior.IsValid() {
+			t.Errorf("Unexpected topic of token %d %s: %v", i, tokens[i], e)
+		}
+	}
+}
+
+func TestTokenTopics(t *testing.T) {
+	tokens := strings.Fields("lion apple lion")
+	doc, _ := NewDocument(strings.Join(tokens, " "), 2)
+	// NewDocument sorts the tokens into apple lion lion.
+	doc.wordtopics = []int{0, 1, 0}
+	doc.topic_histogram = Histogram{2, 1}
+	model := newFruitAnimalModel()
+	model.AddDocument(doc)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	explanation := sampler.TokenTopics(doc, true)
+	for i, topic := range []int{1, 0, 0} {
+		if explanation[i].Word != tokens[i] || explanation[i].Topic != topic {
+			t.Errorf("Unexpected topic of token %d %s: %v", i, tokens[i], explanation[i])
+		}
+	}
+	// The second lion is assigned topic 0, but topic 1 explains it.
+	if explanation[2].Posterior[1] < 0.9 {
+		t.Errorf("Unexpected posterior of lion: %v", explanation[2].Posterior)
+	}
+}
+
+func TestFormatExplanations(t *testing.T) {
+	explanations := [][]TokenTopic{
+		{{"apple", 0, Distribution{0.9, 0.1}}, {"a<b", 1, Distribution{0.2, 0.8}}},
+		{{"lion", 1, Distribution{0.3, 0.7}}},
+	}
+	text, err := FormatExplanations(explanations, kJSONExplanation, nil)
+	if err != nil {
+		t.Fatalf("Cannot format JSON: " + err.String())
+	}
+	lines := strings.Split(strings.TrimSpace(text), "\n", -1)
+	if len(lines) != 2 {
+		t.Fatalf("Expecting a line per document, but got: " + text)
+	}
+	var tokens []map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &tokens); err != nil || len(tokens) != 2 ||
+		tokens[1]["word"] != "a<b" || tokens[1]["topic"] != 1.0 {
+		t.Errorf("Unexpected JSON: " + lines[0])
+	}
+
+	text, _ = FormatExplanations(explanations, kANSIExplanation, []string{"fruits", "animals"})
+	if strings.Index(text, "\033[31mtopic 0 fruits\033[0m") < 0 ||
+		strings.Index(text, "\033[32mlion\033[0m") < 0 {
+		t.Errorf("Unexpected ANSI explanation: %q", text)
+	}
+	text, _ = FormatExplanations(explanations, kHTMLExplanation, nil)
+	if strings.Index(text, ">a&lt;b</span>") < 0 ||
+		strings.Index(text, "topic 1: 0.800, topic 0: 0.200") < 0 {
+		t.Errorf("Unexpected HTML explanation: " + text)
+	}
+	if _, err := FormatExplanations(explanations, "xml", nil); err == nil {
+		t.Errorf("Expecting an error on an unknown format")
+	}
+}
@@ -0,0 +1,216 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"rand"
+)
+
+// HDPSampler trains a Hierarchical Dirichlet Process topic model (Teh,
+// Jordan, Beal and Blei, 2006) by the direct assignment Gibbs sampler,
+// which infers the number of topics rather than taking it as given.
+// Each word occurrence is assigned an existing topic, or a new one; after
+// every sweep of the corpus, topics left without words are removed.
+//
+// The global topic weights beta are drawn from a stick-breaking process
+// with concentration gamma, and topic distributions of documents from a
+// Dirichlet process with concentration alpha around beta.  beta has a
+// weight per topic, plus that of all unused topics.
+type HDPSampler struct {
+	alpha      float64
+	gamma      float64
+	word_prior float64
+	num_words  int // The size of the vocabulary of corpus.
+	corpus     *Corpus
+	model      *Model
+	beta       Distribution
+	rng        *rand.Rand // nil means the global random source
+}
+
+// Create an HDPSampler training on corpus, starting from the current
+// topic assignments of its documents, which must all have the same
+// number of topics and no allowed topics.  This number is only the
+// initial number of topics.
+func NewHDPSampler(alpha float64, gamma float64, word_prior float64, corpus *Corpus) *HDPSampler {
+	if alpha <= 0 || gamma <= 0 || word_prior <= 0 {
+		panic("alpha, gamma and word_prior must be positive")
+	}
+	if len(*corpus) == 0 {
+		panic("Empty corpus")
+	}
+	num_topics := len((*corpus)[0].topic_histogram)
+	for _, doc := range *corpus {
+		if len(doc.topic_histogram) != num_topics || doc.allowed_topics != nil {
+			panic("Documents must have the same number of topics and no allowed topics")
+		}
+	}
+	model := CreateModel(num_topics, corpus)
+	beta := NewDistribution(num_topics + 1)
+	for k := range beta {
+		beta[k] = 1 / float64(len(beta))
+	}
+	return &HDPSampler{alpha, gamma, word_prior, model.NumWords(), corpus, model, beta, nil}
+}
+
+// Make the sampler draw from rng instead of the global random source.
+func (sampler *HDPSampler) SetRand(rng *rand.Rand) {
+	sampler.rng = rng
+}
+
+func (sampler *HDPSampler) NumTopics() int {
+	return sampler.model.NumTopics()
+}
+
+// Returns the model of the current sample.  It is modified by Iterate.
+func (sampler *HDPSampler) Model() *Model {
+	return sampler.model
+}
+
+// Run a Gibbs sampling sweep over the corpus, remove topics left without
+// words, and resample the global topic weights.
+func (sampler *HDPSampler) Iterate() {
+	for _, doc := range *sampler.corpus {
+		sampler.documentGibbsSampling(doc)
+	}
+	sampler.compactTopics()
+	sampler.sampleBeta()
+}
+
+func (sampler *HDPSampler) documentGibbsSampling(doc *Document) {
+	sampler.growDocumentTopics(doc)
+	v := float64(sampler.num_words)
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		num_topics := sampler.model.NumTopics()
+		word_histogram := sampler.model.GetWordTopicHistogram(iter.Word())
+		global_histogram := sampler.model.GetGlobalTopicHistogram()
+		old_topic := iter.Topic()
+
+		// The last entry is the probability of a new topic, whose word
+		// distribution is the prior.
+		distribution := NewDistribution(num_topics + 1)
+		for k := 0; k < num_topics; k++ {
+			adjustment := 0
+			if k == old_topic {
+				adjustment = -1
+			}
+			distribution[k] =
+				(float64(doc.topic_histogram[k]+adjustment) + sampler.alpha*sampler.beta[k]) *
+					(float64(word_histogram[k]+adjustment) + sampler.word_prior) /
+					(float64(global_histogram[k]+adjustment) + v*sampler.word_prior)
+		}
+		distribution[num_topics] = sampler.alpha * sampler.beta[num_topics] / v
+
+		new_topic := GetAccumulativeSampleWithRand(distribution, sampler.rng)
+		if new_topic == -1 {
+			panic(fmt.Sprintf("Cannot sample from: %v", distribution))
+		}
+		if new_topic == num_topics {
+			sampler.addTopic()
+			sampler.growDocumentTopics(doc)
+		}
+		sampler.model.ReassignTopic(iter.Word(), old_topic, new_topic)
+		iter.SetTopic(new_topic)
+	}
+}
+
+// Add a topic, taking a Beta(1, gamma) fraction of the weight of unused
+// topics, as in stick-breaking.
+func (sampler *HDPSampler) addTopic() {
+	sampler.model.AddTopic()
+	b := randGamma(sampler.rng, 1)
+	b /= b + randGamma(sampler.rng, sampler.gamma)
+	unused := sampler.beta[len(sampler.beta)-1]
+	sampler.beta[len(sampler.beta)-1] = b * unused
+	sampler.beta = append(sampler.beta, (1-b)*unused)
+}
+
+// Documents learn about topics added to the model lazily, when sampled.
+func (sampler *HDPSampler) growDocumentTopics(doc *Document) {
+	for len(doc.topic_histogram) < sampler.model.NumTopics() {
+		doc.addTopic()
+	}
+}
+
+// Remove topics without words, unless fewer than two topics would remain.
+// Afterwards, all documents have the topics of the model.
+func (sampler *HDPSampler) compactTopics() {
+	for _, doc := range *sampler.corpus {
+		sampler.growDocumentTopics(doc)
+	}
+	num_live_topics := 0
+	for _, c := range sampler.model.GetGlobalTopicHistogram() {
+		if c > 0 {
+			num_live_topics++
+		}
+	}
+	if num_live_topics < 2 || num_live_topics == sampler.model.NumTopics() {
+		return
+	}
+	mapping := sampler.model.CompactTopics()
+	for _, doc := range *sampler.corpus {
+		doc.remapTopics(mapping, num_live_topics)
+	}
+	beta := NewDistribution(num_live_topics + 1)
+	for k, new_k := range mapping {
+		if new_k >= 0 {
+			beta[new_k] = sampler.beta[k]
+		} else {
+			beta[num_live_topics] += sampler.beta[k]
+		}
+	}
+	beta[num_live_topics] += sampler.beta[len(sampler.beta)-1]
+	sampler.beta = beta
+}
+
+// Draw beta from its posterior, Dirichlet(m_1, ..., m_K, gamma), where
+// m_k is the number of tables serving topic k in the Chinese restaurant
+// franchise, drawn from the Antoniak distribution of every document.
+func (sampler *HDPSampler) sampleBeta() {
+	num_topics := sampler.model.NumTopics()
+	tables := make([]int, num_topics)
+	for _, doc := range *sampler.corpus {
+		for k, n := range doc.topic_histogram {
+			weight := sampler.alpha * sampler.beta[k]
+			for i := 0; i < n; i++ {
+				if randFloat64(sampler.rng) < weight/(weight+float64(i)) {
+					tables[k]++
+				}
+			}
+		}
+	}
+	sum := 0.0
+	for k := range sampler.beta {
+		if k < num_topics {
+			sampler.beta[k] = randGamma(sampler.rng, float64(tables[k]))
+		} else {
+			sampler.beta[k] = randGamma(sampler.rng, sampler.gamma)
+		}
+		sum += sampler.beta[k]
+	}
+	for k := range sampler.beta {
+		sampler.beta[k] /= sum
+	}
+}
+
+// Returns the log-likelihood of the corpus given the current sample.
+func (sampler *HDPSampler) CorpusLogLikelihood() float64 {
+	num_topics := sampler.model.NumTopics()
+	v := float64(sampler.num_words)
+	global_histogram := sampler.model.GetGlobalTopicHistogram()
+	log_likelihood := 0.0
+	for _, doc := range *sampler.corpus {
+		normalizer := float64(doc.Length()) + sampler.alpha
+		for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+			word_histogram := sampler.model.GetWordTopicHistogram(iter.Word())
+			prob_word := sampler.alpha * sampler.beta[num_topics] / normalizer / v
+			for k := 0; k < num_topics; k++ {
+				prob_word +=
+					(float64(doc.topic_histogram[k]) + sampler.alpha*sampler.beta[k]) / normalizer *
+						(float64(word_histogram[k]) + sampler.word_prior) /
+						(float64(global_histogram[k]) + v*sampler.word_prior)
+			}
+			log_likelihood += math.Log(prob_word)
+		}
+	}
+	return log_likelihood
+}
@@ -0,0 +1,50 @@
+package lda
+
+import (
+	"rand"
+	"testing"
+)
+
+func TestHDPSamplerFindsTopics(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// Start with more topics than needed, so that HDP has to remove some.
+	corpus := newTwoTopicCorpus(rng, 40, 8)
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(rng)
+	}
+	sampler := NewHDPSampler(1.0, 1.0, 0.01, corpus)
+	sampler.SetRand(rng)
+	for iter := 0; iter < 100; iter++ {
+		sampler.Iterate()
+	}
+
+	// HDP may split fruits or animals into several topics, but should
+	// remove unneeded topics, and never mix fruits and animals.
+	model := sampler.Model()
+	if n := sampler.NumTopics(); n < 2 || n >= 8 {
+		t.Errorf("Expecting 2 to 7 topics, but got %d", n)
+	}
+	for k := 0; k < model.NumTopics(); k++ {
+		fruits, animals := 0, 0
+		for _, w := range kFruits {
+			fruits += model.GetWordTopicHistogram(w)[k]
+		}
+		for _, w := range kAnimals {
+			animals += model.GetWordTopicHistogram(w)[k]
+		}
+		if fruits > 0 && animals > 0 && fruits+animals > 20 {
+			t.Errorf("Topic %d mixes %d fruits and %d animals", k, fruits, animals)
+		}
+	}
+	for _, doc := range *corpus {
+		if len(doc.topic_histogram) != sampler.NumTopics() {
+			t.Errorf("Document has %d topics; model has %d topics",
+				len(doc.topic_histogram), sampler.NumTopics())
+		}
+	}
+	for _, c := range sampler.Model().GetGlobalTopicHistogram() {
+		if c == 0 {
+			t.Errorf("Empty topic in model: %v", sampler.Model().GetGlobalTopicHistogram())
+		}
+	}
+}
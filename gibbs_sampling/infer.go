@@ -0,0 +1,58 @@
+package lda
+
+import "fmt"
+
+// Infer the topic distribution of doc by folding it into the sampler's
+// model, i.e., Gibbs sampling the topics of doc's words while keeping
+// the model fixed (update_model=false).  The document's topics are
+// randomly initialized, sampled for burn_in_iterations, and then the
+// document topic histogram is averaged over accumulate_iterations
+// further iterations.  Since the model is not modified, a read-only
+// model can be shared by concurrent inferences, as long as each uses
+// its own Sampler and random source.  Topics not allowed in doc have
+// zero probability.  The topic prior of doc, if any, is used.
+func (sampler *Sampler) InferTopicDistribution(doc *Document,
+	burn_in_iterations int, accumulate_iterations int) Distribution {
+	num_topics := sampler.model.NumTopics()
+	if len(doc.topic_histogram) != num_topics {
+		panic(fmt.Sprintf("doc has (%d) topics; model has (%d) topics.",
+			len(doc.topic_histogram), num_topics))
+	}
+	if accumulate_iterations <= 0 {
+		panic("accumulate_iterations must be positive")
+	}
+
+	accum_histogram := sampler.averageTopicHistogram(doc, burn_in_iterations,
+		accumulate_iterations)
+
+	distribution := NewDistribution(num_topics)
+	normalizer := float64(doc.Length()) + sampler.documentTopicPriorSum(doc)
+	for k := 0; k < num_topics; k++ {
+		if doc.IsTopicAllowed(k) {
+			distribution[k] = (accum_histogram[k] + sampler.documentTopicPrior(doc, k)) / normalizer
+		}
+	}
+	return distribution
+}
+
+// Fold doc into the model as InferTopicDistribution does, and returns its
+// topic histogram averaged over accumulate_iterations.
+func (sampler *Sampler) averageTopicHistogram(doc *Document,
+	burn_in_iterations int, accumulate_iterations int) Distribution {
+	doc.RandomizeTopics(sampler.rng)
+	for iter := 0; iter < burn_in_iterations; iter++ {
+		sampler.DocumentGibbsSampling(doc, false)
+	}
+
+	accum_histogram := NewDistribution(len(doc.topic_histogram))
+	for iter := 0; iter < accumulate_iterations; iter++ {
+		sampler.DocumentGibbsSampling(doc, false)
+		for k, c := range doc.topic_histogram {
+			accum_histogram[k] += float64(c)
+		}
+	}
+	for k := range accum_histogram {
+		accum_histogram[k] /= float64(accumulate_iterations)
+	}
+	return accum_histogram
+}
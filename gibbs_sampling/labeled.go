@@ -0,0 +1,112 @@
+package lda
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The prefix of the names of latent topics in Labeled LDA.
+const kLatentTopicNamePrefix = "latent_"
+
+// Load a corpus for Labeled LDA (Ramage et al., 2009), where each line
+// has the form
+//
+// label_1,label_2,...<TAB>text
+//
+// Every distinct label is a topic; labels are sorted and assigned topics
+// 0, 1, ..., followed by num_latent_topics latent topics shared by all
+// documents.  Words of a document may be assigned only the topics of its
+// labels and the latent topics.  A document without labels must have
+// latent topics.  Returns the corpus and the names of all topics, i.e.,
+// the labels followed by latent_0, latent_1, ....
+func LoadLabeledCorpus(filename string, num_latent_topics int) (
+	corpus *Corpus, topic_names []string, err os.Error) {
+	if num_latent_topics < 0 {
+		return nil, nil, os.NewError("num_latent_topics must be non-negative")
+	}
+
+	texts := make([]string, 0)
+	doc_labels := make([][]string, 0)
+	label_topics := make(map[string]int)
+	err = readLines(filename, func(line string) os.Error {
+		metadata, text, err := splitMetadata(line)
+		if err != nil {
+			return err
+		}
+		labels, err := parseLabels(metadata)
+		if err != nil {
+			return err
+		}
+		if len(labels) == 0 && num_latent_topics == 0 {
+			return os.NewError("Document has neither labels nor latent topics: " + line)
+		}
+		for _, label := range labels {
+			label_topics[label] = -1
+		}
+		texts = append(texts, text)
+		doc_labels = append(doc_labels, labels)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	topic_names = make([]string, 0, len(label_topics)+num_latent_topics)
+	for label := range label_topics {
+		topic_names = append(topic_names, label)
+	}
+	sort.SortStrings(topic_names)
+	for k, label := range topic_names {
+		label_topics[label] = k
+	}
+	num_labels := len(topic_names)
+	for i := 0; i < num_latent_topics; i++ {
+		topic_names = append(topic_names, kLatentTopicNamePrefix+strconv.Itoa(i))
+	}
+	num_topics := len(topic_names)
+	if num_topics < 2 {
+		return nil, nil, os.NewError("Labeled corpus needs >= 2 topics: " + filename)
+	}
+
+	corpus = NewCorpus()
+	for i, text := range texts {
+		doc, err := NewDocument(text, num_topics)
+		if err != nil {
+			return nil, nil, os.NewError("Cannot create document from: " + text +
+				" due to " + err.String())
+		}
+		allowed_topics := make([]int, 0, len(doc_labels[i])+num_latent_topics)
+		for _, label := range doc_labels[i] {
+			allowed_topics = append(allowed_topics, label_topics[label])
+		}
+		for k := num_labels; k < num_topics; k++ {
+			allowed_topics = append(allowed_topics, k)
+		}
+		if err := doc.SetAllowedTopics(allowed_topics); err != nil {
+			return nil, nil, err
+		}
+		*corpus = append(*corpus, doc)
+	}
+	return corpus, topic_names, nil
+}
+
+// Parse comma separated labels, ignoring empty and duplicated ones.
+// Labels must not contain whitespaces.
+func parseLabels(metadata string) (labels []string, err os.Error) {
+	labels = make([]string, 0)
+	seen := make(map[string]bool)
+	for _, label := range strings.Split(metadata, ",", -1) {
+		label = strings.TrimSpace(label)
+		if len(label) == 0 || seen[label] {
+			continue
+		}
+		if len(strings.Fields(label)) != 1 {
+			return nil, os.NewError("Invalid label: \"" + label + "\"")
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
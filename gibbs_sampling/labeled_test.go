@@ -0,0 +1,76 @@
+package lda
+
+import (
+	"fmt"
+	"testing"
+)
+
+const kLabeledCorpusFile = "testdata/labeled_corpus.txt"
+
+func TestLoadLabeledCorpus(t *testing.T) {
+	if _, _, err := LoadLabeledCorpus(kLabeledCorpusFile, 0); err == nil {
+		t.Errorf("Expecting an error for a document without labels or latent topics")
+	}
+
+	corpus, topic_names, err := LoadLabeledCorpus(kLabeledCorpusFile, 1)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kLabeledCorpusFile + " : " + err.String())
+	}
+	if names := fmt.Sprintf("%v", topic_names); names != "[animals fruits latent_0]" {
+		t.Errorf("Unexpected topic names: %s", names)
+	}
+	const kLabeledCorpusGoFmt = "{[apple banana orange] [0 2 3] [1 1 1 1] [0 2 3 1] [0 3 1 2] [0 4 0] [1 2] []}," +
+		"{[monky zebra] [0 1] [0 0 0] [1 0 2] [1 0 2] [3 0 0] [0 2] []}," +
+		"{[apple monky orange zebra] [0 1 2 3] [0 0 0 0] [0 3 2 1] [0 3 2 1] [4 0 0] [0 1 2] []}," +
+		"{[grape orange] [0 1] [2 2] [1 0] [1 0] [0 0 2] [2] []}"
+	corpus_gofmt := fmt.Sprintf("%v,%v,%v,%v", *(*corpus)[0], *(*corpus)[1], *(*corpus)[2], *(*corpus)[3])
+	if corpus_gofmt != kLabeledCorpusGoFmt {
+		t.Errorf("Expecting: " + kLabeledCorpusGoFmt + ", but got: " + corpus_gofmt)
+	}
+}
+
+func TestLabeledGibbsSampling(t *testing.T) {
+	corpus, topic_names, _ := LoadLabeledCorpus(kLabeledCorpusFile, 1)
+	model := CreateModel(len(topic_names), corpus)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	for iter := 0; iter < 10; iter++ {
+		sampler.CorpusGibbsSampling(corpus, true, true)
+	}
+	for _, doc := range *corpus {
+		for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+			if !doc.IsTopicAllowed(iter.Topic()) {
+				t.Errorf("Word %s of %v is assigned a disallowed topic %d",
+					iter.Word(), *doc, iter.Topic())
+			}
+		}
+	}
+	// Words of the first two documents can only be their label topics
+	// or the latent topic.
+	if hist := model.GetWordTopicHistogram("banana"); hist[0] != 0 {
+		t.Errorf("banana is assigned topic animals: %v", hist)
+	}
+	if hist := model.GetWordTopicHistogram("grape"); hist[2] != 1 {
+		t.Errorf("grape is not assigned the latent topic: %v", hist)
+	}
+}
+
+func TestSetAllowedTopics(t *testing.T) {
+	doc, _ := NewDocument("apple orange apple", 3)
+	for _, topics := range [][]int{{}, {3}, {-1}, {1, 1}} {
+		if err := doc.SetAllowedTopics(topics); err == nil {
+			t.Errorf("Expecting an error setting allowed topics %v", topics)
+		}
+	}
+	if err := doc.SetAllowedTopics([]int{2, 1}); err != nil {
+		t.Errorf("Cannot set allowed topics: " + err.String())
+	}
+	if doc.IsTopicAllowed(0) || !doc.IsTopicAllowed(1) || doc.NumAllowedTopics() != 2 {
+		t.Errorf("Unexpected allowed topics: %v", *doc)
+	}
+	for i := 0; i < 10; i++ {
+		doc.RandomizeTopics(nil)
+		if doc.topic_histogram[0] != 0 {
+			t.Errorf("Disallowed topic is assigned: %v", *doc)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package lda
+
+import (
+	"fmt"
+	"json"
+	"math"
+	"os"
+	"sort"
+)
+
+// The step of the relevance weight lambda in the LDAvis slider.
+const kLDAvisLambdaStep = 0.01
+
+// The relevance weights for which the top terms of every topic are
+// included in the LDAvis payload.  Terms relevant only for other weights
+// are not shown, which keeps the payload small for large vocabularies.
+var kLDAvisLambdas = []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// The minimum P(topic|term) of rows in the token table of LDAvis.
+const kLDAvisMinTopicProbability = 0.01
+
+// LDAvis holds the data of an LDAvis (Sievert and Shirley, 2014)
+// visualization of a model and its training corpus: the topic-term
+// distributions, the topic distributions of documents, and the
+// positions of topics on a plane, by classical multidimensional scaling
+// (MDS) of the Jensen-Shannon divergences between topics.
+type LDAvis struct {
+	vocab            []string       // Words of the model occurring in the corpus, sorted.
+	term_frequency   []int          // By term in vocab.
+	doc_lengths      []int
+	topic_term_dists []Distribution // P(term|topic), over vocab.
+	doc_topic_dists  []Distribution
+	topic_frequency  []float64      // The expected number of tokens of each topic.
+	topic_distances  [][]float64
+	coordinates      [][]float64    // Two-dimensional, by topic.
+	num_terms        int            // The number of terms shown per topic.
+}
+
+type indexedValue struct {
+	index int
+	value float64
+}
+
+// Sorts in descending order of value, and ascending index among ties.
+type indexedValueArray []indexedValue
+
+func (a indexedValueArray) Len() int      { return len(a) }
+func (a indexedValueArray) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a indexedValueArray) Less(i, j int) bool {
+	if a[i].value != a[j].value {
+		return a[i].value > a[j].value
+	}
+	return a[i].index < a[j].index
+}
+
+// Returns indices of values in descending order of the values.
+func sortIndicesByValue(values []float64) []int {
+	pairs := make(indexedValueArray, len(values))
+	for i, v := range values {
+		pairs[i] = indexedValue{i, v}
+	}
+	sort.Sort(pairs)
+	indices := make([]int, len(values))
+	for i, p := range pairs {
+		indices[i] = p.index
+	}
+	return indices
+}
+
+// Prepare the LDAvis of model and corpus, with the topic distributions
+// of documents in corpus, e.g., inferred by Sampler, and num_terms terms
+// shown per topic.  P(term|topic) is smoothed by the symmetric
+// word_prior, and normalized over the words of model occurring in corpus,
+// i.e., the terms shown.
+func NewLDAvis(model *Model, corpus *Corpus, doc_topic_dists []Distribution,
+	word_prior float64, num_terms int) *LDAvis {
+	if len(*corpus) != len(doc_topic_dists) {
+		panic(fmt.Sprintf("%d documents, but %d topic distributions",
+			len(*corpus), len(doc_topic_dists)))
+	}
+	num_topics := model.NumTopics()
+	vis := &LDAvis{doc_topic_dists: doc_topic_dists, num_terms: num_terms}
+
+	counts := make(map[string]int)
+	vis.doc_lengths = make([]int, len(*corpus))
+	vis.topic_frequency = make([]float64, num_topics)
+	for d, doc := range *corpus {
+		for i, w := range doc.unique_words {
+			counts[w] += doc.uniqueWordCount(i)
+		}
+		vis.doc_lengths[d] = doc.Length()
+		for k, p := range doc_topic_dists[d] {
+			vis.topic_frequency[k] += p * float64(doc.Length())
+		}
+	}
+	vis.vocab = make([]string, 0)
+	vis.term_frequency = make([]int, 0)
+	for _, w := range model.Words() {
+		if counts[w] > 0 {
+			vis.vocab = append(vis.vocab, w)
+			vis.term_frequency = append(vis.term_frequency, counts[w])
+		}
+	}
+
+	vis.topic_term_dists = model.topicWordDistributions(vis.vocab, word_prior)
+	for _, dist := range vis.topic_term_dists {
+		sum := dist.Sum()
+		for t := range dist {
+			dist[t] /= sum
+		}
+	}
+
+	vis.topic_distances = make([][]float64, num_topics)
+	for k := range vis.topic_distances {
+		vis.topic_distances[k] = make([]float64, num_topics)
+		for j := 0; j < k; j++ {
+			vis.topic_distances[k][j] = JensenShannonDivergence(
+				vis.topic_term_dists[k], vis.topic_term_dists[j])
+			vis.topic_distances[j][k] = vis.topic_distances[k][j]
+		}
+	}
+	vis.coordinates = ClassicalMDS(vis.topic_distances, 2)
+	return vis
+}
+
+// Returns the Jensen-Shannon divergences between topics.
+func (vis *LDAvis) TopicDistances() [][]float64 {
+	return vis.topic_distances
+}
+
+// Returns the positions of topics on the plane.
+func (vis *LDAvis) Coordinates() [][]float64 {
+	return vis.coordinates
+}
+
+// Returns P(topic|term) of every term, by term and topic.
+func (vis *LDAvis) termTopicProbabilities() [][]float64 {
+	result := make([][]float64, len(vis.vocab))
+	for t := range vis.vocab {
+		result[t] = make([]float64, len(vis.topic_term_dists))
+		sum := 0.0
+		for k, dist := range vis.topic_term_dists {
+			result[t][k] = dist[t] * vis.topic_frequency[k]
+			sum += result[t][k]
+		}
+		for k := range result[t] {
+			result[t][k] /= sum
+		}
+	}
+	return result
+}
+
+// Returns the JSON payload of LDAvis, i.e., the fields of LDAvis.js
+// (mdsDat, tinfo, token.table, R, lambda.step, plot.opts and
+// topic.order), where topics are numbered from 1 in descending order of
+// frequency, along with the inputs of pyLDAvis.prepare (topic_term_dists,
+// doc_topic_dists, doc_lengths, vocab and term_frequency) and
+// topic_distances, by the original topic indices.
+func (vis *LDAvis) JSON() ([]byte, os.Error) {
+	num_topics := len(vis.topic_term_dists)
+	order := sortIndicesByValue(vis.topic_frequency)
+	total_tokens := 0
+	for _, c := range vis.term_frequency {
+		total_tokens += c
+	}
+	total_frequency := 0.0
+	for _, f := range vis.topic_frequency {
+		total_frequency += f
+	}
+	term_topic := vis.termTopicProbabilities()
+
+	mds := map[string][]interface{}{}
+	topic_order := make([]int, num_topics)
+	for i, k := range order {
+		topic_order[i] = k + 1
+		mds["x"] = append(mds["x"], vis.coordinates[k][0])
+		mds["y"] = append(mds["y"], vis.coordinates[k][1])
+		mds["topics"] = append(mds["topics"], i+1)
+		mds["cluster"] = append(mds["cluster"], 1)
+		mds["Freq"] = append(mds["Freq"], 100*vis.topic_frequency[k]/total_frequency)
+	}
+
+	tinfo := map[string][]interface{}{}
+	add_term := func(t int, freq float64, category string, logprob float64, loglift float64) {
+		tinfo["Term"] = append(tinfo["Term"], vis.vocab[t])
+		tinfo["Freq"] = append(tinfo["Freq"], freq)
+		tinfo["Total"] = append(tinfo["Total"], vis.term_frequency[t])
+		tinfo["Category"] = append(tinfo["Category"], category)
+		tinfo["logprob"] = append(tinfo["logprob"], logprob)
+		tinfo["loglift"] = append(tinfo["loglift"], loglift)
+	}
+	shown := make(map[int]bool)
+
+	// The default terms are the most salient ones (Chuang, Manning and
+	// Heer, 2012), with decreasing placeholder logprob and loglift.
+	saliency := make([]float64, len(vis.vocab))
+	for t := range vis.vocab {
+		distinctiveness := 0.0
+		for k, p := range term_topic[t] {
+			if p > 0 {
+				distinctiveness += p * math.Log(p/(vis.topic_frequency[k]/total_frequency))
+			}
+		}
+		saliency[t] = float64(vis.term_frequency[t]) / float64(total_tokens) * distinctiveness
+	}
+	for i, t := range sortIndicesByValue(saliency) {
+		if i >= vis.num_terms {
+			break
+		}
+		rank := float64(vis.num_terms - i)
+		add_term(t, float64(vis.term_frequency[t]), "Default", rank, rank)
+		shown[t] = true
+	}
+
+	for i, k := range order {
+		logprob := make([]float64, len(vis.vocab))
+		loglift := make([]float64, len(vis.vocab))
+		for t, p := range vis.topic_term_dists[k] {
+			logprob[t] = math.Log(p)
+			loglift[t] = math.Log(p * float64(total_tokens) / float64(vis.term_frequency[t]))
+		}
+		terms := make(map[int]bool)
+		relevance := make([]float64, len(vis.vocab))
+		for _, lambda := range kLDAvisLambdas {
+			for t := range relevance {
+				relevance[t] = lambda*logprob[t] + (1-lambda)*loglift[t]
+			}
+			for j, t := range sortIndicesByValue(relevance) {
+				if j >= vis.num_terms {
+					break
+				}
+				terms[t] = true
+			}
+		}
+		sorted_terms := make([]int, 0, len(terms))
+		for t := range terms {
+			sorted_terms = append(sorted_terms, t)
+		}
+		sort.SortInts(sorted_terms)
+		for _, t := range sorted_terms {
+			add_term(t, term_topic[t][k]*float64(vis.term_frequency[t]),
+				fmt.Sprintf("Topic%d", i+1), logprob[t], loglift[t])
+			shown[t] = true
+		}
+	}
+
+	shown_terms := make([]int, 0, len(shown))
+	for t := range shown {
+		shown_terms = append(shown_terms, t)
+	}
+	sort.SortInts(shown_terms)
+	token_table := map[string][]interface{}{}
+	for _, t := range shown_terms {
+		for i, k := range order {
+			if p := term_topic[t][k]; p >= kLDAvisMinTopicProbability {
+				token_table["TermID"] = append(token_table["TermID"], t+1)
+				token_table["Topic"] = append(token_table["Topic"], i+1)
+				token_table["Freq"] = append(token_table["Freq"], p)
+				token_table["Term"] = append(token_table["Term"], vis.vocab[t])
+			}
+		}
+	}
+
+	payload := map[string]interface{}{
+		"mdsDat":           mds,
+		"tinfo":            tinfo,
+		"token.table":      token_table,
+		"R":                vis.num_terms,
+		"lambda.step":      kLDAvisLambdaStep,
+		"plot.opts":        map[string]string{"xlab": "PC1", "ylab": "PC2"},
+		"topic.order":      topic_order,
+		"topic_term_dists": vis.topic_term_dists,
+		"doc_topic_dists":  vis.doc_topic_dists,
+		"doc_lengths":      vis.doc_lengths,
+		"vocab":            vis.vocab,
+		"term_frequency":   vis.term_frequency,
+		"topic_distances":  vis.topic_distances,
+	}
+	return json.Marshal(payload)
+}
+
+// Returns the coordinates of points in dims dimensions by classical
+// multidimensional scaling, i.e., principal coordinate analysis, of
+// their pairwise distances: the top eigenvectors of the double centered
+// matrix of squared distances, scaled by the square roots of their
+// eigenvalues.  Dimensions with non-positive eigenvalues are 0.
+func ClassicalMDS(distances [][]float64, dims int) [][]float64 {
+	n := len(distances)
+	b := make([][]float64, n)
+	row_means := make([]float64, n)
+	mean := 0.0
+	for i := range b {
+		b[i] = make([]float64, n)
+		for j := range b[i] {
+			b[i][j] = distances[i][j] * distances[i][j]
+			row_means[i] += b[i][j] / float64(n)
+		}
+		mean += row_means[i] / float64(n)
+	}
+	for i := range b {
+		for j := range b[i] {
+			b[i][j] = -(b[i][j] - row_means[i] - row_means[j] + mean) / 2
+		}
+	}
+
+	values, vectors := symmetricEigen(b)
+	coordinates := make([][]float64, n)
+	for i := range coordinates {
+		coordinates[i] = make([]float64, dims)
+	}
+	for d, e := range sortIndicesByValue(values) {
+		if d >= dims || values[e] <= 0 {
+			break
+		}
+		for i := range coordinates {
+			coordinates[i][d] = vectors[e][i] * math.Sqrt(values[e])
+		}
+	}
+	return coordinates
+}
+
+// Returns the eigenvalues of the symmetric matrix a and their unit
+// eigenvectors, vectors[i] for values[i], by the cyclic Jacobi method.
+// a is destroyed.
+func symmetricEigen(a [][]float64) (values []float64, vectors [][]float64) {
+	n := len(a)
+	v := make([][]float64, n) // Columns are the eigenvectors.
+	for i := range v {
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += a[i][j] * a[i][j]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Fabs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p], v[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+
+	values = make([]float64, n)
+	vectors = make([][]float64, n)
+	for i := range values {
+		values[i] = a[i][i]
+		vectors[i] = make([]float64, n)
+		for k := range vectors[i] {
+			vectors[i][k] = v[k][i]
+		}
+	}
+	return values, vectors
+}
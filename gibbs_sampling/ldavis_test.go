@@ -0,0 +1,104 @@
+package lda
+
+import (
+	"json"
+	"math"
+	"rand"
+	"testing"
+)
+
+func TestClassicalMDS(t *testing.T) {
+	// Points of a right triangle, whose distances are recovered in two
+	// dimensions.
+	points := [][]float64{{0, 0}, {3, 0}, {0, 4}, {1, 1}}
+	distances := make([][]float64, len(points))
+	for i, p := range points {
+		distances[i] = make([]float64, len(points))
+		for j, q := range points {
+			distances[i][j] = math.Sqrt((p[0]-q[0])*(p[0]-q[0]) + (p[1]-q[1])*(p[1]-q[1]))
+		}
+	}
+	coordinates := ClassicalMDS(distances, 2)
+	for i, p := range coordinates {
+		for j, q := range coordinates {
+			d := math.Sqrt((p[0]-q[0])*(p[0]-q[0]) + (p[1]-q[1])*(p[1]-q[1]))
+			if math.Fabs(d-distances[i][j]) > 1e-6 {
+				t.Errorf("Distance between %d and %d: expecting %f, but got %f",
+					i, j, distances[i][j], d)
+			}
+		}
+	}
+}
+
+func TestLDAvis(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 40, 3)
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(rng)
+	}
+	model := CreateModel(3, corpus)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	sampler.SetRand(rng)
+	for iter := 0; iter < 30; iter++ {
+		sampler.CorpusGibbsSampling(corpus, true, true)
+	}
+	doc_topic_dists := make([]Distribution, len(*corpus))
+	for d, doc := range *corpus {
+		doc_topic_dists[d] = NewDistribution(3)
+		for k, c := range doc.topic_histogram {
+			doc_topic_dists[d][k] = float64(c) / float64(doc.Length())
+		}
+	}
+
+	vis := NewLDAvis(model, corpus, doc_topic_dists, 0.01, 5)
+	fruit_topic := dominantTopic(model, kFruits[0])
+	animal_topic := dominantTopic(model, kAnimals[0])
+	if d := vis.TopicDistances()[fruit_topic][animal_topic]; d < 0.5 {
+		t.Errorf("Expecting fruits and animals far apart, but got %f", d)
+	}
+
+	encoding, err := vis.JSON()
+	if err != nil {
+		t.Fatalf("Cannot encode: " + err.String())
+	}
+	var payload struct {
+		MdsDat struct {
+			Freq   []float64
+			Topics []int
+		}
+		Tinfo struct {
+			Term     []string
+			Category []string
+		}
+		R     int
+		Vocab []string
+	}
+	if err := json.Unmarshal(encoding, &payload); err != nil {
+		t.Fatalf("Cannot decode: " + err.String())
+	}
+	if len(payload.MdsDat.Topics) != 3 || payload.MdsDat.Freq[0] < payload.MdsDat.Freq[1] {
+		t.Errorf("Expecting 3 topics in descending frequency, but got %v", payload.MdsDat)
+	}
+	if payload.R != 5 || len(payload.Vocab) != len(kFruits)+len(kAnimals) {
+		t.Errorf("Unexpected R %d or vocab %v", payload.R, payload.Vocab)
+	}
+	if len(payload.Tinfo.Term) == 0 || payload.Tinfo.Category[0] != "Default" ||
+		len(payload.Tinfo.Term) != len(payload.Tinfo.Category) {
+		t.Errorf("Unexpected tinfo: %v", payload.Tinfo)
+	}
+}
+
+func TestLDAvisTopicTermDistributions(t *testing.T) {
+	corpus := NewCorpus()
+	for _, text := range []string{"apple orange", "zebra lion"} {
+		doc, _ := NewDocument(text, 2)
+		*corpus = append(*corpus, doc)
+	}
+	model := newFruitAnimalModel() // With words missing from corpus.
+	vis := NewLDAvis(model, corpus, []Distribution{{1, 0}, {0, 1}}, 0.01, 5)
+	for k, dist := range vis.topic_term_dists {
+		if len(dist) != 4 || math.Fabs(dist.Sum()-1) > 1e-9 {
+			t.Errorf("Expecting a distribution over 4 terms of topic %d, but got %v", k, dist)
+		}
+	}
+}
@@ -4,17 +4,43 @@ import (
 	"bufio"
 	"fmt"
 	"encoding/line"
+	"io/ioutil"
 	"os"
+	"rand"
+	"sort"
 	"strings"
 	"strconv"
 )
 
 const kMaxModelFileLineLength = 1024 * 1024 // at most 1MB per line
 
+// The suffix of the file naming the topics of a model file.
+const kTopicNamesFileSuffix = ".topic_names"
+
+// The prefix of the names of topics added to a model with named topics.
+const kNewTopicNamePrefix = "topic_"
+
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+type wordCountArray []WordCount
+
+func (a wordCountArray) Len() int      { return len(a) }
+func (a wordCountArray) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a wordCountArray) Less(i, j int) bool {
+	if a[i].Count != a[j].Count {
+		return a[i].Count > a[j].Count
+	}
+	return a[i].Word < a[j].Word
+}
+
 type Model struct {
 	topic_histograms map[string]Histogram
 	global_histogram Histogram
 	zero_histogram   Histogram
+	topic_names      []string // nil if topics are not named
 }
 
 // Create an empty model with num_topics topics.
@@ -29,12 +55,23 @@ func NewModel(num_topics int) *Model {
 // Create a model by counting topic assignments in a corpus.
 func CreateModel(num_topics int, corpus *Corpus) *Model {
 	model := NewModel(num_topics)
+	model.AddCorpus(corpus)
+	return model
+}
+
+// Add the topic assignments of doc's words into the model counts.
+func (model *Model) AddDocument(doc *Document) {
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		model.IncrementTopic(iter.Word(), iter.Topic(), 1)
+	}
+}
+
+// Add the topic assignments of all documents in corpus into the model
+// counts.
+func (model *Model) AddCorpus(corpus *Corpus) {
 	for _, v := range *corpus {
-		for iter, _ := NewWordIterator(v); !iter.Done(); iter.Next() {
-			model.IncrementTopic(iter.Word(), iter.Topic(), 1)
-		}
+		model.AddDocument(v)
 	}
-	return model
 }
 
 // Load model from a text file, which must be in the following format:
@@ -48,6 +85,16 @@ func CreateModel(num_topics int, corpus *Corpus) *Model {
 // is an integer, counting the number of times that word_x is assigned
 // topic_y.  Fields in a line are separated by one or more whitespaces.
 //
+// If the file filename.topic_names exists, it names the topics:
+//
+// name_of_topic_0
+// name_of_topic_1
+// ...
+//
+// where topic names are non-empty strings containing no whitespaces.
+// Topic names are kept apart from the model file so that its every line
+// is the histogram of a word.
+//
 func LoadModel(filename string) (model *Model, err os.Error) {
 	file, err := os.Open(filename, 0, 0)
 	if err != nil {
@@ -96,7 +143,7 @@ func LoadModel(filename string) (model *Model, err os.Error) {
 		}
 		model.topic_histograms[fields[0]] = hist
 
-		l, _, err = reader.ReadLine()
+		l, is_prefix, err = reader.ReadLine()
 	}
 
 	if err != os.EOF {
@@ -106,27 +153,64 @@ func LoadModel(filename string) (model *Model, err os.Error) {
 		return nil, os.NewError("No valid line in file: " + filename)
 	}
 
+	names_filename := filename + kTopicNamesFileSuffix
+	if _, err := os.Stat(names_filename); err == nil {
+		names, err := ioutil.ReadFile(names_filename)
+		if err != nil {
+			return nil, os.NewError("Cannot read file: " + names_filename + " " + err.String())
+		}
+		if err := model.SetTopicNames(strings.Fields(string(names))); err != nil {
+			return nil, os.NewError("Invalid topic names in " + names_filename + ": " + err.String())
+		}
+	}
+
 	return model, nil
 }
 
+// Save the model in the format of LoadModel, with the names of topics, if
+// any, in filename.topic_names, and otherwise remove that file.  Every
+// file is written into a temporary file which is then renamed, so that
+// readers of filename, e.g., ModelRegistry, never see a partial model.
+// Topic names are saved first, so that they are in place when the model
+// changes.
 func (model *Model) SaveModel(filename string) os.Error {
-	file, err := os.Open(filename, os.O_WRONLY|os.O_CREAT|os.O_TRUNC, 0666)
-	if err != nil {
-		return os.NewError("Cannot open file: " + filename + " " + err.String())
+	names_filename := filename + kTopicNamesFileSuffix
+	if model.topic_names != nil {
+		err := saveFileByRename(names_filename, func(writer *bufio.Writer) {
+			for _, name := range model.topic_names {
+				fmt.Fprintf(writer, "%s\n", name)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(names_filename); err == nil {
+		if err := os.Remove(names_filename); err != nil {
+			return os.NewError("Cannot remove file: " + names_filename + " " + err.String())
+		}
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	defer writer.Flush()
 
-	for k, v := range model.topic_histograms {
-		fmt.Fprintf(writer, "%s", k)
-		for _, c := range v {
-			fmt.Fprintf(writer, " %d", c)
+	return saveFileByRename(filename, func(writer *bufio.Writer) {
+		for k, v := range model.topic_histograms {
+			fmt.Fprintf(writer, "%s", k)
+			for _, c := range v {
+				fmt.Fprintf(writer, " %d", c)
+			}
+			fmt.Fprintf(writer, "\n")
 		}
-		fmt.Fprintf(writer, "\n")
-	}
+	})
+}
 
+// Write filename by write into filename.tmp, which is then renamed to
+// filename.
+func saveFileByRename(filename string, write func(writer *bufio.Writer)) os.Error {
+	tmp_filename := filename + ".tmp"
+	if err := writeFile(tmp_filename, write); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp_filename, filename); err != nil {
+		return os.NewError("Cannot rename " + tmp_filename + " to " + filename + " " + err.String())
+	}
 	return nil
 }
 
@@ -138,6 +222,103 @@ func (model *Model) NumWords() int {
 	return len(model.topic_histograms)
 }
 
+// Returns the words in the model, sorted.
+func (model *Model) Words() []string {
+	words := make([]string, 0, len(model.topic_histograms))
+	for word := range model.topic_histograms {
+		words = append(words, word)
+	}
+	sort.SortStrings(words)
+	return words
+}
+
+// Returns the names of topics, or nil if topics are not named.
+func (model *Model) TopicNames() []string {
+	return model.topic_names
+}
+
+// Name the topics, e.g., by the labels of Labeled LDA.  Names must be
+// non-empty and contain no whitespaces.  A nil names removes the names.
+func (model *Model) SetTopicNames(names []string) os.Error {
+	if names == nil {
+		model.topic_names = nil
+		return nil
+	}
+	if len(names) != model.NumTopics() {
+		return os.NewError(fmt.Sprintf("%d names for %d topics",
+			len(names), model.NumTopics()))
+	}
+	for _, name := range names {
+		if fields := strings.Fields(name); len(fields) != 1 || fields[0] != name {
+			return os.NewError("Invalid topic name: \"" + name + "\"")
+		}
+	}
+	model.topic_names = make([]string, len(names))
+	copy(model.topic_names, names)
+	return nil
+}
+
+// Append a topic with zero counts, and returns its index.  If topics
+// are named, the new topic is named kNewTopicNamePrefix followed by its
+// index.  This is used by trainers inferring the number of topics, e.g.,
+// HDPSampler.
+func (model *Model) AddTopic() int {
+	topic := model.NumTopics()
+	for word, hist := range model.topic_histograms {
+		model.topic_histograms[word] = append(hist, 0)
+	}
+	model.global_histogram = append(model.global_histogram, 0)
+	model.zero_histogram = NewHistogram(len(model.global_histogram))
+	if model.topic_names != nil {
+		model.topic_names = append(model.topic_names,
+			kNewTopicNamePrefix+strconv.Itoa(topic))
+	}
+	return topic
+}
+
+// Remove topics with zero counts, keeping the order of the others, and
+// returns the new index of every old topic, or -1 if it is removed.
+func (model *Model) CompactTopics() []int {
+	mapping := make([]int, model.NumTopics())
+	num_topics := 0
+	for k, c := range model.global_histogram {
+		if c != 0 {
+			mapping[k] = num_topics
+			num_topics++
+		} else {
+			mapping[k] = -1
+		}
+	}
+	if num_topics == model.NumTopics() {
+		return mapping
+	}
+
+	compact := func(hist Histogram) Histogram {
+		h := NewHistogram(num_topics)
+		for k, c := range hist {
+			if mapping[k] >= 0 {
+				h[mapping[k]] = c
+			}
+		}
+		return h
+	}
+	for word, hist := range model.topic_histograms {
+		model.topic_histograms[word] = compact(hist)
+	}
+	model.global_histogram = compact(model.global_histogram)
+	model.zero_histogram = NewHistogram(num_topics)
+	if model.topic_names != nil {
+		names := make([]string, 0, num_topics)
+		for k, name := range model.topic_names {
+			if mapping[k] >= 0 {
+				names = append(names, name)
+			}
+		}
+		model.topic_names = names
+	}
+	return mapping
+}
+
 func (model *Model) IncrementTopic(word string, topic int, count int) {
 	if topic >= model.NumTopics() {
 		panic(fmt.Sprintf("topic (%d) > num_topics (%d)",
@@ -156,14 +337,122 @@ func (model *Model) ReassignTopic(word string, old_topic int, new_topic int) {
 	model.IncrementTopic(word, new_topic, 1)
 }
 
+// Returns the topic histogram of word, or an all-zero histogram if word
+// is not in the model (e.g., an unseen word during inference).  The
+// returned histogram must not be modified.
 func (model *Model) GetWordTopicHistogram(word string) Histogram {
-	return model.topic_histograms[word];
+	if hist, present := model.topic_histograms[word]; present {
+		return hist
+	}
+	return model.zero_histogram
+}
+
+// Returns at most n words with the largest counts in topic, in
+// descending order of counts.  Words with zero count are skipped.
+func (model *Model) TopWords(topic int, n int) []WordCount {
+	if topic < 0 || topic >= model.NumTopics() {
+		panic(fmt.Sprintf("topic (%d) out of range [0, %d)",
+			topic, model.NumTopics()))
+	}
+	words := make(wordCountArray, 0)
+	for word, hist := range model.topic_histograms {
+		if hist[topic] > 0 {
+			words = append(words, WordCount{word, hist[topic]})
+		}
+	}
+	sort.Sort(words)
+	if len(words) > n {
+		words = words[0:n]
+	}
+	return words
 }
 
 func (model *Model) GetGlobalTopicHistogram() Histogram {
 	return model.global_histogram;
 }
 
+// Multiply every count in the model by factor and round it to the
+// nearest integer.  This is used to average accumulated models.  Words
+// are kept even if their counts all become zero, so that the vocabulary,
+// and hence the smoothing of P(word|topic), is unchanged.
+func (model *Model) Scale(factor float64) {
+	if factor < 0 {
+		panic(fmt.Sprintf("negative scaling factor: %f", factor))
+	}
+	for i := range model.global_histogram {
+		model.global_histogram[i] = 0
+	}
+	for _, hist := range model.topic_histograms {
+		for topic, c := range hist {
+			hist[topic] = int(float64(c)*factor + 0.5)
+			model.global_histogram[topic] += hist[topic]
+		}
+	}
+}
+
+// Multiply every count in the model by factor in [0, 1] and round it
+// stochastically, i.e., up with the probability of its fractional part,
+// so that counts are scaled by factor in expectation, and repeated decay
+// drives small counts to zero.  This is used to forget old counts in
+// incremental training.  Words whose counts all become zero are removed
+// from the model.  A nil rng uses the global random source.
+func (model *Model) Decay(factor float64, rng *rand.Rand) {
+	if factor < 0 || factor > 1 {
+		panic(fmt.Sprintf("decay factor out of range [0, 1]: %f", factor))
+	}
+	for i := range model.global_histogram {
+		model.global_histogram[i] = 0
+	}
+	for word, hist := range model.topic_histograms {
+		total := 0
+		for topic, c := range hist {
+			scaled := float64(c) * factor
+			hist[topic] = int(scaled)
+			if randFloat64(rng) < scaled-float64(hist[topic]) {
+				hist[topic]++
+			}
+			model.global_histogram[topic] += hist[topic]
+			total += hist[topic]
+		}
+		if total == 0 {
+			model.topic_histograms[word] = nil, false
+		}
+	}
+}
+
+// Remove the words of doc from the model counts, each from a topic drawn
+// in proportion to the counts of the word, e.g., to re-sample a document
+// of the training data of the model, whose topics are not known.  Words
+// without counts left are skipped, and those whose counts all become zero
+// are removed from the model.  A nil rng uses the global random source.
+func (model *Model) RemoveDocument(doc *Document, rng *rand.Rand) {
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		hist, present := model.topic_histograms[iter.Word()]
+		if !present {
+			continue
+		}
+		total := 0
+		for _, c := range hist {
+			total += c
+		}
+		if total <= 0 {
+			continue
+		}
+		r := randIntn(rng, total)
+		for topic, c := range hist {
+			if r < c {
+				hist[topic]--
+				model.global_histogram[topic]--
+				break
+			}
+			r -= c
+		}
+		if total == 1 {
+			model.topic_histograms[iter.Word()] = nil, false
+		}
+	}
+}
+
 func (model *Model) AccumulateModel(m *Model) {
 	if model.NumTopics() != m.NumTopics() {
 		panic(fmt.Sprintf("model has (%d) topics; m has (%d) topics.",
@@ -0,0 +1,216 @@
+package lda
+
+import (
+	"json"
+	"math"
+	"os"
+	"sort"
+)
+
+// The difference of a topic between an old and a new model.
+type TopicDiff struct {
+	OldTopic   int
+	NewTopic   int
+	Similarity float64  // In [0, 1], 1 for identical word distributions.
+	Entered    []string // Top words of NewTopic that are not of OldTopic.
+	Left       []string // Top words of OldTopic that are not of NewTopic.
+}
+
+// The difference between an old and a new model, e.g., of consecutive
+// retrainings.
+type ModelDiff struct {
+	Matched      []TopicDiff // In ascending order of OldTopic.
+	UnmatchedOld []int       // Topics of the old model matched to none.
+	UnmatchedNew []int       // Topics of the new model matched to none.
+	AddedWords   []string    // Words of the new model only, sorted.
+	RemovedWords []string    // Words of the old model only, sorted.
+}
+
+// Returns the maximum-weight matching of rows to columns of similarities,
+// i.e., match[i] is the column of row i, or -1 if more rows than columns
+// leave row i unmatched, by the Hungarian algorithm.
+func MatchTopics(similarities [][]float64) []int {
+	n := len(similarities)
+	if n == 0 {
+		return []int{}
+	}
+	m := len(similarities[0])
+	if n > m {
+		transposed := make([][]float64, m)
+		for j := range transposed {
+			transposed[j] = make([]float64, n)
+			for i := range similarities {
+				transposed[j][i] = similarities[i][j]
+			}
+		}
+		match := make([]int, n)
+		for i := range match {
+			match[i] = -1
+		}
+		for j, i := range MatchTopics(transposed) {
+			match[i] = j
+		}
+		return match
+	}
+
+	// Minimizes the cost -similarities with potentials u of rows and v of
+	// columns, where row[j] is the row matched to column j, numbered from
+	// 1 with 0 for none.
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	row := make([]int, m+1)
+	way := make([]int, m+1)
+	for i := 1; i <= n; i++ {
+		row[0] = i
+		j0 := 0
+		min_v := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range min_v {
+			min_v[j] = math.Inf(1)
+		}
+		for row[j0] != 0 {
+			used[j0] = true
+			i0, delta, j1 := row[j0], math.Inf(1), 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				cost := -similarities[i0-1][j-1] - u[i0] - v[j]
+				if cost < min_v[j] {
+					min_v[j], way[j] = cost, j0
+				}
+				if min_v[j] < delta {
+					delta, j1 = min_v[j], j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[row[j]] += delta
+					v[j] -= delta
+				} else {
+					min_v[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			row[j0] = row[j1]
+			j0 = j1
+		}
+	}
+
+	match := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if row[j] > 0 {
+			match[row[j]-1] = j - 1
+		}
+	}
+	return match
+}
+
+// Returns the words of a that are not in b, in their order in a.
+func missingWords(a []WordCount, b []WordCount) []string {
+	in_b := make(map[string]bool)
+	for _, w := range b {
+		in_b[w.Word] = true
+	}
+	missing := make([]string, 0)
+	for _, w := range a {
+		if !in_b[w.Word] {
+			missing = append(missing, w.Word)
+		}
+	}
+	return missing
+}
+
+// Returns the difference from old_model to new_model.  Topics are aligned
+// by the optimal matching on the similarities of their word distributions,
+// smoothed by word_prior over the words of both models, where the
+// similarity is 1 - the distance by metric (kJensenShannonDistance,
+// kHellingerDistance or kCosineDistance) divided by its maximum.  Matches
+// less similar than min_similarity are left unmatched.  The top words of
+// topics are their num_top_words most frequent ones.
+func DiffModels(old_model *Model, new_model *Model, word_prior float64, metric string,
+	num_top_words int, min_similarity float64) (*ModelDiff, os.Error) {
+	distance, max_distance, err := topicDistance(metric)
+	if err != nil {
+		return nil, err
+	}
+	diff := &ModelDiff{make([]TopicDiff, 0), make([]int, 0), make([]int, 0),
+		make([]string, 0), make([]string, 0)}
+
+	vocabulary := make(map[string]bool)
+	for w := range old_model.topic_histograms {
+		vocabulary[w] = true
+		if _, present := new_model.topic_histograms[w]; !present {
+			diff.RemovedWords = append(diff.RemovedWords, w)
+		}
+	}
+	for w := range new_model.topic_histograms {
+		if !vocabulary[w] {
+			vocabulary[w] = true
+			diff.AddedWords = append(diff.AddedWords, w)
+		}
+	}
+	sort.SortStrings(diff.RemovedWords)
+	sort.SortStrings(diff.AddedWords)
+	words := make([]string, 0, len(vocabulary))
+	for w := range vocabulary {
+		words = append(words, w)
+	}
+	sort.SortStrings(words)
+
+	old_phi := old_model.topicWordDistributions(words, word_prior)
+	new_phi := new_model.topicWordDistributions(words, word_prior)
+	similarities := make([][]float64, len(old_phi))
+	for k := range similarities {
+		similarities[k] = make([]float64, len(new_phi))
+		for j := range new_phi {
+			similarities[k][j] = 1 - distance(old_phi[k], new_phi[j])/max_distance
+		}
+	}
+
+	matched_new := make([]bool, len(new_phi))
+	for k, j := range MatchTopics(similarities) {
+		if j < 0 || similarities[k][j] < min_similarity {
+			diff.UnmatchedOld = append(diff.UnmatchedOld, k)
+			continue
+		}
+		matched_new[j] = true
+		old_top := old_model.TopWords(k, num_top_words)
+		new_top := new_model.TopWords(j, num_top_words)
+		diff.Matched = append(diff.Matched, TopicDiff{k, j, similarities[k][j],
+			missingWords(new_top, old_top), missingWords(old_top, new_top)})
+	}
+	for j, matched := range matched_new {
+		if !matched {
+			diff.UnmatchedNew = append(diff.UnmatchedNew, j)
+		}
+	}
+	return diff, nil
+}
+
+// Returns the difference in JSON, an object of matched, unmatched_old,
+// unmatched_new, added_words and removed_words, where matched topics are
+// objects of old_topic, new_topic, similarity, entered and left.
+func (diff *ModelDiff) JSON() ([]byte, os.Error) {
+	matched := make([]map[string]interface{}, len(diff.Matched))
+	for i, d := range diff.Matched {
+		matched[i] = map[string]interface{}{
+			"old_topic":  d.OldTopic,
+			"new_topic":  d.NewTopic,
+			"similarity": d.Similarity,
+			"entered":    d.Entered,
+			"left":       d.Left,
+		}
+	}
+	payload := map[string]interface{}{
+		"matched":       matched,
+		"unmatched_old": diff.UnmatchedOld,
+		"unmatched_new": diff.UnmatchedNew,
+		"added_words":   diff.AddedWords,
+		"removed_words": diff.RemovedWords,
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,101 @@
+package lda
+
+import (
+	"fmt"
+	"json"
+	"testing"
+)
+
+func TestMatchTopics(t *testing.T) {
+	// Greedily matching the most similar pair, 0 to 0, is not optimal.
+	similarities := [][]float64{
+		{0.9, 0.8, 0.0},
+		{0.8, 0.1, 0.0},
+		{0.0, 0.2, 0.5},
+	}
+	if s := fmt.Sprint(MatchTopics(similarities)); s != "[1 0 2]" {
+		t.Errorf("Expecting: [1 0 2], but got: " + s)
+	}
+	if s := fmt.Sprint(MatchTopics(similarities[0:2])); s != "[1 0]" {
+		t.Errorf("Expecting: [1 0], but got: " + s)
+	}
+	wide := [][]float64{{0.1, 0.2}, {0.9, 0.3}, {0.8, 0.1}}
+	if s := fmt.Sprint(MatchTopics(wide)); s != "[1 0 -1]" {
+		t.Errorf("Expecting: [1 0 -1], but got: " + s)
+	}
+}
+
+func TestDiffModels(t *testing.T) {
+	old_model := NewModel(2)
+	for i, w := range kFruits {
+		old_model.IncrementTopic(w, 0, 10-i)
+	}
+	for i, w := range kAnimals {
+		old_model.IncrementTopic(w, 1, 10-i)
+	}
+	old_model.IncrementTopic("durian", 0, 1)
+
+	// The topics are swapped, "durian" is gone, "cherry" is a new top word
+	// of fruits, and a topic of new words is added.
+	new_model := NewModel(3)
+	for i, w := range kAnimals {
+		new_model.IncrementTopic(w, 0, 10-i)
+	}
+	for i, w := range kFruits {
+		new_model.IncrementTopic(w, 1, 10-i)
+	}
+	new_model.IncrementTopic("cherry", 1, 20)
+	new_model.IncrementTopic("rock", 2, 10)
+	new_model.IncrementTopic("jazz", 2, 10)
+
+	diff, err := DiffModels(old_model, new_model, 0.01, kHellingerDistance, 3, 0.5)
+	if err != nil {
+		t.Fatalf("Cannot diff models: " + err.String())
+	}
+	if len(diff.Matched) != 2 {
+		t.Fatalf("Expecting 2 matched topics, but got %v", diff.Matched)
+	}
+	fruits, animals := diff.Matched[0], diff.Matched[1]
+	if fruits.OldTopic != 0 || fruits.NewTopic != 1 || animals.OldTopic != 1 ||
+		animals.NewTopic != 0 {
+		t.Errorf("Unexpected matches: %v", diff.Matched)
+	}
+	if animals.Similarity < 0.99 || fruits.Similarity > animals.Similarity {
+		t.Errorf("Unexpected similarities: %v", diff.Matched)
+	}
+	if fmt.Sprint(fruits.Entered, fruits.Left) != fmt.Sprint([]string{"cherry"}, kFruits[2:3]) ||
+		len(animals.Entered)+len(animals.Left) != 0 {
+		t.Errorf("Unexpected top words: %v", diff.Matched)
+	}
+	if s := fmt.Sprint(diff.UnmatchedOld, diff.UnmatchedNew); s != "[] [2]" {
+		t.Errorf("Expecting unmatched topics: [] [2], but got: " + s)
+	}
+	if s := fmt.Sprint(diff.AddedWords, diff.RemovedWords); s != "[cherry jazz rock] [durian]" {
+		t.Errorf("Expecting added and removed words: [cherry jazz rock] [durian], but got: " + s)
+	}
+
+	// No topic is similar enough.
+	diff, _ = DiffModels(old_model, new_model, 0.01, kHellingerDistance, 3, 1.1)
+	if s := fmt.Sprint(len(diff.Matched), diff.UnmatchedOld, diff.UnmatchedNew); s != "0 [0 1] [0 1 2]" {
+		t.Errorf("Expecting no match, but got: " + s)
+	}
+
+	data, err := diff.JSON()
+	if err != nil {
+		t.Fatalf("Cannot encode JSON: " + err.String())
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON: " + err.String())
+	}
+	for _, key := range []string{"matched", "unmatched_old", "unmatched_new", "added_words",
+		"removed_words"} {
+		if _, present := decoded[key]; !present {
+			t.Errorf("Missing key %s in %s", key, string(data))
+		}
+	}
+
+	if _, err := DiffModels(old_model, new_model, 0.01, "euclidean", 3, 0.5); err == nil {
+		t.Errorf("Expecting an error on an unknown metric")
+	}
+}
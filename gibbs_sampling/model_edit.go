@@ -0,0 +1,289 @@
+package lda
+
+import (
+	"fmt"
+	"os"
+	"rand"
+	"strconv"
+	"strings"
+)
+
+// Returns an error unless topics are distinct topics of model.
+func (model *Model) checkTopics(topics []int) os.Error {
+	seen := make(map[int]bool)
+	for _, k := range topics {
+		if k < 0 || k >= model.NumTopics() {
+			return os.NewError(fmt.Sprintf("Topic %d out of range [0, %d)", k, model.NumTopics()))
+		}
+		if seen[k] {
+			return os.NewError(fmt.Sprintf("Duplicated topic %d", k))
+		}
+		seen[k] = true
+	}
+	return nil
+}
+
+// Move the counts of every topic k to topic mapping[k] of num_topics
+// topics, summing the counts of topics mapped together and dropping those
+// of topics mapped to -1.  Words left without counts are removed.  Every
+// new topic gets the name of the first old topic mapped to it.
+func (model *Model) remapTopics(mapping []int, num_topics int) {
+	remap := func(hist Histogram) Histogram {
+		h := NewHistogram(num_topics)
+		for k, c := range hist {
+			if mapping[k] >= 0 {
+				h[mapping[k]] += c
+			}
+		}
+		return h
+	}
+	for word, hist := range model.topic_histograms {
+		h := remap(hist)
+		total := 0
+		for _, c := range h {
+			total += c
+		}
+		if total == 0 {
+			model.topic_histograms[word] = nil, false
+		} else {
+			model.topic_histograms[word] = h
+		}
+	}
+	model.global_histogram = remap(model.global_histogram)
+	model.zero_histogram = NewHistogram(num_topics)
+	if model.topic_names != nil {
+		names := make([]string, num_topics)
+		for k := len(mapping) - 1; k >= 0; k-- {
+			if mapping[k] >= 0 {
+				names[mapping[k]] = model.topic_names[k]
+			}
+		}
+		model.topic_names = names
+	}
+}
+
+// Merge topics into one, at the position and with the name of the first
+// of them in the model, summing their counts.  At least two topics must
+// remain.  Returns the new index of every old topic.
+func (model *Model) MergeTopics(topics []int) (mapping []int, err os.Error) {
+	if err := model.checkTopics(topics); err != nil {
+		return nil, err
+	}
+	if len(topics) < 2 {
+		return nil, os.NewError("Must merge at least 2 topics")
+	}
+	num_topics := model.NumTopics() - len(topics) + 1
+	if num_topics < 2 {
+		return nil, os.NewError("At least 2 topics must remain")
+	}
+	merged := make(map[int]bool)
+	target := topics[0]
+	for _, k := range topics {
+		merged[k] = true
+		if k < target {
+			target = k
+		}
+	}
+	mapping = make([]int, model.NumTopics())
+	next := 0
+	for k := range mapping {
+		if merged[k] && k != target {
+			mapping[k] = mapping[target]
+		} else {
+			mapping[k] = next
+			next++
+		}
+	}
+	model.remapTopics(mapping, num_topics)
+	return mapping, nil
+}
+
+// Delete topics with their counts.  At least two topics must remain.
+// Returns the new index of every old topic, or -1 if it is deleted.
+func (model *Model) DeleteTopics(topics []int) (mapping []int, err os.Error) {
+	if err := model.checkTopics(topics); err != nil {
+		return nil, err
+	}
+	num_topics := model.NumTopics() - len(topics)
+	if num_topics < 2 {
+		return nil, os.NewError("At least 2 topics must remain")
+	}
+	deleted := make(map[int]bool)
+	for _, k := range topics {
+		deleted[k] = true
+	}
+	mapping = make([]int, model.NumTopics())
+	next := 0
+	for k := range mapping {
+		if deleted[k] {
+			mapping[k] = -1
+		} else {
+			mapping[k] = next
+			next++
+		}
+	}
+	model.remapTopics(mapping, num_topics)
+	return mapping, nil
+}
+
+// Reorder topics, so that topic order[i] becomes topic i.  order must be
+// a permutation of all topics.  Returns the new index of every old topic.
+func (model *Model) ReorderTopics(order []int) (mapping []int, err os.Error) {
+	if err := model.checkTopics(order); err != nil {
+		return nil, err
+	}
+	if len(order) != model.NumTopics() {
+		return nil, os.NewError(fmt.Sprintf("Order of %d topics for %d topics",
+			len(order), model.NumTopics()))
+	}
+	mapping = make([]int, len(order))
+	for i, k := range order {
+		mapping[k] = i
+	}
+	model.remapTopics(mapping, len(order))
+	return mapping, nil
+}
+
+// Name topic, which must be non-empty and contain no whitespaces.  If
+// topics are not named yet, the others are named kNewTopicNamePrefix
+// followed by their indices.
+func (model *Model) SetTopicName(topic int, name string) os.Error {
+	if err := model.checkTopics([]int{topic}); err != nil {
+		return err
+	}
+	names := model.topic_names
+	if names == nil {
+		names = make([]string, model.NumTopics())
+		for k := range names {
+			names[k] = kNewTopicNamePrefix + strconv.Itoa(k)
+		}
+	} else {
+		names = append([]string{}, names...)
+	}
+	names[topic] = name
+	return model.SetTopicNames(names)
+}
+
+// Split topic into two by re-sampling its tokens in corpus: the topics
+// of documents in corpus are inferred by the model for iterations
+// iterations, then the tokens of topic are re-sampled into two topics by
+// collapsed Gibbs sampling of LDA restricted to them, for iterations
+// iterations.  The counts of each word in topic are divided between the
+// two in proportion to its tokens in them.  The second topic is appended
+// to the model, as by AddTopic, and its index is returned.  The topic
+// assignments of corpus are overwritten.  A nil rng uses the global
+// random source.
+func (model *Model) SplitTopic(topic int, corpus *Corpus, topic_prior float64,
+	word_prior float64, iterations int, rng *rand.Rand) (int, os.Error) {
+	if err := model.checkTopics([]int{topic}); err != nil {
+		return -1, err
+	}
+	if iterations <= 0 {
+		return -1, os.NewError("iterations must be positive")
+	}
+
+	// The words of the tokens of topic, by document.
+	sampler := NewSampler(topic_prior, word_prior, model, nil)
+	sampler.SetRand(rng)
+	sub_corpus := NewCorpus()
+	for _, doc := range *corpus {
+		if len(doc.topic_histogram) != model.NumTopics() {
+			return -1, os.NewError(fmt.Sprintf("doc has (%d) topics; model has (%d) topics.",
+				len(doc.topic_histogram), model.NumTopics()))
+		}
+		doc.RandomizeTopics(rng)
+		for iter := 0; iter < iterations; iter++ {
+			sampler.DocumentGibbsSampling(doc, false)
+		}
+		words := make([]string, 0)
+		for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+			if iter.Topic() == topic {
+				words = append(words, iter.Word())
+			}
+		}
+		// Documents of a single token of topic say nothing about how to
+		// split it.
+		if len(words) >= 2 {
+			sub_doc, err := NewDocument(strings.Join(words, " "), 2)
+			if err != nil {
+				return -1, err
+			}
+			sub_doc.RandomizeTopics(rng)
+			*sub_corpus = append(*sub_corpus, sub_doc)
+		}
+	}
+	if len(*sub_corpus) == 0 {
+		return -1, os.NewError(fmt.Sprintf("No document in corpus has 2 tokens of topic %d", topic))
+	}
+
+	sub_model := CreateModel(2, sub_corpus)
+	sub_sampler := NewSampler(topic_prior, word_prior, sub_model, nil)
+	sub_sampler.SetRand(rng)
+	for iter := 0; iter < iterations; iter++ {
+		sub_sampler.CorpusGibbsSampling(sub_corpus, true, true)
+	}
+
+	new_topic := model.AddTopic()
+	for w, hist := range model.topic_histograms {
+		sub := sub_model.GetWordTopicHistogram(w)
+		if sub[0]+sub[1] == 0 {
+			continue // The word stays in topic.
+		}
+		moved := int(float64(hist[topic])*float64(sub[1])/float64(sub[0]+sub[1]) + 0.5)
+		model.IncrementTopic(w, topic, -moved)
+		model.IncrementTopic(w, new_topic, moved)
+	}
+	return new_topic, nil
+}
+
+// TopicTracker follows topics of a model through a sequence of edits, so
+// that an edit script may name topics by their indices in the original
+// model, e.g., the merge lines output by topic-analysis, however the
+// preceding edits renumbered them.  Topics appended by SplitTopic are
+// named by the number of original topics plus the number of earlier
+// splits.
+type TopicTracker struct {
+	current []int // The current index of every named topic, -1 if deleted.
+}
+
+// Create a tracker of the num_topics topics of an unedited model.
+func NewTopicTracker(num_topics int) *TopicTracker {
+	current := make([]int, num_topics)
+	for k := range current {
+		current[k] = k
+	}
+	return &TopicTracker{current}
+}
+
+// Returns the current indices of topics named in a script.
+func (tracker *TopicTracker) Translate(topics []int) ([]int, os.Error) {
+	result := make([]int, len(topics))
+	for i, k := range topics {
+		if k < 0 || k >= len(tracker.current) {
+			return nil, os.NewError(fmt.Sprintf("Topic %d out of range [0, %d)",
+				k, len(tracker.current)))
+		}
+		if tracker.current[k] < 0 {
+			return nil, os.NewError(fmt.Sprintf("Topic %d was deleted", k))
+		}
+		result[i] = tracker.current[k]
+	}
+	return result, nil
+}
+
+// Follow an edit that moved every current topic k to mapping[k], as
+// returned by MergeTopics, DeleteTopics and ReorderTopics.
+func (tracker *TopicTracker) Update(mapping []int) {
+	for k, c := range tracker.current {
+		if c >= 0 {
+			tracker.current[k] = mapping[c]
+		}
+	}
+}
+
+// Follow the append of topic, e.g., by SplitTopic.  Returns its name in
+// scripts.
+func (tracker *TopicTracker) AddTopic(topic int) int {
+	tracker.current = append(tracker.current, topic)
+	return len(tracker.current) - 1
+}
@@ -0,0 +1,174 @@
+package lda
+
+import (
+	"fmt"
+	"os"
+	"rand"
+	"testing"
+)
+
+// Returns a model of 4 named topics of words a, b and c.
+func newEditTestModel() *Model {
+	model := NewModel(4)
+	model.IncrementTopic("a", 0, 1)
+	model.IncrementTopic("a", 1, 2)
+	model.IncrementTopic("b", 2, 3)
+	model.IncrementTopic("c", 3, 4)
+	model.SetTopicNames([]string{"t0", "t1", "t2", "t3"})
+	return model
+}
+
+func modelString(model *Model) string {
+	s := fmt.Sprint(model.TopicNames(), model.GetGlobalTopicHistogram())
+	for _, w := range model.Words() {
+		s += fmt.Sprintf(" %s:%v", w, model.GetWordTopicHistogram(w))
+	}
+	return s
+}
+
+func TestMergeDeleteReorderTopics(t *testing.T) {
+	model := newEditTestModel()
+	mapping, err := model.MergeTopics([]int{3, 1})
+	if err != nil {
+		t.Fatalf("Cannot merge topics: " + err.String())
+	}
+	const kMerged = "[t0 t1 t2] [1 6 3] a:[1 2 0] b:[0 0 3] c:[0 4 0]"
+	if s := modelString(model); s != kMerged || fmt.Sprint(mapping) != "[0 1 2 1]" {
+		t.Errorf("Expecting: " + kMerged + ", but got: " + s + fmt.Sprint(mapping))
+	}
+
+	model = newEditTestModel()
+	mapping, err = model.DeleteTopics([]int{2, 3})
+	if err != nil {
+		t.Fatalf("Cannot delete topics: " + err.String())
+	}
+	const kDeleted = "[t0 t1] [1 2] a:[1 2]"
+	if s := modelString(model); s != kDeleted || fmt.Sprint(mapping) != "[0 1 -1 -1]" {
+		t.Errorf("Expecting: " + kDeleted + ", but got: " + s + fmt.Sprint(mapping))
+	}
+
+	model = newEditTestModel()
+	if _, err := model.ReorderTopics([]int{3, 2, 1, 0}); err != nil {
+		t.Fatalf("Cannot reorder topics: " + err.String())
+	}
+	const kReordered = "[t3 t2 t1 t0] [4 3 2 1] a:[0 0 2 1] b:[0 3 0 0] c:[4 0 0 0]"
+	if s := modelString(model); s != kReordered {
+		t.Errorf("Expecting: " + kReordered + ", but got: " + s)
+	}
+
+	for _, edit := range []func(m *Model) ([]int, os.Error){
+		func(m *Model) ([]int, os.Error) { return m.MergeTopics([]int{0, 1, 2, 3}) },
+		func(m *Model) ([]int, os.Error) { return m.MergeTopics([]int{1}) },
+		func(m *Model) ([]int, os.Error) { return m.DeleteTopics([]int{0, 1, 2}) },
+		func(m *Model) ([]int, os.Error) { return m.DeleteTopics([]int{4}) },
+		func(m *Model) ([]int, os.Error) { return m.ReorderTopics([]int{0, 1, 2}) },
+		func(m *Model) ([]int, os.Error) { return m.ReorderTopics([]int{0, 1, 1, 2}) },
+	} {
+		model = newEditTestModel()
+		if _, err := edit(model); err == nil {
+			t.Errorf("Expecting an error, but got model %s", modelString(model))
+		}
+	}
+}
+
+func TestSetTopicName(t *testing.T) {
+	model := NewModel(3)
+	if err := model.SetTopicName(1, "sports"); err != nil {
+		t.Fatalf("Cannot name topic: " + err.String())
+	}
+	if s := fmt.Sprint(model.TopicNames()); s != "[topic_0 sports topic_2]" {
+		t.Errorf("Unexpected names: %s", s)
+	}
+	if err := model.SetTopicName(0, "two words"); err == nil {
+		t.Errorf("Expecting an error on an invalid name")
+	}
+	if err := model.SetTopicName(3, "x"); err == nil {
+		t.Errorf("Expecting an error on an invalid topic")
+	}
+}
+
+func TestSplitTopic(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 40, 2)
+	// A model of fruits and animals in topic 0, and nothing in topic 1.
+	model := CreateModel(2, corpus)
+	model.IncrementTopic("junk", 1, 1)
+
+	new_topic, err := model.SplitTopic(0, corpus, 0.1, 0.01, 30, rng)
+	if err != nil {
+		t.Fatalf("Cannot split topic: " + err.String())
+	}
+	if new_topic != 2 || model.NumTopics() != 3 {
+		t.Fatalf("Expecting new topic 2, but got %d of %d", new_topic, model.NumTopics())
+	}
+	global := model.GetGlobalTopicHistogram()
+	if global[0]+global[2] != 40*8 || global[1] != 1 {
+		t.Errorf("Counts are not preserved: %v", global)
+	}
+	fruit_topic := dominantTopic(model, kFruits[0])
+	animal_topic := dominantTopic(model, kAnimals[0])
+	if fruit_topic == animal_topic || fruit_topic == 1 || animal_topic == 1 {
+		t.Errorf("Expecting fruits and animals in topics 0 and 2, but got %s", modelString(model))
+	}
+	for _, w := range kFruits {
+		if dominantTopic(model, w) != fruit_topic {
+			t.Errorf("Unexpected topic of %s: %v", w, model.GetWordTopicHistogram(w))
+		}
+	}
+}
+
+func TestTopicTracker(t *testing.T) {
+	// Topics 0 and 3, and topics 2 and 4, are near duplicates.
+	model := NewModel(5)
+	for k, counts := range [][]int{{10, 10, 0, 0, 0, 0}, {0, 0, 10, 10, 0, 0},
+		{0, 0, 0, 0, 10, 10}, {11, 9, 0, 0, 0, 0}, {0, 0, 0, 0, 9, 11}} {
+		for i, c := range counts {
+			model.IncrementTopic([]string{"apple", "orange", "zebra", "lion", "car", "bus"}[i], k, c)
+		}
+	}
+	// The merges suggested by topic-analysis, in its order.
+	distances, _ := TopicDistances(model, 0.01, kJensenShannonDistance)
+	merges, _ := ClusterTopics(distances, kAverageLinkage)
+	clusters := make([][]int, 0)
+	for _, cluster := range CutTopicHierarchy(merges, 5, 0.1) {
+		if len(cluster) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	if fmt.Sprint(clusters) != "[[0 3] [2 4]]" {
+		t.Fatalf("Expecting clusters [[0 3] [2 4]], but got %v", clusters)
+	}
+
+	tracker := NewTopicTracker(5)
+	for _, cluster := range clusters {
+		topics, err := tracker.Translate(cluster)
+		if err != nil {
+			t.Fatalf("Cannot translate %v: %s", cluster, err.String())
+		}
+		mapping, err := model.MergeTopics(topics)
+		if err != nil {
+			t.Fatalf("Cannot merge %v: %s", cluster, err.String())
+		}
+		tracker.Update(mapping)
+	}
+	const kMerged = "[] [40 20 40] apple:[21 0 0] bus:[0 0 21] car:[0 0 19] " +
+		"lion:[0 10 0] orange:[19 0 0] zebra:[0 10 0]"
+	if s := modelString(model); s != kMerged {
+		t.Errorf("Expecting: " + kMerged + ", but got: " + s)
+	}
+	if topics, _ := tracker.Translate([]int{0, 1, 2, 3, 4}); fmt.Sprint(topics) != "[0 1 2 0 2]" {
+		t.Errorf("Expecting current topics [0 1 2 0 2], but got %v", topics)
+	}
+
+	mapping, _ := model.DeleteTopics([]int{1})
+	tracker.Update(mapping)
+	if _, err := tracker.Translate([]int{1}); err == nil {
+		t.Errorf("Expecting an error for a deleted topic")
+	}
+	if k := tracker.AddTopic(model.AddTopic()); k != 5 {
+		t.Errorf("Expecting the added topic to be named 5, but got %d", k)
+	}
+	if topics, _ := tracker.Translate([]int{4, 5}); fmt.Sprint(topics) != "[1 2]" {
+		t.Errorf("Expecting current topics [1 2], but got %v", topics)
+	}
+}
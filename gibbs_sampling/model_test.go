@@ -2,11 +2,13 @@ package lda
 
 import (
 	"fmt"
+	"io/ioutil"
+	"rand"
 	"testing"
 )
 
 const kTestModelFile = "testdata/model.txt"
-const kTestModelEncoding = "{map[orange:[0 1] zebra:[1 0] monky:[1 0] apple:[0 1] banana:[0 1]] [2 3] [0 0]}"
+const kTestModelEncoding = "{map[orange:[0 1] zebra:[1 0] monky:[1 0] apple:[0 1] banana:[0 1]] [2 3] [0 0] []}"
 const kTmpModelFile = "/tmp/tmp_model.txt"
 
 func TestLoadModel(t *testing.T) {
@@ -27,7 +29,7 @@ func TestReassignTopic(t *testing.T) {
 	model.IncrementTopic("apple", 0, 3)
 	model.IncrementTopic("orange", 1, 5)
 	model.ReassignTopic("apple", 0, 1)
-	if fmt.Sprintf("%v", *model) != "{map[orange:[0 5] apple:[2 1]] [2 6] [0 0]}" {
+	if fmt.Sprintf("%v", *model) != "{map[orange:[0 5] apple:[2 1]] [2 6] [0 0] []}" {
 		t.Errorf("Unexpected model: %v", *model)
 	}
 }
@@ -62,7 +64,7 @@ func TestCreateModel(t *testing.T) {
 	*corpus = append(*corpus, doc)
 	model := CreateModel(2, corpus)
 
-	const kModelGoFmt = "{map[orange:[1 0] zebra:[1 0] apple:[2 0] cat:[1 0]] [5 0] [0 0]}"
+	const kModelGoFmt = "{map[orange:[1 0] zebra:[1 0] apple:[2 0] cat:[1 0]] [5 0] [0 0] []}"
 	if fmt.Sprintf("%v", *model) != kModelGoFmt {
 		t.Errorf("Expecting: " + kModelGoFmt + ", but got: " + fmt.Sprintf("%v", *model))
 	}
@@ -77,8 +79,166 @@ func TestAccumulateModel(t *testing.T) {
 	model_2.IncrementTopic("orange", 1, 1);
 	model_2.AccumulateModel(model_1)
 
-	const kModelGoFmt = "{map[orange:[1 1] apple:[1 0]] [2 1] [0 0]}"
+	const kModelGoFmt = "{map[orange:[1 1] apple:[1 0]] [2 1] [0 0] []}"
 	if fmt.Sprintf("%v", *model_2) != kModelGoFmt {
 		t.Errorf("Expecting: " + kModelGoFmt + ", but got: " + fmt.Sprintf("%v", *model_2))
 	}
 }
+
+// Checks that model has exactly the words of expected, with the topic
+// histograms of expected, and the global topic histogram global, as
+// printed by fmt.
+func checkModelCounts(t *testing.T, model *Model, expected map[string]string, global string) {
+	if model.NumWords() != len(expected) {
+		t.Errorf("Expecting %d words, but got %v", len(expected), model.Words())
+	}
+	for word, hist := range expected {
+		if s := fmt.Sprint(model.GetWordTopicHistogram(word)); s != hist {
+			t.Errorf("Expecting topic histogram %s of %s, but got %s", hist, word, s)
+		}
+	}
+	if s := fmt.Sprint(model.GetGlobalTopicHistogram()); s != global {
+		t.Errorf("Expecting global topic histogram %s, but got %s", global, s)
+	}
+}
+
+func TestScaleModel(t *testing.T) {
+	model := NewModel(2)
+	model.IncrementTopic("apple", 0, 10)
+	model.IncrementTopic("apple", 1, 5)
+	model.IncrementTopic("orange", 1, 1)
+	model.IncrementTopic("zebra", 0, 3)
+	model.Scale(0.3)
+
+	// orange is kept with zero counts.
+	checkModelCounts(t, model, map[string]string{
+		"apple": "[3 2]", "orange": "[0 0]", "zebra": "[1 0]"}, "[4 2]")
+}
+
+func TestDecayModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	model := NewModel(2)
+	model.IncrementTopic("apple", 0, 1000)
+	model.IncrementTopic("orange", 1, 5)
+	model.IncrementTopic("zebra", 0, 1)
+	model.Decay(0.5, rng)
+	if h := model.GetWordTopicHistogram("apple"); h[0] != 500 {
+		t.Errorf("Expecting count 500 of apple, but got %v", h)
+	}
+	if h := model.GetWordTopicHistogram("orange"); h[1] != 2 && h[1] != 3 {
+		t.Errorf("Expecting count 2 or 3 of orange, but got %v", h)
+	}
+
+	// Rounding to the nearest integer would keep counts up to 5 forever.
+	for i := 0; i < 100; i++ {
+		model.Decay(0.9, rng)
+	}
+	if model.NumWords() != 0 {
+		t.Errorf("Expecting old counts to disappear, but got %v", *model)
+	}
+	if h := model.GetGlobalTopicHistogram(); h[0] != 0 || h[1] != 0 {
+		t.Errorf("Expecting zero global histogram, but got %v", h)
+	}
+}
+
+func TestRemoveDocument(t *testing.T) {
+	model := NewModel(2)
+	model.IncrementTopic("apple", 0, 3)
+	model.IncrementTopic("apple", 1, 1)
+	model.IncrementTopic("orange", 1, 1)
+	doc, _ := NewDocument("apple orange orange zebra", 2)
+	model.RemoveDocument(doc, rand.New(rand.NewSource(1)))
+	if h := model.GetWordTopicHistogram("apple"); h[0]+h[1] != 3 || h[0] < 2 {
+		t.Errorf("Expecting a count of apple removed, but got %v", h)
+	}
+	if model.NumWords() != 1 {
+		t.Errorf("Expecting orange removed, but got words %v", model.Words())
+	}
+	if h := model.GetGlobalTopicHistogram(); h[0]+h[1] != 3 {
+		t.Errorf("Expecting 3 tokens left, but got %v", h)
+	}
+}
+
+func TestAddDocument(t *testing.T) {
+	model := NewModel(2)
+	model.IncrementTopic("apple", 1, 1)
+	doc, _ := NewDocument("apple orange apple", 2)
+	model.AddDocument(doc)
+
+	checkModelCounts(t, model, map[string]string{"apple": "[2 1]", "orange": "[1 0]"}, "[3 1]")
+}
+
+func TestTopicNames(t *testing.T) {
+	model, _ := LoadModel(kTestModelFile)
+	if model.TopicNames() != nil {
+		t.Errorf("Unexpected topic names: %v", model.TopicNames())
+	}
+	if err := model.SetTopicNames([]string{"animals"}); err == nil {
+		t.Errorf("Expecting an error with too few topic names")
+	}
+	if err := model.SetTopicNames([]string{"animals", "fruit salad"}); err == nil {
+		t.Errorf("Expecting an error with a topic name containing whitespaces")
+	}
+	if err := model.SetTopicNames([]string{"animals", "fruits"}); err != nil {
+		t.Errorf("Cannot set topic names: " + err.String())
+	}
+
+	if err := model.SaveModel(kTmpModelFile); err != nil {
+		t.Errorf("Cannot write to: " + kTmpModelFile + " due to " + err.String())
+	}
+	model_new, err := LoadModel(kTmpModelFile)
+	if err != nil {
+		t.Errorf("Unexpected error in loading: " + kTmpModelFile + " due to " + err.String())
+	} else if modelString(model) != modelString(model_new) {
+		t.Errorf("Original model: %s\ndoes not equal to loaded&saved model: %s",
+			modelString(model), modelString(model_new))
+	}
+
+	// Topic names are in a file of their own, so that any word can be in
+	// the model file.
+	model.IncrementTopic("#topic_names", 0, 1)
+	if err := model.SaveModel(kTmpModelFile); err != nil {
+		t.Errorf("Cannot write to: " + kTmpModelFile + " due to " + err.String())
+	}
+	if names, err := ioutil.ReadFile(kTmpModelFile + kTopicNamesFileSuffix); err != nil ||
+		string(names) != "animals\nfruits\n" {
+		t.Errorf("Unexpected topic names file: %q, %v", names, err)
+	}
+	if model_new, err := LoadModel(kTmpModelFile); err != nil {
+		t.Errorf("Unexpected error in loading: " + kTmpModelFile + " due to " + err.String())
+	} else if modelString(model) != modelString(model_new) {
+		t.Errorf("Original model: %s\ndoes not equal to loaded&saved model: %s",
+			modelString(model), modelString(model_new))
+	}
+
+	// Saving a model without names removes the names of the previous one.
+	model.SetTopicNames(nil)
+	if err := model.SaveModel(kTmpModelFile); err != nil {
+		t.Errorf("Cannot write to: " + kTmpModelFile + " due to " + err.String())
+	}
+	if model_new, err := LoadModel(kTmpModelFile); err != nil || model_new.TopicNames() != nil {
+		t.Errorf("Expecting a model without topic names, but got %v, %v", model_new, err)
+	}
+}
+
+func TestAddAndCompactTopics(t *testing.T) {
+	model := NewModel(3)
+	model.IncrementTopic("apple", 0, 2)
+	model.IncrementTopic("zebra", 2, 1)
+	model.SetTopicNames([]string{"fruits", "empty", "animals"})
+	if k := model.AddTopic(); k != 3 || model.NumTopics() != 4 {
+		t.Errorf("Expecting new topic 3, but got %d of %d topics", k, model.NumTopics())
+	}
+	model.IncrementTopic("zebra", 3, 1)
+	if names := fmt.Sprintf("%v", model.TopicNames()); names != "[fruits empty animals topic_3]" {
+		t.Errorf("Unexpected topic names: %s", names)
+	}
+
+	if mapping := fmt.Sprintf("%v", model.CompactTopics()); mapping != "[0 -1 1 2]" {
+		t.Errorf("Unexpected mapping of topics: %s", mapping)
+	}
+	checkModelCounts(t, model, map[string]string{"apple": "[2 0 0]", "zebra": "[0 1 1]"}, "[2 1 1]")
+	if names := fmt.Sprint(model.TopicNames()); names != "[fruits animals topic_3]" {
+		t.Errorf("Unexpected topic names after compaction: %s", names)
+	}
+}
@@ -0,0 +1,229 @@
+package lda
+
+import (
+	"math"
+	"rand"
+)
+
+// The maximum number of E-step iterations for a document.
+const kOnlineVBMaxDocumentIterations = 100
+
+// The E-step of a document stops when the average change of its gamma
+// falls below this threshold.
+const kOnlineVBConvergenceThreshold = 0.001
+
+// Variational parameters are initialized by Gamma(kVBInitShape,
+// 1/kVBInitShape) draws, as in Hoffman's reference implementation.
+const kVBInitShape = 100.0
+
+// OnlineVBTrainer trains LDA by online variational Bayes (Hoffman, Blei
+// and Bach, "Online Learning for Latent Dirichlet Allocation", 2010).
+// Unlike Sampler, which keeps integer topic counts, it maintains the
+// topic-word variational parameters lambda, and updates them from one
+// mini-batch of documents at a time, so a large or streaming corpus
+// needs only one pass.  The vocabulary grows as new words show up in
+// mini-batches.
+//
+// The weight of the t-th mini-batch is rho_t = (tau0 + t)^(-kappa),
+// where kappa in (0.5, 1] controls how fast old mini-batches are
+// forgotten, and tau0 >= 0 down-weights early mini-batches.
+type OnlineVBTrainer struct {
+	num_topics  int
+	alpha       float64 // The parameter of symmetric Dirichlet on topics.
+	eta         float64 // The parameter of symmetric Dirichlet on words.
+	tau0        float64
+	kappa       float64
+	corpus_size int // The (estimated) total number of documents.
+	num_updates int
+	lambda      map[string][]float64 // lambda[word][topic]
+	lambda_sums []float64            // Sums of lambda over words, by topic.
+	rng         *rand.Rand           // nil means the global random source
+}
+
+func NewOnlineVBTrainer(num_topics int, alpha float64, eta float64,
+	tau0 float64, kappa float64, corpus_size int) *OnlineVBTrainer {
+	if num_topics <= 1 {
+		panic("num_topics must be >= 2")
+	}
+	if corpus_size <= 0 {
+		panic("corpus_size must be positive")
+	}
+	return &OnlineVBTrainer{
+		num_topics:  num_topics,
+		alpha:       alpha,
+		eta:         eta,
+		tau0:        tau0,
+		kappa:       kappa,
+		corpus_size: corpus_size,
+		lambda:      make(map[string][]float64),
+		lambda_sums: make([]float64, num_topics),
+	}
+}
+
+func (trainer *OnlineVBTrainer) SetRand(rng *rand.Rand) {
+	trainer.rng = rng
+}
+
+func (trainer *OnlineVBTrainer) NumTopics() int {
+	return trainer.num_topics
+}
+
+// Update lambda with a mini-batch of documents, and returns the weight
+// rho given to this mini-batch.
+func (trainer *OnlineVBTrainer) Update(batch []*Document) float64 {
+	// Words seen for the first time get random lambda.
+	for _, doc := range batch {
+		for _, word := range doc.unique_words {
+			if _, present := trainer.lambda[word]; !present {
+				l := make([]float64, trainer.num_topics)
+				for k := range l {
+					l[k] = randGamma(trainer.rng, kVBInitShape) / kVBInitShape
+					trainer.lambda_sums[k] += l[k]
+				}
+				trainer.lambda[word] = l
+			}
+		}
+	}
+
+	// E-step: collect sufficient statistics of the mini-batch.
+	digamma_lambda_sums := trainer.digammaLambdaSums()
+	sstats := make(map[string][]float64)
+	for _, doc := range batch {
+		trainer.inferDocument(doc, digamma_lambda_sums, sstats)
+	}
+
+	// M-step: blend lambda with its estimate from the mini-batch, as if
+	// the whole corpus consisted of corpus_size copies of it.
+	rho := math.Pow(trainer.tau0+float64(trainer.num_updates)+1, -trainer.kappa)
+	scale := float64(trainer.corpus_size) / float64(len(batch))
+	for k := range trainer.lambda_sums {
+		trainer.lambda_sums[k] = 0
+	}
+	for word, l := range trainer.lambda {
+		s := sstats[word]
+		for k := range l {
+			estimate := trainer.eta
+			if s != nil {
+				estimate += scale * s[k]
+			}
+			l[k] = (1-rho)*l[k] + rho*estimate
+			trainer.lambda_sums[k] += l[k]
+		}
+	}
+	trainer.num_updates++
+	return rho
+}
+
+// Infer the topic distribution of doc, i.e., the normalized variational
+// parameter gamma of its topic proportions.  Words not seen in training
+// are ignored.
+func (trainer *OnlineVBTrainer) InferTopicDistribution(doc *Document) Distribution {
+	gamma := trainer.inferDocument(doc, trainer.digammaLambdaSums(), nil)
+	sum := 0.0
+	for _, g := range gamma {
+		sum += g
+	}
+	for k := range gamma {
+		gamma[k] /= sum
+	}
+	return gamma
+}
+
+// Export the trained topics as a Model, whose counts are the expected
+// numbers of times each word is assigned each topic in the corpus, i.e.,
+// lambda minus the prior eta, rounded to integers.
+func (trainer *OnlineVBTrainer) Model() *Model {
+	model := NewModel(trainer.num_topics)
+	for word, l := range trainer.lambda {
+		for k, v := range l {
+			if count := int(v - trainer.eta + 0.5); count > 0 {
+				model.IncrementTopic(word, k, count)
+			}
+		}
+	}
+	return model
+}
+
+func (trainer *OnlineVBTrainer) digammaLambdaSums() []float64 {
+	sums := make([]float64, trainer.num_topics)
+	for k, s := range trainer.lambda_sums {
+		sums[k] = digamma(s)
+	}
+	return sums
+}
+
+// The E-step of a document: fit its variational parameters gamma (topic
+// proportions) and phi (topic assignments of words) by coordinate ascent,
+// with phi kept implicitly as exp(E[log theta]) * exp(E[log beta]) /
+// phi_norm.  Adds phi weighted by word counts into sstats, if not nil,
+// and returns gamma.
+func (trainer *OnlineVBTrainer) inferDocument(doc *Document,
+	digamma_lambda_sums []float64, sstats map[string][]float64) Distribution {
+	num_topics := trainer.num_topics
+
+	// exp(E[log beta]) of the document's words that have lambda.
+	words := make([]string, 0, len(doc.unique_words))
+	counts := make([]float64, 0, len(doc.unique_words))
+	exp_elog_beta := make([][]float64, 0, len(doc.unique_words))
+	for i, word := range doc.unique_words {
+		l, present := trainer.lambda[word]
+		if !present {
+			continue
+		}
+		e := make([]float64, num_topics)
+		for k := range e {
+			e[k] = math.Exp(digamma(l[k]) - digamma_lambda_sums[k])
+		}
+		words = append(words, word)
+		counts = append(counts, float64(doc.uniqueWordCount(i)))
+		exp_elog_beta = append(exp_elog_beta, e)
+	}
+
+	gamma := NewDistribution(num_topics)
+	for k := range gamma {
+		gamma[k] = randGamma(trainer.rng, kVBInitShape) / kVBInitShape
+	}
+	exp_elog_theta := expDirichletExpectation(gamma)
+	phi_norm := make([]float64, len(words))
+	updatePhiNorm := func() {
+		for i, e := range exp_elog_beta {
+			phi_norm[i] = 1e-100
+			for k := range e {
+				phi_norm[i] += exp_elog_theta[k] * e[k]
+			}
+		}
+	}
+	updatePhiNorm()
+
+	for iter := 0; iter < kOnlineVBMaxDocumentIterations; iter++ {
+		change := 0.0
+		for k := range gamma {
+			s := 0.0
+			for i, e := range exp_elog_beta {
+				s += counts[i] / phi_norm[i] * e[k]
+			}
+			new_gamma := trainer.alpha + exp_elog_theta[k]*s
+			change += math.Fabs(new_gamma - gamma[k])
+			gamma[k] = new_gamma
+		}
+		exp_elog_theta = expDirichletExpectation(gamma)
+		updatePhiNorm()
+		if change/float64(num_topics) < kOnlineVBConvergenceThreshold {
+			break
+		}
+	}
+
+	if sstats != nil {
+		for i, word := range words {
+			s, present := sstats[word]
+			if !present {
+				s = make([]float64, num_topics)
+				sstats[word] = s
+			}
+			for k := range s {
+				s[k] += exp_elog_theta[k] * counts[i] / phi_norm[i] * exp_elog_beta[i][k]
+			}
+		}
+	}
+	return gamma
+}
@@ -0,0 +1,53 @@
+package lda
+
+import (
+	"math"
+	"rand"
+	"testing"
+)
+
+func TestDigamma(t *testing.T) {
+	const kEulerGamma = 0.57721566490153286
+	for _, c := range []struct{ x, expected float64 }{
+		{1, -kEulerGamma},
+		{0.5, -kEulerGamma - 2*math.Log(2)},
+		{10, 2.25175258906672110},
+	} {
+		if d := digamma(c.x); math.Fabs(d-c.expected) > 1e-10 {
+			t.Errorf("digamma(%f) = %f, expecting %f", c.x, d, c.expected)
+		}
+	}
+}
+
+func TestOnlineVBTrainer(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 100, 2)
+	trainer := NewOnlineVBTrainer(2, 0.5, 0.1, 1.0, 0.7, len(*corpus))
+	trainer.SetRand(rng)
+
+	const kBatchSize = 10
+	previous_rho := 1.0
+	for pass := 0; pass < 5; pass++ {
+		for i := 0; i < len(*corpus); i += kBatchSize {
+			rho := trainer.Update((*corpus)[i : i+kBatchSize])
+			if rho <= 0 || rho >= previous_rho {
+				t.Errorf("Expecting rho decreasing in (0, 1), but got %f after %f",
+					rho, previous_rho)
+			}
+			previous_rho = rho
+		}
+	}
+
+	model := trainer.Model()
+	if model.NumWords() != len(kFruits)+len(kAnimals) {
+		t.Errorf("Expecting %d words, but got %d",
+			len(kFruits)+len(kAnimals), model.NumWords())
+	}
+	checkTwoTopicModel(t, model)
+
+	doc, _ := NewDocument("apple grape banana", 2)
+	distribution := trainer.InferTopicDistribution(doc)
+	if !distribution.IsValid() || distribution[dominantTopic(model, "apple")] < 0.8 {
+		t.Errorf("Unexpected topic distribution of fruits: %v", distribution)
+	}
+}
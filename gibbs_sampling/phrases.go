@@ -0,0 +1,261 @@
+package lda
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Scores of collocations used by DetectPhrases.
+const (
+	// Pointwise mutual information, log(P(a b) / (P(a) P(b))).
+	kPMIScore = "pmi"
+	// Dunning's log-likelihood ratio, G^2, of the hypothesis that a and b
+	// are dependent.
+	kLikelihoodRatioScore = "llr"
+)
+
+// The maximum number of words in a phrase, i.e., phrases are bigrams or
+// trigrams.
+const kMaxPhraseLength = 3
+
+// Separates the words of a phrase merged into a single token, e.g.,
+// new_york.
+const kPhraseSeparator = "_"
+
+// PhraseTable contains phrases, i.e., collocations of two or three words
+// to be merged into single tokens before documents are created, so that
+// a topic has "new_york" instead of "new" and "york".  Phrases are keyed
+// by their words separated by spaces, and valued by their scores.
+type PhraseTable struct {
+	phrases map[string]float64
+}
+
+func NewPhraseTable() *PhraseTable {
+	return &PhraseTable{make(map[string]float64)}
+}
+
+func (table *PhraseTable) NumPhrases() int {
+	return len(table.phrases)
+}
+
+// Add a phrase of two or three words with its score.
+func (table *PhraseTable) Add(words []string, score float64) {
+	if len(words) < 2 || len(words) > kMaxPhraseLength {
+		panic(fmt.Sprintf("A phrase must have 2 to %d words: %v", kMaxPhraseLength, words))
+	}
+	table.phrases[strings.Join(words, " ")] = score
+}
+
+// Returns the score of the phrase of words, and whether it is present.
+func (table *PhraseTable) Score(words []string) (score float64, present bool) {
+	score, present = table.phrases[strings.Join(words, " ")]
+	return
+}
+
+// Returns the phrases, with words separated by spaces, sorted.
+func (table *PhraseTable) Phrases() []string {
+	phrases := make([]string, 0, len(table.phrases))
+	for p := range table.phrases {
+		phrases = append(phrases, p)
+	}
+	sort.SortStrings(phrases)
+	return phrases
+}
+
+// Returns words with phrases merged into single tokens, preferring
+// longer phrases and, among those of the same length, earlier ones.
+func (table *PhraseTable) Merge(words []string) []string {
+	units := table.mergeUnits(words)
+	merged := make([]string, len(units))
+	for i, u := range units {
+		merged[i] = strings.Join(u, kPhraseSeparator)
+	}
+	return merged
+}
+
+// Returns words split into units, i.e., phrases and other words, as
+// Merge does.
+func (table *PhraseTable) mergeUnits(words []string) [][]string {
+	units := make([][]string, 0, len(words))
+	for i := 0; i < len(words); {
+		n := kMaxPhraseLength
+		for ; n >= 2; n-- {
+			if i+n <= len(words) {
+				if _, present := table.Score(words[i : i+n]); present {
+					break
+				}
+			}
+		}
+		if n < 2 {
+			n = 1
+		}
+		units = append(units, words[i:i+n])
+		i += n
+	}
+	return units
+}
+
+// Create a Document from text, words separated by whitespaces, with
+// phrases merged as at training.  If merging would leave fewer than the
+// two words of a Document, e.g., of text "new york", the words are kept
+// unmerged.
+func (table *PhraseTable) NewDocument(text string, num_topics int) (*Document, os.Error) {
+	words := strings.Fields(text)
+	if merged := table.Merge(words); len(merged) >= 2 {
+		words = merged
+	}
+	return NewDocument(strings.Join(words, " "), num_topics)
+}
+
+// Create a Document from text as NewDocument does, with phrases merged
+// by table if not nil.
+func newPhrasedDocument(text string, num_topics int, table *PhraseTable) (*Document, os.Error) {
+	if table != nil {
+		return table.NewDocument(text, num_topics)
+	}
+	return NewDocument(text, num_topics)
+}
+
+// Load a corpus as LoadCorpus does, with phrases merged.
+func LoadPhrasedCorpus(filename string, num_topics int, table *PhraseTable) (
+	corpus *Corpus, err os.Error) {
+	corpus = NewCorpus()
+	err = readLines(filename, func(line string) os.Error {
+		doc, err := table.NewDocument(line, num_topics)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+// Detect phrases in texts, i.e., sequences of words, as in word2phrase:
+// a first pass scores adjacent pairs of words, and a second pass, over
+// texts with the phrases of the first pass merged, scores pairs of
+// phrases and words, which yields trigrams.  Pairs occurring at least
+// min_count times with a score of score_type (kPMIScore or
+// kLikelihoodRatioScore) above threshold are phrases.
+func DetectPhrases(texts [][]string, score_type string, min_count int,
+	threshold float64) (*PhraseTable, os.Error) {
+	if score_type != kPMIScore && score_type != kLikelihoodRatioScore {
+		return nil, os.NewError("Unknown phrase score: " + score_type)
+	}
+	table := NewPhraseTable()
+	units := make([][][]string, len(texts))
+	for pass := 0; pass < kMaxPhraseLength-1; pass++ {
+		for i, words := range texts {
+			units[i] = table.mergeUnits(words)
+		}
+		detectPhrasePairs(units, score_type, min_count, threshold, table)
+	}
+	return table, nil
+}
+
+// Add to table the pairs of adjacent units, i.e., words or phrases, in
+// texts that are phrases of at most kMaxPhraseLength words.
+func detectPhrasePairs(texts [][][]string, score_type string, min_count int,
+	threshold float64, table *PhraseTable) {
+	first_counts := make(map[string]int)  // As the first unit of pairs.
+	second_counts := make(map[string]int) // As the second unit of pairs.
+	// Pairs are keyed by their units separated by a tab, which no word
+	// contains.
+	pair_counts := make(map[string]int)
+	num_pairs := 0
+	for _, units := range texts {
+		for i := 0; i+1 < len(units); i++ {
+			first, second := strings.Join(units[i], " "), strings.Join(units[i+1], " ")
+			first_counts[first]++
+			second_counts[second]++
+			num_pairs++
+			if len(units[i])+len(units[i+1]) <= kMaxPhraseLength {
+				pair_counts[first+"\t"+second]++
+			}
+		}
+	}
+
+	for pair, c := range pair_counts {
+		if c < min_count {
+			continue
+		}
+		i := strings.Index(pair, "\t")
+		first, second := pair[0:i], pair[i+1:]
+		var score float64
+		if score_type == kPMIScore {
+			score = pmi(c, first_counts[first], second_counts[second], num_pairs)
+		} else {
+			score = likelihoodRatio(c, first_counts[first], second_counts[second], num_pairs)
+		}
+		if score > threshold {
+			table.Add(strings.Fields(first+" "+second), score)
+		}
+	}
+}
+
+// Returns the PMI of a pair occurring n_ab times in n pairs, whose first
+// unit occurs first n_a times and second unit occurs second n_b times.
+func pmi(n_ab int, n_a int, n_b int, n int) float64 {
+	return math.Log(float64(n_ab) * float64(n) / (float64(n_a) * float64(n_b)))
+}
+
+// Returns the log-likelihood ratio of a pair as pmi does, or 0 if the
+// units are negatively associated.
+func likelihoodRatio(n_ab int, n_a int, n_b int, n int) float64 {
+	if float64(n_ab)*float64(n) <= float64(n_a)*float64(n_b) {
+		return 0
+	}
+	xlogx := func(x int) float64 {
+		if x <= 0 {
+			return 0
+		}
+		return float64(x) * math.Log(float64(x))
+	}
+	return 2 * (xlogx(n_ab) + xlogx(n_a-n_ab) + xlogx(n_b-n_ab) + xlogx(n-n_a-n_b+n_ab) -
+		xlogx(n_a) - xlogx(n-n_a) - xlogx(n_b) - xlogx(n-n_b) + xlogx(n))
+}
+
+// Load a phrase table saved by SavePhraseTable.
+func LoadPhraseTable(filename string) (table *PhraseTable, err os.Error) {
+	table = NewPhraseTable()
+	err = readLines(filename, func(line string) os.Error {
+		fields := strings.Split(line, "\t", -1)
+		if len(fields) != 2 {
+			return os.NewError("Invalid phrase: " + line)
+		}
+		words := strings.Fields(fields[0])
+		if len(words) < 2 || len(words) > kMaxPhraseLength {
+			return os.NewError("Invalid phrase: " + line)
+		}
+		score, err := strconv.Atof64(fields[1])
+		if err != nil {
+			return os.NewError("Invalid phrase score: " + line)
+		}
+		table.Add(words, score)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// Save the phrase table, one phrase per line, in the format of
+//
+// word_1 word_2 [word_3]<TAB>score
+func (table *PhraseTable) SavePhraseTable(filename string) os.Error {
+	return writeFile(filename, func(writer *bufio.Writer) {
+		for _, p := range table.Phrases() {
+			fmt.Fprintf(writer, "%s\t%f\n", p, table.phrases[p])
+		}
+	})
+}
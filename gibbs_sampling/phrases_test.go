@@ -0,0 +1,94 @@
+package lda
+
+import (
+	"fmt"
+	"rand"
+	"strings"
+	"testing"
+)
+
+const kPhraseTableFile = "testdata/phrases.txt"
+
+func TestLoadPhraseTableAndMerge(t *testing.T) {
+	table, err := LoadPhraseTable(kPhraseTableFile)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kPhraseTableFile + " : " + err.String())
+	}
+	if table.NumPhrases() != 3 {
+		t.Errorf("Expecting 3 phrases, but got %v", table.Phrases())
+	}
+	if score, present := table.Score([]string{"hot", "dog"}); !present || score != 4.25 {
+		t.Errorf("Unexpected score of hot dog: %f", score)
+	}
+
+	merged := table.Merge(strings.Fields("a hot dog in new york city and new york"))
+	const kMerged = "[a hot_dog in new_york_city and new_york]"
+	if s := fmt.Sprint(merged); s != kMerged {
+		t.Errorf("Expecting: " + kMerged + ", but got: " + s)
+	}
+
+	doc, err := table.NewDocument("new york new york hot", 2)
+	if err != nil {
+		t.Fatalf("Cannot create document: " + err.String())
+	}
+	if s := fmt.Sprint(doc.unique_words); s != "[hot new_york]" {
+		t.Errorf("Unexpected words: %s", s)
+	}
+
+	// A document of a single phrase keeps its words.
+	doc, err = table.NewDocument("new york", 2)
+	if err != nil {
+		t.Fatalf("Cannot create document of a phrase: " + err.String())
+	}
+	if s := fmt.Sprint(doc.unique_words); s != "[new york]" {
+		t.Errorf("Unexpected words: %s", s)
+	}
+}
+
+func TestDetectPhrases(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vocabulary := []string{"a", "the", "in", "of", "bar", "dog", "food", "park", "trip", "hotel"}
+	texts := make([][]string, 0)
+	for d := 0; d < 200; d++ {
+		words := make([]string, 0)
+		for i := 0; i < 10; i++ {
+			words = append(words, vocabulary[rng.Intn(len(vocabulary))])
+		}
+		if d%2 == 0 {
+			words = append(words, "new", "york", "city")
+		}
+		if d%3 == 0 {
+			words = append(words, "san", "francisco")
+		}
+		texts = append(texts, words)
+	}
+
+	for _, score_type := range []string{kPMIScore, kLikelihoodRatioScore} {
+		threshold := 3.0
+		if score_type == kLikelihoodRatioScore {
+			threshold = 100
+		}
+		table, err := DetectPhrases(texts, score_type, 5, threshold)
+		if err != nil {
+			t.Fatalf("Cannot detect phrases: " + err.String())
+		}
+		merged := fmt.Sprint(table.Merge(strings.Fields("the new york city trip to san francisco")))
+		const kMerged = "[the new_york_city trip to san_francisco]"
+		if merged != kMerged {
+			t.Errorf("%s: expecting %s, but got %s from phrases %v",
+				score_type, kMerged, merged, table.Phrases())
+		}
+		for _, p := range table.Phrases() {
+			for _, w := range strings.Fields(p) {
+				if w != "new" && w != "york" && w != "city" && w != "san" && w != "francisco" {
+					t.Errorf("%s: unexpected phrase %s", score_type, p)
+					break
+				}
+			}
+		}
+	}
+
+	if _, err := DetectPhrases(texts, "tfidf", 5, 0); err == nil {
+		t.Errorf("Expecting an error on an unknown score")
+	}
+}
@@ -0,0 +1,228 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"rand"
+	"strings"
+)
+
+// PolylingualDocument is a tuple of aligned documents in different
+// languages, e.g., translations of a product description, for the
+// polylingual topic model (Mimno et al., 2009).  The documents share a
+// topic histogram, i.e., a single topic distribution, while the words
+// of each language are drawn from topics of its own Model.
+type PolylingualDocument struct {
+	docs            []*Document // docs[l] is in language l, or nil if missing.
+	topic_histogram Histogram   // Shared by all docs.
+}
+
+type PolylingualCorpus []*PolylingualDocument
+
+// Create a PolylingualDocument from texts by language, where an empty
+// text means the document is missing in that language.  At least one
+// text must be present.  All words are assigned topic 0.
+func NewPolylingualDocument(texts []string, num_topics int) (
+	doc *PolylingualDocument, err os.Error) {
+	doc = &PolylingualDocument{make([]*Document, len(texts)), NewHistogram(num_topics)}
+	present := false
+	for l, text := range texts {
+		if len(strings.Fields(text)) == 0 {
+			continue
+		}
+		d, err := NewDocument(text, num_topics)
+		if err != nil {
+			return nil, err
+		}
+		d.topic_histogram = doc.topic_histogram
+		doc.topic_histogram[0] += d.Length()
+		doc.docs[l] = d
+		present = true
+	}
+	if !present {
+		return nil, os.NewError("Document is missing in all languages")
+	}
+	return doc, nil
+}
+
+// Returns the documents by language, nil for missing ones, whose topic
+// assignments are shared with the PolylingualDocument.
+func (doc *PolylingualDocument) Documents() []*Document {
+	return doc.docs
+}
+
+// Returns the total number of words in all languages.
+func (doc *PolylingualDocument) Length() int {
+	length := 0
+	for _, d := range doc.docs {
+		if d != nil {
+			length += d.Length()
+		}
+	}
+	return length
+}
+
+// Assign every word occurrence in all languages a topic drawn uniformly
+// at random.  A nil rng uses the global random source.
+func (doc *PolylingualDocument) RandomizeTopics(rng *rand.Rand) {
+	for k := range doc.topic_histogram {
+		doc.topic_histogram[k] = 0
+	}
+	for _, d := range doc.docs {
+		if d == nil {
+			continue
+		}
+		for i := range d.wordtopics {
+			d.wordtopics[i] = randIntn(rng, len(doc.topic_histogram))
+			doc.topic_histogram[d.wordtopics[i]]++
+		}
+	}
+}
+
+// Load a corpus for the polylingual topic model, where each line has
+// the form
+//
+// text_1<TAB>text_2<TAB>...<TAB>text_L
+//
+// i.e., the texts of a document in num_languages languages, in a fixed
+// order.  An empty text means the document is missing in that language.
+func LoadPolylingualCorpus(filename string, num_languages int, num_topics int) (
+	corpus *PolylingualCorpus, err os.Error) {
+	corpus = &PolylingualCorpus{}
+	err = readLines(filename, func(line string) os.Error {
+		texts := strings.Split(line, "\t", -1)
+		if len(texts) != num_languages {
+			return os.NewError(fmt.Sprintf("Expected %d tab-separated texts: %s",
+				num_languages, line))
+		}
+		doc, err := NewPolylingualDocument(texts, num_topics)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+// Returns the documents of corpus present in language, sharing topic
+// assignments with it.  The model of language is CreateModel(num_topics,
+// corpus.LanguageCorpus(language)).
+func (corpus *PolylingualCorpus) LanguageCorpus(language int) *Corpus {
+	docs := NewCorpus()
+	for _, doc := range *corpus {
+		if d := doc.docs[language]; d != nil {
+			*docs = append(*docs, d)
+		}
+	}
+	return docs
+}
+
+// PolylingualSampler trains the polylingual topic model by collapsed
+// Gibbs sampling.  Each language has a Sampler of its own model, which
+// samples the topics of words in that language; since documents of a
+// tuple share their topic histogram, each sampler sees the topics of
+// words in all languages.
+type PolylingualSampler struct {
+	samplers []*Sampler
+}
+
+// Create a PolylingualSampler with a model per language.  Models of
+// samples after burn-in are accumulated into accum_models, if not nil.
+func NewPolylingualSampler(topic_prior float64, word_prior float64,
+	models []*Model, accum_models []*Model) *PolylingualSampler {
+	if accum_models != nil && len(accum_models) != len(models) {
+		panic(fmt.Sprintf("%d models, but %d accum_models", len(models), len(accum_models)))
+	}
+	sampler := &PolylingualSampler{make([]*Sampler, len(models))}
+	for l, model := range models {
+		if model.NumTopics() != models[0].NumTopics() {
+			panic(fmt.Sprintf("models have (%d) and (%d) topics.",
+				models[0].NumTopics(), model.NumTopics()))
+		}
+		var accum_model *Model
+		if accum_models != nil {
+			accum_model = accum_models[l]
+		}
+		sampler.samplers[l] = NewSampler(topic_prior, word_prior, model, accum_model)
+	}
+	return sampler
+}
+
+// Make the sampler draw from rng instead of the global random source.
+func (sampler *PolylingualSampler) SetRand(rng *rand.Rand) {
+	for _, s := range sampler.samplers {
+		s.SetRand(rng)
+	}
+}
+
+func (sampler *PolylingualSampler) DocumentGibbsSampling(doc *PolylingualDocument) {
+	if len(doc.docs) != len(sampler.samplers) {
+		panic(fmt.Sprintf("doc has (%d) languages; sampler has (%d) languages.",
+			len(doc.docs), len(sampler.samplers)))
+	}
+	for l, d := range doc.docs {
+		if d != nil {
+			sampler.samplers[l].DocumentGibbsSampling(d, true)
+		}
+	}
+}
+
+func (sampler *PolylingualSampler) CorpusGibbsSampling(corpus *PolylingualCorpus, burn_in bool) {
+	for _, doc := range *corpus {
+		sampler.DocumentGibbsSampling(doc)
+	}
+
+	if !burn_in {
+		for _, s := range sampler.samplers {
+			if s.accum_model != nil {
+				s.accum_model.AccumulateModel(s.model)
+			}
+		}
+	}
+}
+
+// Returns the log-likelihood of the words of doc in all languages, each
+// given the topic distribution of the tuple and the model of its
+// language.
+func (sampler *PolylingualSampler) DocumentLogLikelihood(doc *PolylingualDocument) float64 {
+	num_topics := len(doc.topic_histogram)
+	prob_topic := NewDistribution(num_topics)
+	normalizer := float64(doc.Length()) + float64(num_topics)*sampler.samplers[0].topic_prior
+	for k, c := range doc.topic_histogram {
+		prob_topic[k] = (float64(c) + sampler.samplers[0].topic_prior) / normalizer
+	}
+
+	log_likelihood := 0.0
+	for l, d := range doc.docs {
+		if d == nil {
+			continue
+		}
+		s := sampler.samplers[l]
+		global_histogram := s.model.GetGlobalTopicHistogram()
+		for iter, _ := NewWordIterator(d); !iter.Done(); iter.Next() {
+			word_histogram := s.model.GetWordTopicHistogram(iter.Word())
+			prob_word := 0.0
+			for k := 0; k < num_topics; k++ {
+				prob_word += prob_topic[k] *
+					(float64(word_histogram[k]) + s.word_prior.Get(iter.Word(), k)) /
+					(float64(global_histogram[k]) + s.word_prior.Sum(k, s.model))
+			}
+			log_likelihood += math.Log(prob_word)
+		}
+	}
+	return log_likelihood
+}
+
+func (sampler *PolylingualSampler) CorpusLogLikelihood(corpus *PolylingualCorpus) float64 {
+	total_log_likelihood := 0.0
+	for _, doc := range *corpus {
+		total_log_likelihood += sampler.DocumentLogLikelihood(doc)
+	}
+	return total_log_likelihood
+}
@@ -0,0 +1,102 @@
+package lda
+
+import (
+	"fmt"
+	"rand"
+	"testing"
+)
+
+const kPolylingualCorpusFile = "testdata/polylingual_corpus.txt"
+
+func TestLoadPolylingualCorpus(t *testing.T) {
+	corpus, err := LoadPolylingualCorpus(kPolylingualCorpusFile, 2, 2)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kPolylingualCorpusFile + " : " + err.String())
+	}
+	if len(*corpus) != 3 {
+		t.Fatalf("Expecting 3 documents, but got %d", len(*corpus))
+	}
+	doc := (*corpus)[1]
+	if doc.Documents()[0] != nil || doc.Length() != 3 {
+		t.Errorf("Expecting a German document of 3 words, but got %v", doc.Documents())
+	}
+	if s := fmt.Sprint(doc.topic_histogram); s != "[3 0]" {
+		t.Errorf("Unexpected topic histogram: %s", s)
+	}
+	if n := len(*corpus.LanguageCorpus(0)); n != 2 {
+		t.Errorf("Expecting 2 English documents, but got %d", n)
+	}
+
+	if _, err := LoadPolylingualCorpus(kPolylingualCorpusFile, 3, 2); err == nil {
+		t.Errorf("Expecting an error on the wrong number of languages")
+	}
+	if _, err := NewPolylingualDocument([]string{"", " "}, 2); err == nil {
+		t.Errorf("Expecting an error on a document missing in all languages")
+	}
+}
+
+// Returns the German translation of English words in the test corpora.
+func germanWords(words []string) []string {
+	result := make([]string, len(words))
+	for i, w := range words {
+		result[i] = w + "_de"
+	}
+	return result
+}
+
+func TestPolylingualGibbsSampling(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	german_fruits, german_animals := germanWords(kFruits), germanWords(kAnimals)
+	corpus := &PolylingualCorpus{}
+	for d := 0; d < 40; d++ {
+		english, german := "", ""
+		for i := 0; i < 8; i++ {
+			if d%2 == 0 {
+				english += kFruits[rng.Intn(len(kFruits))] + " "
+				german += german_fruits[rng.Intn(len(german_fruits))] + " "
+			} else {
+				english += kAnimals[rng.Intn(len(kAnimals))] + " "
+				german += german_animals[rng.Intn(len(german_animals))] + " "
+			}
+		}
+		if d%5 == 0 {
+			english = ""
+		}
+		doc, err := NewPolylingualDocument([]string{english, german}, 2)
+		if err != nil {
+			t.Fatalf("Cannot create document: " + err.String())
+		}
+		doc.RandomizeTopics(rng)
+		*corpus = append(*corpus, doc)
+	}
+
+	models := []*Model{CreateModel(2, corpus.LanguageCorpus(0)),
+		CreateModel(2, corpus.LanguageCorpus(1))}
+	sampler := NewPolylingualSampler(0.1, 0.01, models, nil)
+	sampler.SetRand(rng)
+	initial := sampler.CorpusLogLikelihood(corpus)
+	for iter := 0; iter < 50; iter++ {
+		sampler.CorpusGibbsSampling(corpus, true)
+	}
+	if final := sampler.CorpusLogLikelihood(corpus); final <= initial {
+		t.Errorf("Log-likelihood did not improve: %f -> %f", initial, final)
+	}
+
+	for _, doc := range *corpus {
+		total := 0
+		for _, c := range doc.topic_histogram {
+			total += c
+		}
+		if total != doc.Length() {
+			t.Fatalf("Topic histogram %v of a document of %d words",
+				doc.topic_histogram, doc.Length())
+		}
+	}
+	checkTwoTopicModel(t, models[0])
+	if dominantTopic(models[0], kFruits[0]) != dominantTopic(models[1], german_fruits[0]) ||
+		dominantTopic(models[0], kAnimals[0]) != dominantTopic(models[1], german_animals[0]) {
+		t.Errorf("Topics are not aligned across languages: %v vs. %v",
+			models[0].GetWordTopicHistogram(kFruits[0]),
+			models[1].GetWordTopicHistogram(german_fruits[0]))
+	}
+}
@@ -0,0 +1,113 @@
+package lda
+
+import (
+	"fmt"
+	"rand"
+)
+
+// Zero the counts of words in topics for which keep returns false,
+// recompute global_histogram, and remove words left without counts.
+// Returns the number of removed words.
+func (model *Model) pruneCounts(keep func(word string, topic int, count int) bool) int {
+	for k := range model.global_histogram {
+		model.global_histogram[k] = 0
+	}
+	removed := 0
+	for word, hist := range model.topic_histograms {
+		total := 0
+		for k, c := range hist {
+			if c > 0 && !keep(word, k, c) {
+				hist[k] = 0
+			}
+			model.global_histogram[k] += hist[k]
+			total += hist[k]
+		}
+		if total == 0 {
+			model.topic_histograms[word] = nil, false
+			removed++
+		}
+	}
+	return removed
+}
+
+// Remove words whose total counts over all topics are less than
+// min_count.  Returns the number of removed words.
+func (model *Model) PruneRareWords(min_count int) int {
+	totals := make(map[string]int)
+	for word, hist := range model.topic_histograms {
+		for _, c := range hist {
+			totals[word] += c
+		}
+	}
+	return model.pruneCounts(func(word string, topic int, count int) bool {
+		return totals[word] >= min_count
+	})
+}
+
+// Keep only the counts of the num_words most frequent words of every
+// topic, ties broken as by TopWords.  Returns the number of removed words.
+func (model *Model) PruneTopWords(num_words int) int {
+	if num_words < 0 {
+		panic(fmt.Sprintf("negative number of words: %d", num_words))
+	}
+	top := make([]map[string]bool, model.NumTopics())
+	for k := range top {
+		top[k] = make(map[string]bool)
+		for _, w := range model.TopWords(k, num_words) {
+			top[k][w.Word] = true
+		}
+	}
+	return model.pruneCounts(func(word string, topic int, count int) bool {
+		return top[topic][word]
+	})
+}
+
+// Zero the counts of words in topics whose proportions of the topics,
+// i.e., unsmoothed P(word|topic), are less than min_probability.  Returns
+// the number of removed words.
+func (model *Model) PruneCounts(min_probability float64) int {
+	global_histogram := append(Histogram{}, model.global_histogram...)
+	return model.pruneCounts(func(word string, topic int, count int) bool {
+		return float64(count) >= min_probability*float64(global_histogram[topic])
+	})
+}
+
+// Returns the average log-likelihood per token of held-out corpus by
+// model.  The topics of every document are inferred by Gibbs sampling
+// for iterations iterations, with the model fixed.  Both the inference and
+// the likelihood smooth P(word|topic) by word_prior over num_words words,
+// e.g., the vocabulary of the model before pruning, so that likelihoods of
+// models with different vocabularies are comparable.  The topic
+// assignments of corpus are overwritten.  A nil rng uses the global
+// random source.
+func HeldOutLogLikelihood(model *Model, corpus *Corpus, topic_prior float64, word_prior float64,
+	num_words int, iterations int, rng *rand.Rand) float64 {
+	if num_words < model.NumWords() {
+		panic(fmt.Sprintf("num_words (%d) is less than the words of model (%d)",
+			num_words, model.NumWords()))
+	}
+	sampler := NewSampler(topic_prior, word_prior, model, nil)
+	prior := NewWordPrior(word_prior, model.NumTopics())
+	prior.SetNumWords(num_words)
+	sampler.SetWordPrior(prior)
+	sampler.SetRand(rng)
+	num_topics := model.NumTopics()
+	log_likelihood := 0.0
+	num_tokens := 0
+	for _, doc := range *corpus {
+		if len(doc.topic_histogram) != num_topics {
+			panic(fmt.Sprintf("doc has (%d) topics; model has (%d) topics.",
+				len(doc.topic_histogram), num_topics))
+		}
+		doc.RandomizeTopics(rng)
+		for iter := 0; iter < iterations; iter++ {
+			sampler.DocumentGibbsSampling(doc, false)
+		}
+		log_likelihood += sampler.DocumentLogLikelihood(doc)
+		num_tokens += doc.Length()
+	}
+	if num_tokens == 0 {
+		return 0
+	}
+	return log_likelihood / float64(num_tokens)
+}
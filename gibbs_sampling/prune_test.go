@@ -0,0 +1,106 @@
+package lda
+
+import (
+	"math"
+	"rand"
+	"testing"
+)
+
+// Returns a model of words a to e in 2 topics.
+func newPruneTestModel() *Model {
+	model := NewModel(2)
+	model.IncrementTopic("a", 0, 50)
+	model.IncrementTopic("b", 0, 30)
+	model.IncrementTopic("b", 1, 2)
+	model.IncrementTopic("c", 0, 3)
+	model.IncrementTopic("c", 1, 40)
+	model.IncrementTopic("d", 1, 1)
+	model.IncrementTopic("e", 0, 1)
+	return model
+}
+
+func TestPruneModel(t *testing.T) {
+	model := newPruneTestModel()
+	if removed := model.PruneRareWords(2); removed != 2 {
+		t.Errorf("Expecting 2 removed words, but got %d", removed)
+	}
+	const kRare = "[] [83 42] a:[50 0] b:[30 2] c:[3 40]"
+	if s := modelString(model); s != kRare {
+		t.Errorf("Expecting: " + kRare + ", but got: " + s)
+	}
+
+	model = newPruneTestModel()
+	if removed := model.PruneTopWords(2); removed != 2 {
+		t.Errorf("Expecting 2 removed words, but got %d", removed)
+	}
+	const kTop = "[] [80 42] a:[50 0] b:[30 2] c:[0 40]"
+	if s := modelString(model); s != kTop {
+		t.Errorf("Expecting: " + kTop + ", but got: " + s)
+	}
+
+	model = newPruneTestModel()
+	if removed := model.PruneCounts(0.05); removed != 2 {
+		t.Errorf("Expecting 2 removed words, but got %d", removed)
+	}
+	const kCounts = "[] [80 40] a:[50 0] b:[30 0] c:[0 40]"
+	if s := modelString(model); s != kCounts {
+		t.Errorf("Expecting: " + kCounts + ", but got: " + s)
+	}
+}
+
+func TestHeldOutLogLikelihood(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	model := NewModel(2)
+	for _, w := range kFruits {
+		model.IncrementTopic(w, 0, 100)
+	}
+	for _, w := range kAnimals {
+		model.IncrementTopic(w, 1, 100)
+	}
+	model.IncrementTopic("rare", 0, 1)
+	num_words := model.NumWords()
+
+	held_out := newTwoTopicCorpus(rng, 20, 2)
+	full := HeldOutLogLikelihood(model, held_out, 0.1, 0.01, num_words, 10, rng)
+	model.PruneRareWords(2)
+	pruned := HeldOutLogLikelihood(model, held_out, 0.1, 0.01, num_words, 10, rng)
+	if full >= 0 || full < -3 || math.Fabs(full-pruned) > 0.01 {
+		t.Errorf("Unexpected held-out log-likelihoods: %f before and %f after pruning", full, pruned)
+	}
+
+	// Keeping a single word of each topic loses the likelihood of the others.
+	model.PruneTopWords(1)
+	if top := HeldOutLogLikelihood(model, held_out, 0.1, 0.01, num_words, 10, rng); top >= pruned-1 {
+		t.Errorf("Expecting a much lower likelihood than %f, but got %f", pruned, top)
+	}
+}
+
+func TestHeldOutLogLikelihoodOfUnprunedModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	model := NewModel(2)
+	for _, w := range kFruits {
+		model.IncrementTopic(w, 0, 100)
+	}
+	for _, w := range kAnimals {
+		model.IncrementTopic(w, 1, 100)
+	}
+	held_out := newTwoTopicCorpus(rng, 20, 2)
+	score := HeldOutLogLikelihood(model, held_out, 0.1, 0.01, model.NumWords(), 10, rand.New(rand.NewSource(2)))
+
+	// The same inference and likelihood by a sampler of the model.
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	rng = rand.New(rand.NewSource(2))
+	sampler.SetRand(rng)
+	num_tokens := 0
+	for _, doc := range *held_out {
+		doc.RandomizeTopics(rng)
+		for iter := 0; iter < 10; iter++ {
+			sampler.DocumentGibbsSampling(doc, false)
+		}
+		num_tokens += doc.Length()
+	}
+	expected := sampler.CorpusLogLikelihood(held_out) / float64(num_tokens)
+	if math.Fabs(score-expected) > 1e-12 {
+		t.Errorf("Expecting held-out log-likelihood %f, but got %f", expected, score)
+	}
+}
@@ -0,0 +1,249 @@
+package lda
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The number of versions of each model kept in a ModelRegistry, so that
+// clients pinned to a version keep working for a while after a reload.
+const kNumRetainedModelVersions = 2
+
+// ModelRegistry holds read-only models keyed by name and version.  A
+// model registered with a filename is reloaded by Refresh (or Watch)
+// when the file changes on disk, and then stays unchanged until the next
+// Refresh, so that a file being written is not loaded half-way; each
+// (re)load gets a new version number.  A reload is atomic: the new model
+// is loaded completely before it replaces the old one, and a failed
+// reload keeps the old model.  Callers must not modify the returned
+// models.
+//
+// Model files should be replaced by renaming complete files over them, as
+// SaveModel does, so that no Refresh sees a partial file however slowly
+// it is written, and rewrites of the same size within the resolution of
+// modification times are detected by the changed inode.
+type ModelRegistry struct {
+	mutex   sync.RWMutex // guards entries
+	entries map[string]*registryEntry
+}
+
+type registryEntry struct {
+	filename string // empty if the model is not backed by a file
+	loaded   fileState
+	changed  fileState // of the file seen changed by the last Refresh
+	versions []*RegisteredModel // the latest version is the last one
+}
+
+// The state of a file by which changes are detected.
+type fileState struct {
+	ino      uint64
+	mtime_ns int64
+	size     int64
+}
+
+func newFileState(info *os.FileInfo) fileState {
+	return fileState{info.Ino, info.Mtime_ns, info.Size}
+}
+
+// RegisteredModel is a snapshot of a model in the registry.
+type RegisteredModel struct {
+	Name     string
+	Version  int
+	Filename string
+	Model    *Model
+}
+
+func NewModelRegistry() *ModelRegistry {
+	return &ModelRegistry{entries: make(map[string]*registryEntry)}
+}
+
+// Add model under name, or replace the latest version of name by model.
+// Models added this way are not reloaded from disk.
+func (registry *ModelRegistry) Add(name string, model *Model) *RegisteredModel {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	entry, present := registry.entries[name]
+	if !present {
+		entry = new(registryEntry)
+		registry.entries[name] = entry
+	}
+	entry.filename = ""
+	return entry.addVersion(name, "", model)
+}
+
+// Load the model in filename and register it under name.  The model will
+// be reloaded by Refresh when filename changes.
+func (registry *ModelRegistry) Register(name string, filename string) (*RegisteredModel, os.Error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, os.NewError("Cannot stat file: " + filename + " " + err.String())
+	}
+	model, err := LoadModel(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	entry, present := registry.entries[name]
+	if !present {
+		entry = new(registryEntry)
+		registry.entries[name] = entry
+	}
+	entry.filename = filename
+	entry.loaded = newFileState(info)
+	entry.changed = entry.loaded
+	return entry.addVersion(name, filename, model), nil
+}
+
+// Must be called with registry.mutex held for writing.
+func (entry *registryEntry) addVersion(name string, filename string, model *Model) *RegisteredModel {
+	version := 1
+	if len(entry.versions) > 0 {
+		version = entry.versions[len(entry.versions)-1].Version + 1
+	}
+	registered := &RegisteredModel{name, version, filename, model}
+	entry.versions = append(entry.versions, registered)
+	if len(entry.versions) > kNumRetainedModelVersions {
+		entry.versions = entry.versions[len(entry.versions)-kNumRetainedModelVersions:]
+	}
+	return registered
+}
+
+// Returns the latest version of the model registered under name.
+func (registry *ModelRegistry) Get(name string) (*RegisteredModel, os.Error) {
+	return registry.GetVersion(name, 0)
+}
+
+// Returns the given version of the model registered under name, or the
+// latest version if version is 0.  Only the most recent versions are
+// retained.
+func (registry *ModelRegistry) GetVersion(name string, version int) (*RegisteredModel, os.Error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	entry, present := registry.entries[name]
+	if !present {
+		return nil, os.NewError("Unknown model: " + name)
+	}
+	if version == 0 {
+		return entry.versions[len(entry.versions)-1], nil
+	}
+	for _, v := range entry.versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, os.NewError("Version " + strconv.Itoa(version) +
+		" of model " + name + " is not loaded")
+}
+
+// Returns the latest versions of all registered models, sorted by name.
+func (registry *ModelRegistry) List() []*RegisteredModel {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	names := make([]string, 0, len(registry.entries))
+	for name := range registry.entries {
+		names = append(names, name)
+	}
+	sort.SortStrings(names)
+	models := make([]*RegisteredModel, len(names))
+	for i, name := range names {
+		entry := registry.entries[name]
+		models[i] = entry.versions[len(entry.versions)-1]
+	}
+	return models
+}
+
+// Reload every file-backed model whose file has changed (in inode,
+// modification time or size) since it was last loaded, and has not
+// changed since the previous Refresh.  A file seen changing is reloaded by
+// the next Refresh that finds it unchanged.  Returns the newly loaded
+// models, and the last error encountered, if any.  A model that fails to
+// reload keeps serving its previous version.
+func (registry *ModelRegistry) Refresh() (reloaded []*RegisteredModel, err os.Error) {
+	type candidate struct {
+		name     string
+		filename string
+		loaded   fileState
+		changed  fileState
+	}
+	candidates := make([]candidate, 0)
+	registry.mutex.RLock()
+	for name, entry := range registry.entries {
+		if len(entry.filename) > 0 {
+			candidates = append(candidates,
+				candidate{name, entry.filename, entry.loaded, entry.changed})
+		}
+	}
+	registry.mutex.RUnlock()
+
+	reloaded = make([]*RegisteredModel, 0)
+	for _, c := range candidates {
+		info, stat_err := os.Stat(c.filename)
+		if stat_err != nil {
+			err = os.NewError("Cannot stat file: " + c.filename + " " + stat_err.String())
+			continue
+		}
+		state := newFileState(info)
+		if state == c.loaded {
+			continue
+		}
+		if state != c.changed {
+			// Still being written, perhaps; wait for the next Refresh.
+			registry.mutex.Lock()
+			entry, present := registry.entries[c.name]
+			if present && entry.filename == c.filename {
+				entry.changed = state
+			}
+			registry.mutex.Unlock()
+			continue
+		}
+
+		// Load outside the lock, so that requests are served by the
+		// current version while the new one is being loaded.
+		model, load_err := LoadModel(c.filename)
+		if load_err != nil {
+			err = load_err
+			continue
+		}
+
+		registry.mutex.Lock()
+		entry, present := registry.entries[c.name]
+		if present && entry.filename == c.filename {
+			entry.loaded = state
+			reloaded = append(reloaded, entry.addVersion(c.name, c.filename, model))
+		}
+		registry.mutex.Unlock()
+	}
+	return reloaded, err
+}
+
+// Call Refresh every interval_ns nanoseconds in a separate goroutine,
+// until a value is sent to (or the caller closes) the returned channel.
+func (registry *ModelRegistry) Watch(interval_ns int64) chan<- bool {
+	stop := make(chan bool)
+	ticker := time.NewTicker(interval_ns)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				reloaded, err := registry.Refresh()
+				for _, m := range reloaded {
+					log.Printf("Reloaded model %s version %d from %s",
+						m.Name, m.Version, m.Filename)
+				}
+				if err != nil {
+					log.Printf("Error in reloading models: %s", err.String())
+				}
+			}
+		}
+	}()
+	return stop
+}
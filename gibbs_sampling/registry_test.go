@@ -0,0 +1,189 @@
+package lda
+
+import (
+	"bytes"
+	"fmt"
+	"http"
+	"json"
+	"os"
+	"testing"
+)
+
+const kTmpRegistryModelFile = "/tmp/tmp_registry_model.txt"
+
+// Models of odd versions have 2 topics, and those of even versions have
+// 3 topics, so that a response mixing up versions is detectable.
+var kRegistryTestModels = []string{
+	"apple 1 0\norange 0 1\nzebra 3 0\n",
+	"apple 1 0 0\norange 0 1 0\nzebra 0 0 3\n",
+}
+
+func writeTestModelFile(t *testing.T, filename string, content string) {
+	file, err := os.Open(filename, os.O_WRONLY|os.O_CREAT|os.O_TRUNC, 0666)
+	if err != nil {
+		t.Fatalf("Cannot open file: " + filename + " " + err.String())
+	}
+	defer file.Close()
+	if _, err := file.Write([]byte(content)); err != nil {
+		t.Fatalf("Cannot write file: " + filename + " " + err.String())
+	}
+}
+
+func TestModelRegistry(t *testing.T) {
+	registry := NewModelRegistry()
+	if _, err := registry.Get("default"); err == nil {
+		t.Errorf("Expecting an error getting an unregistered model")
+	}
+	if _, err := registry.Register("default", "/tmp/no_such_model.txt"); err == nil {
+		t.Errorf("Expecting an error registering a non-existing file")
+	}
+
+	writeTestModelFile(t, kTmpRegistryModelFile, kRegistryTestModels[0])
+	registered, err := registry.Register("default", kTmpRegistryModelFile)
+	if err != nil {
+		t.Fatalf("Cannot register: " + err.String())
+	}
+	if registered.Version != 1 || registered.Model.NumTopics() != 2 {
+		t.Errorf("Unexpected version %d with %d topics",
+			registered.Version, registered.Model.NumTopics())
+	}
+
+	if reloaded, err := registry.Refresh(); err != nil || len(reloaded) != 0 {
+		t.Errorf("Unexpected reload of an unchanged file: %v %v", reloaded, err)
+	}
+
+	for version := 2; version <= 3; version++ {
+		writeTestModelFile(t, kTmpRegistryModelFile, kRegistryTestModels[(version-1)%2])
+		// The change is seen, but the file may still be being written.
+		if reloaded, err := registry.Refresh(); err != nil || len(reloaded) != 0 {
+			t.Fatalf("Unexpected reload of a changing file: %v %v", reloaded, err)
+		}
+		reloaded, err := registry.Refresh()
+		if err != nil || len(reloaded) != 1 || reloaded[0].Version != version {
+			t.Fatalf("Expecting to reload version %d, but got: %v %v", version, reloaded, err)
+		}
+	}
+	if latest, _ := registry.Get("default"); latest.Version != 3 {
+		t.Errorf("Expecting latest version 3, but got %d", latest.Version)
+	}
+	if previous, err := registry.GetVersion("default", 2); err != nil ||
+		previous.Model.NumTopics() != 3 {
+		t.Errorf("Expecting version 2 with 3 topics to be retained")
+	}
+	if _, err := registry.GetVersion("default", 1); err == nil {
+		t.Errorf("Expecting version 1 not to be retained")
+	}
+
+	// A broken file keeps the previous version.
+	writeTestModelFile(t, kTmpRegistryModelFile, "apple 1 x\n")
+	registry.Refresh()
+	if reloaded, err := registry.Refresh(); err == nil || len(reloaded) != 0 {
+		t.Errorf("Expecting a failed reload, but got: %v %v", reloaded, err)
+	}
+	if latest, _ := registry.Get("default"); latest.Version != 3 {
+		t.Errorf("Expecting latest version 3, but got %d", latest.Version)
+	}
+}
+
+func TestModelRegistryPartialWrite(t *testing.T) {
+	writeTestModelFile(t, kTmpRegistryModelFile, kRegistryTestModels[0])
+	registry := NewModelRegistry()
+	if _, err := registry.Register("default", kTmpRegistryModelFile); err != nil {
+		t.Fatalf("Cannot register: " + err.String())
+	}
+
+	// A partial file that parses, with the last count cut to its leading
+	// digit, is not loaded while it keeps changing.
+	const kModel = "apple 1 0\norange 0 1\nzebra 0 31\n"
+	writeTestModelFile(t, kTmpRegistryModelFile, kModel[0:len(kModel)-2])
+	if reloaded, err := registry.Refresh(); err != nil || len(reloaded) != 0 {
+		t.Errorf("Unexpected reload of a partial file: %v %v", reloaded, err)
+	}
+	writeTestModelFile(t, kTmpRegistryModelFile, kModel)
+	if reloaded, err := registry.Refresh(); err != nil || len(reloaded) != 0 {
+		t.Errorf("Unexpected reload of a changing file: %v %v", reloaded, err)
+	}
+	reloaded, err := registry.Refresh()
+	if err != nil || len(reloaded) != 1 {
+		t.Fatalf("Expecting to reload the complete file, but got: %v %v", reloaded, err)
+	}
+	if h := reloaded[0].Model.GetWordTopicHistogram("zebra"); h[1] != 31 {
+		t.Errorf("Expecting count 31 of zebra, but got %v", h)
+	}
+
+	// A model of the same size saved right away is detected by its inode.
+	model, _ := LoadModel(kTmpRegistryModelFile)
+	model.IncrementTopic("apple", 0, -1)
+	model.IncrementTopic("apple", 1, 1)
+	if err := model.SaveModel(kTmpRegistryModelFile); err != nil {
+		t.Fatalf("Cannot save model: " + err.String())
+	}
+	registry.Refresh()
+	reloaded, err = registry.Refresh()
+	if err != nil || len(reloaded) != 1 {
+		t.Fatalf("Expecting to reload the saved model, but got: %v %v", reloaded, err)
+	}
+	if h := reloaded[0].Model.GetWordTopicHistogram("apple"); h[1] != 1 {
+		t.Errorf("Expecting count 1 of apple in topic 1, but got %v", h)
+	}
+}
+
+func TestModelRegistryReloadUnderLoad(t *testing.T) {
+	writeTestModelFile(t, kTmpRegistryModelFile, kRegistryTestModels[0])
+	registry := NewModelRegistry()
+	if _, err := registry.Register("default", kTmpRegistryModelFile); err != nil {
+		t.Fatalf("Cannot register: " + err.String())
+	}
+	server := NewServer(registry, 0.1, 0.01, 2, 2)
+	server.SetBatchParallelism(2)
+
+	const kNumClients = 4
+	const kNumReloads = 20
+	stop := make(chan bool)
+	errors := make(chan string, kNumClients)
+	for c := 0; c < kNumClients; c++ {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					errors <- ""
+					return
+				default:
+				}
+				body := "{\"Text\": \"apple orange zebra\"}\n{\"Text\": \"zebra zebra apple\"}\n"
+				recorder := serve(server, "POST", "/infer/batch", body)
+				if recorder.Code != http.StatusOK {
+					errors <- fmt.Sprintf("Unexpected status %d", recorder.Code)
+					return
+				}
+				decoder := json.NewDecoder(bytes.NewBuffer(recorder.Body.Bytes()))
+				for i := 0; i < 2; i++ {
+					var response BatchInferResponse
+					if err := decoder.Decode(&response); err != nil {
+						errors <- "Cannot parse response: " + err.String()
+						return
+					}
+					if expected := 2 + (response.Version+1)%2; len(response.Distribution) != expected {
+						errors <- fmt.Sprintf("Version %d has %d topics, expecting %d",
+							response.Version, len(response.Distribution), expected)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for version := 2; version <= kNumReloads+1; version++ {
+		writeTestModelFile(t, kTmpRegistryModelFile, kRegistryTestModels[(version-1)%2])
+		registry.Refresh()
+		if reloaded, err := registry.Refresh(); err != nil || len(reloaded) != 1 {
+			t.Errorf("Expecting to reload version %d, but got: %v %v", version, reloaded, err)
+		}
+	}
+	close(stop)
+	for c := 0; c < kNumClients; c++ {
+		if msg := <-errors; len(msg) > 0 {
+			t.Errorf(msg)
+		}
+	}
+}
@@ -3,51 +3,124 @@ package lda
 import (
 	"fmt"
 	"math"
+	"os"
+	"rand"
 )
 
 type Sampler struct {
 	topic_prior float64
-	word_prior  float64
+	word_prior  *WordPrior
 	model       *Model
 	accum_model *Model
+	rng         *rand.Rand // nil means the global random source
+	in_order    bool       // Whether words are sampled in their text order.
 }
 
 func NewSampler(topic_prior float64, word_prior float64, model *Model, accum_model *Model) *Sampler {
-	return &Sampler{topic_prior, word_prior, model, accum_model}
+	return &Sampler{topic_prior, NewWordPrior(word_prior, model.NumTopics()),
+		model, accum_model, nil, false}
+}
+
+// Replace the symmetric word prior by word_prior, e.g., one boosting seed
+// words in their topics.  Both sampling and likelihood honor it.
+func (sampler *Sampler) SetWordPrior(word_prior *WordPrior) {
+	if word_prior.NumTopics() != sampler.model.NumTopics() {
+		panic(fmt.Sprintf("word_prior has (%d) topics; model has (%d) topics.",
+			word_prior.NumTopics(), sampler.model.NumTopics()))
+	}
+	sampler.word_prior = word_prior
+}
+
+// Make the sampler draw from rng instead of the global random source.
+// Samplers running concurrently should each have their own rng.
+func (sampler *Sampler) SetRand(rng *rand.Rand) {
+	sampler.rng = rng
+}
+
+// Returns the Dirichlet prior on topic of doc: its own topic prior, if
+// any, or the symmetric topic_prior.
+func (sampler *Sampler) documentTopicPrior(doc *Document, topic int) float64 {
+	if doc.topic_prior != nil {
+		return doc.topic_prior[topic]
+	}
+	return sampler.topic_prior
+}
+
+// Returns the sum of the Dirichlet prior on topics of doc over its
+// allowed topics.
+func (sampler *Sampler) documentTopicPriorSum(doc *Document) float64 {
+	if doc.topic_prior == nil {
+		return sampler.topic_prior * float64(doc.NumAllowedTopics())
+	}
+	sum := 0.0
+	for k, a := range doc.topic_prior {
+		if doc.IsTopicAllowed(k) {
+			sum += a
+		}
+	}
+	return sum
 }
 
 func (sampler *Sampler) GenerateTopicDistributionForWord(doc *Document,
 	word string, target_topic int, update_model bool) Distribution {
 	num_topics := sampler.model.NumTopics()
-	num_words := sampler.model.NumWords()
 	distribution := NewDistribution(num_topics)
 	word_histogram := sampler.model.GetWordTopicHistogram(word)
+	word_boosts := sampler.word_prior.wordBoosts(word)
 
 	for k := 0; k < num_topics; k++ {
+		if !doc.IsTopicAllowed(k) {
+			continue // Leave distribution[k] zero.
+		}
 		// We will need to temporarily unassign the word from its old
 		// topic, which we accomplish by decrementing the appropriate
-		// counts by 1.
-		adjustment := 0
-		if update_model && k == target_topic {
-			adjustment = -1
+		// counts by 1.  The model counts include the word only if we
+		// are updating the model; when folding in a document for
+		// inference, only the document counts do.
+		model_adjustment := 0
+		document_adjustment := 0
+		if k == target_topic {
+			document_adjustment = -1
+			if update_model {
+				model_adjustment = -1
+			}
 		}
-		topic_word_factor := float64(word_histogram[k] + adjustment)
-		global_topic_factor := float64(sampler.model.GetGlobalTopicHistogram()[k] + adjustment)
-		document_topic_factor := float64(doc.topic_histogram[k] + adjustment)
-		distribution[k] = (topic_word_factor + sampler.word_prior) *
-                        (document_topic_factor + sampler.topic_prior) /
-                        (global_topic_factor + float64(num_words) * sampler.word_prior)
+		topic_word_factor := float64(word_histogram[k] + model_adjustment)
+		global_topic_factor := float64(sampler.model.GetGlobalTopicHistogram()[k] + model_adjustment)
+		document_topic_factor := float64(doc.topic_histogram[k] + document_adjustment)
+		word_prior := sampler.word_prior.base
+		if word_boosts != nil {
+			word_prior += word_boosts[k]
+		}
+		distribution[k] = (topic_word_factor + word_prior) *
+                        (document_topic_factor + sampler.documentTopicPrior(doc, k)) /
+                        (global_topic_factor + sampler.word_prior.Sum(k, sampler.model))
 	}
 	return distribution
 }
 
+// Make the sampler visit the words of documents in their original order
+// in the text, instead of grouped by words, e.g., for extensions whose
+// topic of a word depends on its neighbors.
+func (sampler *Sampler) SetSampleInOrder(in_order bool) {
+	sampler.in_order = in_order
+}
+
+// Returns an iterator over the words of doc in the sampling order.
+func (sampler *Sampler) newWordIterator(doc *Document) (*WordIterator, os.Error) {
+	if sampler.in_order {
+		return NewOrderedWordIterator(doc)
+	}
+	return NewWordIterator(doc)
+}
+
 func (sampler *Sampler) DocumentGibbsSampling(doc *Document, update_model bool) {
-	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+	for iter, _ := sampler.newWordIterator(doc); !iter.Done(); iter.Next() {
 		// This is a (non-normalized) probability distribution from which we will
 		// select the new topic for the current word occurrence.
 		new_topic_distribution := sampler.GenerateTopicDistributionForWord(
 			doc, iter.Word(), iter.Topic(), update_model)
-		new_topic := GetAccumulativeSample(new_topic_distribution);
+		new_topic := GetAccumulativeSampleWithRand(new_topic_distribution, sampler.rng);
 		if new_topic != -1 {
 			// If new_topic != -1 (i.e. GetAccumulativeSample) runs OK, we
 			// update document and model parameters with the new topic.
@@ -77,9 +150,11 @@ func (sampler *Sampler) DocumentLogLikelihood(doc *Document) float64 {
 
 	// Compute P(z|d) for the given document and all topics.
 	prob_topic_given_document := NewDistribution(num_topics)
-	smoothed_doc_length := float64(doc_length) + sampler.topic_prior * float64(num_topics)
+	smoothed_doc_length := float64(doc_length) + sampler.documentTopicPriorSum(doc)
 	for i, v := range doc.topic_histogram {
-		prob_topic_given_document[i] = (float64(v) + sampler.topic_prior) / smoothed_doc_length
+		if doc.IsTopicAllowed(i) {
+			prob_topic_given_document[i] = (float64(v) + sampler.documentTopicPrior(doc, i)) / smoothed_doc_length
+		}
 	}
 
 	// Get global topic occurrences, which will be used to compute P(w|z).
@@ -98,8 +173,8 @@ func (sampler *Sampler) DocumentLogLikelihood(doc *Document) float64 {
 		// Compute P(w|z).
 		for t := 0; t < num_topics; t++ {
 			prob_word_given_topic[t] =
-				(float64(word_topic_histogram[t]) + sampler.word_prior) /
-				(float64(global_topic_histogram[t]) + float64(doc.Length()) * sampler.word_prior)
+				(float64(word_topic_histogram[t]) + sampler.word_prior.Get(iter.Word(), t)) /
+				(float64(global_topic_histogram[t]) + sampler.word_prior.Sum(t, sampler.model))
 		}
 
 		// Compute P(w) = sum_z P(w|z)P(z|d)
@@ -0,0 +1,86 @@
+package lda
+
+import (
+	"fmt"
+	"os"
+	"rand"
+	"strconv"
+	"strings"
+)
+
+// SeedWords maps each seed word to the topics it seeds, for guided LDA.
+type SeedWords map[string][]int
+
+// Load seed words from a file, in which each line has the form
+//
+// topic  seed_word_1  seed_word_2 ...
+//
+// where topic is an integer in [0, num_topics).  A word may seed more
+// than one topic.
+func LoadSeedWords(filename string, num_topics int) (seeds SeedWords, err os.Error) {
+	seeds = make(SeedWords)
+	err = readLines(filename, func(line string) os.Error {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return nil
+		}
+		if len(fields) < 2 {
+			return os.NewError("Invalid line: " + line)
+		}
+		topic, err := strconv.Atoi(fields[0])
+		if err != nil || topic < 0 || topic >= num_topics {
+			return os.NewError(fmt.Sprintf("Invalid topic in [0, %d): %s",
+				num_topics, line))
+		}
+		for _, word := range fields[1:] {
+			seeds.Add(word, topic)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return seeds, nil
+}
+
+// Make word a seed of topic.
+func (seeds SeedWords) Add(word string, topic int) {
+	for _, t := range seeds[word] {
+		if t == topic {
+			return
+		}
+	}
+	seeds[word] = append(seeds[word], topic)
+}
+
+// Returns a word prior of num_topics topics that is base for all words,
+// plus boost for seed words in their topics.
+func (seeds SeedWords) WordPrior(base float64, boost float64, num_topics int) *WordPrior {
+	prior := NewWordPrior(base, num_topics)
+	for word, topics := range seeds {
+		for _, topic := range topics {
+			prior.Boost(word, topic, boost)
+		}
+	}
+	return prior
+}
+
+// Assign every occurrence of a seed word in corpus to one of its seed
+// topics (chosen at random if more than one) allowed in the document.
+// This must be done before the model is created from the corpus.  A nil
+// rng uses the global random source.
+func (seeds SeedWords) InitializeTopics(corpus *Corpus, rng *rand.Rand) {
+	for _, doc := range *corpus {
+		for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+			topics := make([]int, 0)
+			for _, t := range seeds[iter.Word()] {
+				if doc.IsTopicAllowed(t) {
+					topics = append(topics, t)
+				}
+			}
+			if len(topics) > 0 {
+				iter.SetTopic(topics[randIntn(rng, len(topics))])
+			}
+		}
+	}
+}
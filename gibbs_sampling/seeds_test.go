@@ -0,0 +1,76 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+const kSeedsFile = "testdata/seeds.txt"
+
+func TestLoadSeedWords(t *testing.T) {
+	if _, err := LoadSeedWords(kSeedsFile, 1); err == nil {
+		t.Errorf("Expecting an error for a seed topic out of range")
+	}
+	seeds, err := LoadSeedWords(kSeedsFile, 2)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kSeedsFile + " : " + err.String())
+	}
+	if len(seeds) != 3 {
+		t.Errorf("Expecting 3 seed words, but got %d", len(seeds))
+	}
+	for word, topics := range map[string]string{"apple": "[1]", "orange": "[1]", "zebra": "[0]"} {
+		if s := fmt.Sprint(seeds[word]); s != topics {
+			t.Errorf("Expecting seed topics %s of %s, but got %s", topics, word, s)
+		}
+	}
+}
+
+func TestSeededWordPrior(t *testing.T) {
+	seeds, _ := LoadSeedWords(kSeedsFile, 2)
+	prior := seeds.WordPrior(0.01, 1.0, 2)
+	if prior.Get("apple", 1) != 1.01 || prior.Get("apple", 0) != 0.01 ||
+		prior.Get("banana", 1) != 0.01 {
+		t.Errorf("Unexpected word prior: %v", *prior)
+	}
+
+	// Seeding apple in topic 1 makes it relatively more likely there.
+	model := NewModel(2)
+	for k := 0; k < 2; k++ {
+		model.IncrementTopic("apple", k, 1)
+		model.IncrementTopic("kiwi", k, 100)
+	}
+	// The boost of orange, which is not in the model, is not counted.
+	if sum := prior.Sum(1, model); math.Fabs(sum-1.02) > 1e-12 {
+		t.Errorf("Expecting prior sum 1.02 of topic 1, but got %f", sum)
+	}
+	model.IncrementTopic("orange", 0, 1)
+	if sum := prior.Sum(1, model); math.Fabs(sum-2.03) > 1e-12 {
+		t.Errorf("Expecting prior sum 2.03 of topic 1 with orange, but got %f", sum)
+	}
+	model.IncrementTopic("orange", 0, -1)
+	doc, _ := NewDocument("apple banana", 2)
+	symmetric := NewSampler(0.1, 0.01, model, nil)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	sampler.SetWordPrior(prior)
+	d := symmetric.GenerateTopicDistributionForWord(doc, "apple", -1, false)
+	seeded := sampler.GenerateTopicDistributionForWord(doc, "apple", -1, false)
+	if seeded[1]/seeded[0] <= d[1]/d[0] {
+		t.Errorf("Seeding does not favor topic 1: %v vs. %v", seeded, d)
+	}
+	if sampler.DocumentLogLikelihood(doc) == symmetric.DocumentLogLikelihood(doc) {
+		t.Errorf("Likelihood ignores the seeded word prior")
+	}
+}
+
+func TestInitializeSeedTopics(t *testing.T) {
+	seeds, _ := LoadSeedWords(kSeedsFile, 2)
+	corpus, _ := LoadCorpus(kCorpusFile, 2)
+	seeds.InitializeTopics(corpus, nil)
+	const kSeededCorpusGoFmt = "{[apple orange] [0 2] [1 1 1] [0 2 1] [0 2 1] [0 3] [] []}," +
+		"{[jagar zebra] [0 1] [0 0] [1 0] [1 0] [2 0] [] []}"
+	corpus_gofmt := fmt.Sprintf("%v,%v", *(*corpus)[0], *(*corpus)[1])
+	if corpus_gofmt != kSeededCorpusGoFmt {
+		t.Errorf("Expecting: " + kSeededCorpusGoFmt + ", but got: " + corpus_gofmt)
+	}
+}
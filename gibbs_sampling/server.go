@@ -0,0 +1,424 @@
+package lda
+
+import (
+	"fmt"
+	"http"
+	"json"
+	"os"
+	"rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const kDefaultNumTopWords = 10
+
+// The model used by requests that do not name one.
+const kDefaultModelName = "default"
+
+// The number of documents read from a batch request before inferring
+// them in parallel and writing their results.
+const kBatchChunkSize = 256
+
+// Server serves topic inference over HTTP for the read-only models in a
+// ModelRegistry.  It handles:
+//
+// POST /infer        with a JSON body {"Text": "..."} or {"Tokens": [...]},
+//                    returns {"Model": ..., "Version": ..., "TopicNames":
+//                    [...], "Distribution": [...]}, the topic distribution
+//                    of the text inferred by fold-in Gibbs sampling, or
+//                    from its biterms after SetBitermInference, and the
+//                    topic names of the model, if any.
+// POST /infer/batch  with a body of newline-delimited JSON requests, each
+//                    {"Id": ..., "Text": "..."} or {"Id": ..., "Tokens":
+//                    [...]}, returns one JSON line per request, in order,
+//                    with the request's Id and either its Distribution
+//                    or an Error.  The whole batch uses one model version.
+// GET  /topics       with an optional query parameter n, returns the top
+//                    n words of every topic.
+// GET  /models       returns the latest version of every model.
+// GET  /healthz      returns "ok".
+//
+// All requests but /models and /healthz take optional query parameters
+// model (default "default") and version (default the latest) selecting
+// the model.  Requests are handled concurrently; each request (or batch
+// worker) gets its own Sampler and random source, so the shared models
+// are never modified.  If the models were trained with phrases merged,
+// SetPhraseTable makes requests merge the same phrases.
+type Server struct {
+	registry              *ModelRegistry
+	topic_prior           float64
+	word_prior            float64
+	burn_in_iterations    int
+	accumulate_iterations int
+	batch_parallelism     int
+	phrases               *PhraseTable // nil means no phrases
+	biterm_window         int          // negative means fold-in Gibbs sampling
+	mux                   *http.ServeMux
+
+	seed_mutex sync.Mutex // guards seed_rng
+	seed_rng   *rand.Rand
+}
+
+type InferRequest struct {
+	Text   string
+	Tokens []string
+}
+
+type InferResponse struct {
+	Model        string
+	Version      int
+	TopicNames   []string // nil if topics are not named
+	Distribution Distribution
+}
+
+type BatchInferRequest struct {
+	Id     interface{}
+	Text   string
+	Tokens []string
+}
+
+type BatchInferResponse struct {
+	Id           interface{}
+	Model        string
+	Version      int
+	Distribution Distribution
+	Error        string
+}
+
+type TopicWords struct {
+	Topic int
+	Name  string // empty if topics are not named
+	Words []WordCount
+}
+
+type TopicsResponse struct {
+	Model   string
+	Version int
+	Topics  []TopicWords
+}
+
+type ModelInfo struct {
+	Name      string
+	Version   int
+	Filename  string
+	NumTopics int
+	NumWords  int
+}
+
+type ModelsResponse struct {
+	Models []ModelInfo
+}
+
+func NewServer(registry *ModelRegistry, topic_prior float64, word_prior float64,
+	burn_in_iterations int, accumulate_iterations int) *Server {
+	server := &Server{
+		registry:              registry,
+		topic_prior:           topic_prior,
+		word_prior:            word_prior,
+		burn_in_iterations:    burn_in_iterations,
+		accumulate_iterations: accumulate_iterations,
+		batch_parallelism:     1,
+		biterm_window:         -1,
+		mux:                   http.NewServeMux(),
+		seed_rng:              rand.New(rand.NewSource(time.Nanoseconds())),
+	}
+	server.mux.HandleFunc("/infer", func(w http.ResponseWriter, r *http.Request) {
+		server.handleInfer(w, r)
+	})
+	server.mux.HandleFunc("/infer/batch", func(w http.ResponseWriter, r *http.Request) {
+		server.handleBatchInfer(w, r)
+	})
+	server.mux.HandleFunc("/topics", func(w http.ResponseWriter, r *http.Request) {
+		server.handleTopics(w, r)
+	})
+	server.mux.HandleFunc("/models", func(w http.ResponseWriter, r *http.Request) {
+		server.handleModels(w, r)
+	})
+	server.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		server.handleHealthz(w, r)
+	})
+	return server
+}
+
+// Set the number of goroutines inferring the documents of a batch
+// request in parallel.
+func (server *Server) SetBatchParallelism(n int) {
+	if n <= 0 {
+		panic("batch parallelism must be positive")
+	}
+	server.batch_parallelism = n
+}
+
+// Merge phrases of table in the words of requests, as at training.
+func (server *Server) SetPhraseTable(table *PhraseTable) {
+	server.phrases = table
+}
+
+// Infer topics of requests as the biterm topic model does, for models
+// trained by BitermSampler, from the biterms of words at most window
+// positions apart, or any words if window is 0.  Unlike fold-in Gibbs
+// sampling, texts of a single word are accepted.
+func (server *Server) SetBitermInference(window int) {
+	if window < 0 {
+		panic("biterm window must be non-negative")
+	}
+	server.biterm_window = window
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+// Returns a random source for a single request.  Seeds are drawn from a
+// shared, mutex-guarded source so that concurrent requests do not get
+// identical random sequences.
+func (server *Server) newRand() *rand.Rand {
+	server.seed_mutex.Lock()
+	defer server.seed_mutex.Unlock()
+	return rand.New(rand.NewSource(server.seed_rng.Int63()))
+}
+
+func (server *Server) newSampler(model *Model) *Sampler {
+	sampler := NewSampler(server.topic_prior, server.word_prior, model, nil)
+	sampler.SetRand(server.newRand())
+	return sampler
+}
+
+// Infer the topic distribution of text, given as whitespace separated
+// words, or of tokens; exactly one of them must be non-empty.
+func (server *Server) infer(sampler *Sampler, text string, tokens []string) (
+	distribution Distribution, err os.Error) {
+	if len(tokens) > 0 {
+		if len(text) > 0 {
+			return nil, os.NewError("Specify either Text or Tokens, not both")
+		}
+		text = strings.Join(tokens, " ")
+	}
+	if server.biterm_window >= 0 {
+		words := strings.Fields(text)
+		if server.phrases != nil {
+			words = server.phrases.Merge(words)
+		}
+		if len(words) == 0 {
+			return nil, os.NewError("No words in the text")
+		}
+		return InferBitermTopicDistribution(sampler.model, words, server.biterm_window,
+			server.topic_prior, server.word_prior), nil
+	}
+	var doc *Document
+	if server.phrases != nil {
+		doc, err = server.phrases.NewDocument(text, sampler.model.NumTopics())
+	} else {
+		doc, err = NewDocument(text, sampler.model.NumTopics())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sampler.InferTopicDistribution(doc,
+		server.burn_in_iterations, server.accumulate_iterations), nil
+}
+
+// Returns the model selected by the query parameters model and version
+// of r, or writes an error response and returns nil.  Only the URL is
+// read, not a form in the body, which is left to the handler.
+func (server *Server) lookupModel(w http.ResponseWriter, r *http.Request) *RegisteredModel {
+	query, err := http.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		http.Error(w, "Cannot parse query: "+err.String(), http.StatusBadRequest)
+		return nil
+	}
+	name := kDefaultModelName
+	if values := query["model"]; len(values) > 0 && len(values[0]) > 0 {
+		name = values[0]
+	}
+	version := 0
+	if values := query["version"]; len(values) > 0 && len(values[0]) > 0 {
+		s := values[0]
+		if version, err = strconv.Atoi(s); err != nil || version <= 0 {
+			http.Error(w, "version must be a positive integer: "+s, http.StatusBadRequest)
+			return nil
+		}
+	}
+	registered, err := server.registry.GetVersion(name, version)
+	if err != nil {
+		http.Error(w, err.String(), http.StatusNotFound)
+		return nil
+	}
+	return registered
+}
+
+func (server *Server) handleInfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	registered := server.lookupModel(w, r)
+	if registered == nil {
+		return
+	}
+
+	var request InferRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Cannot parse request: "+err.String(), http.StatusBadRequest)
+		return
+	}
+
+	sampler := server.newSampler(registered.Model)
+	distribution, err := server.infer(sampler, request.Text, request.Tokens)
+	if err != nil {
+		http.Error(w, "Cannot infer topics: "+err.String(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, &InferResponse{registered.Name, registered.Version,
+		registered.Model.TopicNames(), distribution})
+}
+
+func (server *Server) handleBatchInfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	registered := server.lookupModel(w, r)
+	if registered == nil {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	encoder := json.NewEncoder(w)
+	written := false
+	for done := false; !done; {
+		requests := make([]BatchInferRequest, 0, kBatchChunkSize)
+		parse_error := ""
+		for len(requests) < kBatchChunkSize {
+			var request BatchInferRequest
+			if err := decoder.Decode(&request); err != nil {
+				if err != os.EOF {
+					parse_error = "Cannot parse request: " + err.String()
+				}
+				done = true
+				break
+			}
+			requests = append(requests, request)
+		}
+
+		if !written {
+			if len(parse_error) > 0 && len(requests) == 0 {
+				http.Error(w, parse_error, http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			written = true
+		}
+		for _, response := range server.inferBatch(registered, requests) {
+			if err := encoder.Encode(&response); err != nil {
+				return // The client has probably gone away.
+			}
+		}
+		if len(parse_error) > 0 {
+			// Results of earlier requests are already written, so
+			// report the error in the stream.
+			encoder.Encode(&BatchInferResponse{Error: parse_error})
+		}
+	}
+}
+
+// Infer the topic distributions of requests in parallel, using
+// server.batch_parallelism goroutines.  Responses are in the order of
+// requests.
+func (server *Server) inferBatch(registered *RegisteredModel,
+	requests []BatchInferRequest) []BatchInferResponse {
+	responses := make([]BatchInferResponse, len(requests))
+	indices := make(chan int, len(requests))
+	for i := range requests {
+		indices <- i
+	}
+	close(indices)
+
+	done := make(chan bool)
+	for w := 0; w < server.batch_parallelism; w++ {
+		go func() {
+			sampler := server.newSampler(registered.Model)
+			for i := range indices {
+				response := &responses[i]
+				response.Id = requests[i].Id
+				response.Model = registered.Name
+				response.Version = registered.Version
+				distribution, err := server.infer(sampler, requests[i].Text, requests[i].Tokens)
+				if err != nil {
+					response.Error = "Cannot infer topics: " + err.String()
+				} else {
+					response.Distribution = distribution
+				}
+			}
+			done <- true
+		}()
+	}
+	for w := 0; w < server.batch_parallelism; w++ {
+		<-done
+	}
+	return responses
+}
+
+func (server *Server) handleTopics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	registered := server.lookupModel(w, r)
+	if registered == nil {
+		return
+	}
+
+	n := kDefaultNumTopWords
+	if s := r.FormValue("n"); len(s) > 0 {
+		var err os.Error
+		if n, err = strconv.Atoi(s); err != nil || n <= 0 {
+			http.Error(w, "n must be a positive integer: "+s, http.StatusBadRequest)
+			return
+		}
+	}
+
+	model := registered.Model
+	response := &TopicsResponse{registered.Name, registered.Version,
+		make([]TopicWords, model.NumTopics())}
+	for k := range response.Topics {
+		name := ""
+		if model.TopicNames() != nil {
+			name = model.TopicNames()[k]
+		}
+		response.Topics[k] = TopicWords{k, name, model.TopWords(k, n)}
+	}
+	writeJSON(w, response)
+}
+
+func (server *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	models := server.registry.List()
+	response := &ModelsResponse{make([]ModelInfo, len(models))}
+	for i, m := range models {
+		response.Models[i] = ModelInfo{m.Name, m.Version, m.Filename,
+			m.Model.NumTopics(), m.Model.NumWords()}
+	}
+	writeJSON(w, response)
+}
+
+func (server *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "ok\n")
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	encoding, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Cannot encode response: "+err.String(),
+			http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(encoding)
+}
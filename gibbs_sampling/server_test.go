@@ -0,0 +1,241 @@
+package lda
+
+import (
+	"bytes"
+	"fmt"
+	"http"
+	"http/httptest"
+	"json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *Server {
+	registry := NewModelRegistry()
+	if _, err := registry.Register("default", kTestModelFile); err != nil {
+		t.Fatalf("Unexpected error in loading: " + kTestModelFile + " due to " + err.String())
+	}
+	return NewServer(registry, 0.1, 0.01, 10, 10)
+}
+
+func serve(server http.Handler, method string, url string, body string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		panic("Cannot create request: " + err.String())
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServerHealthz(t *testing.T) {
+	recorder := serve(newTestServer(t), "GET", "/healthz", "")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "ok\n" {
+		t.Errorf("Unexpected /healthz response: %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServerInfer(t *testing.T) {
+	server := newTestServer(t)
+	for _, body := range []string{
+		`{"Text": "apple orange banana apple"}`,
+		`{"Tokens": ["apple", "orange", "banana", "apple"]}`,
+	} {
+		recorder := serve(server, "POST", "/infer", body)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Unexpected status %d for %s: %s", recorder.Code, body, recorder.Body.String())
+			continue
+		}
+		var response InferResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Errorf("Cannot parse response: " + err.String())
+			continue
+		}
+		if response.Model != "default" || response.Version != 1 {
+			t.Errorf("Unexpected model: %s version %d", response.Model, response.Version)
+		}
+		if len(response.Distribution) != 2 || !response.Distribution.IsValid() {
+			t.Errorf("Invalid distribution: %v", response.Distribution)
+		} else if response.Distribution[1] <= response.Distribution[0] {
+			// All words in the document are topic 1 words in testdata/model.txt.
+			t.Errorf("Expecting topic 1 to dominate, but got %v", response.Distribution)
+		}
+	}
+}
+
+func TestServerInferFormEncodedBody(t *testing.T) {
+	// As sent by curl -d, which must not make the server read the body as
+	// a form before decoding it.
+	request, err := http.NewRequest("POST", "/infer?model=default&version=1",
+		bytes.NewBufferString(`{"Text": "apple orange banana apple"}`))
+	if err != nil {
+		t.Fatalf("Cannot create request: " + err.String())
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	newTestServer(t).ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServerBitermInference(t *testing.T) {
+	server := newTestServer(t)
+	server.SetBitermInference(0)
+	model, _ := LoadModel(kTestModelFile)
+	for _, text := range []string{"apple", "apple orange zebra"} {
+		recorder := serve(server, "POST", "/infer", `{"Text": "`+text+`"}`)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Unexpected status %d for %s: %s", recorder.Code, text, recorder.Body.String())
+			continue
+		}
+		var response InferResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Errorf("Cannot parse response: " + err.String())
+			continue
+		}
+		expected := InferBitermTopicDistribution(model, strings.Fields(text), 0, 0.1, 0.01)
+		if len(response.Distribution) != 2 || math.Fabs(response.Distribution[0]-expected[0]) > 1e-9 {
+			t.Errorf("Expecting distribution %v of %s, but got %v", expected, text, response.Distribution)
+		}
+	}
+}
+
+func TestServerInferBadRequests(t *testing.T) {
+	server := newTestServer(t)
+	if recorder := serve(server, "GET", "/infer", ""); recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expecting %d for GET /infer, but got %d",
+			http.StatusMethodNotAllowed, recorder.Code)
+	}
+	for _, body := range []string{
+		`not json`,
+		`{"Text": "apple"}`,
+		`{"Text": "apple orange", "Tokens": ["apple", "orange"]}`,
+	} {
+		if recorder := serve(server, "POST", "/infer", body); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expecting %d for %s, but got %d", http.StatusBadRequest, body, recorder.Code)
+		}
+	}
+}
+
+func TestServerTopics(t *testing.T) {
+	recorder := serve(newTestServer(t), "GET", "/topics?n=2", "")
+	const kTopicsJSON = `{"Model":"default","Version":1,"Topics":[{"Topic":0,"Name":"","Words":[{"Word":"monky","Count":1},{"Word":"zebra","Count":1}]},` +
+		`{"Topic":1,"Name":"","Words":[{"Word":"apple","Count":1},{"Word":"banana","Count":1}]}]}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != kTopicsJSON {
+		t.Errorf("Expecting: %s\nbut got: %d %s", kTopicsJSON, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServerTopicNames(t *testing.T) {
+	model, _ := LoadModel(kTestModelFile)
+	model.SetTopicNames([]string{"animals", "fruits"})
+	registry := NewModelRegistry()
+	registry.Add("default", model)
+	server := NewServer(registry, 0.1, 0.01, 10, 10)
+
+	recorder := serve(server, "POST", "/infer", `{"Text": "apple orange"}`)
+	var response InferResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Cannot parse response: " + err.String())
+	}
+	if fmt.Sprintf("%v", response.TopicNames) != "[animals fruits]" {
+		t.Errorf("Unexpected topic names: %v", response.TopicNames)
+	}
+
+	recorder = serve(server, "GET", "/topics?n=1", "")
+	const kTopicsJSON = `{"Model":"default","Version":1,"Topics":[` +
+		`{"Topic":0,"Name":"animals","Words":[{"Word":"monky","Count":1}]},` +
+		`{"Topic":1,"Name":"fruits","Words":[{"Word":"apple","Count":1}]}]}`
+	if recorder.Body.String() != kTopicsJSON {
+		t.Errorf("Expecting: %s\nbut got: %s", kTopicsJSON, recorder.Body.String())
+	}
+}
+
+func TestServerUnknownModel(t *testing.T) {
+	server := newTestServer(t)
+	for _, url := range []string{"/topics?model=unknown", "/topics?version=2"} {
+		if recorder := serve(server, "GET", url, ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expecting %d for %s, but got %d", http.StatusNotFound, url, recorder.Code)
+		}
+	}
+}
+
+func TestServerBatchInfer(t *testing.T) {
+	server := newTestServer(t)
+	server.SetBatchParallelism(3)
+	body := bytes.NewBufferString("")
+	const kNumRequests = kBatchChunkSize + 10
+	for i := 0; i < kNumRequests; i++ {
+		if i == 5 {
+			fmt.Fprintf(body, "{\"Id\": %d, \"Text\": \"apple\"}\n", i)
+		} else {
+			fmt.Fprintf(body, "{\"Id\": %d, \"Text\": \"apple zebra orange\"}\n", i)
+		}
+	}
+	recorder := serve(server, "POST", "/infer/batch", body.String())
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	decoder := json.NewDecoder(recorder.Body)
+	for i := 0; i < kNumRequests; i++ {
+		var response BatchInferResponse
+		if err := decoder.Decode(&response); err != nil {
+			t.Fatalf("Cannot parse response %d: %s", i, err.String())
+		}
+		if id, ok := response.Id.(float64); !ok || int(id) != i {
+			t.Errorf("Expecting Id %d, but got %v", i, response.Id)
+		}
+		if i == 5 {
+			if len(response.Error) == 0 {
+				t.Errorf("Expecting an error for a one-word document")
+			}
+		} else if len(response.Error) > 0 || !response.Distribution.IsValid() {
+			t.Errorf("Unexpected response %d: %v", i, response)
+		}
+	}
+	var extra BatchInferResponse
+	if err := decoder.Decode(&extra); err == nil {
+		t.Errorf("Unexpected extra response: %v", extra)
+	}
+}
+
+func TestServerBatchInferMalformed(t *testing.T) {
+	server := newTestServer(t)
+	if recorder := serve(server, "POST", "/infer/batch", "{bad"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expecting %d, but got %d", http.StatusBadRequest, recorder.Code)
+	}
+
+	recorder := serve(server, "POST", "/infer/batch", "{\"Text\": \"apple zebra\"}\n{bad\n")
+	decoder := json.NewDecoder(recorder.Body)
+	var first, second BatchInferResponse
+	if err := decoder.Decode(&first); err != nil || len(first.Error) > 0 {
+		t.Errorf("Unexpected first response: %v", first)
+	}
+	if err := decoder.Decode(&second); err != nil || len(second.Error) == 0 {
+		t.Errorf("Expecting a parse error, but got: %v", second)
+	}
+}
+
+func TestServerConcurrentInfer(t *testing.T) {
+	server := newTestServer(t)
+	const kNumRequests = 20
+	done := make(chan string, kNumRequests)
+	for i := 0; i < kNumRequests; i++ {
+		go func() {
+			recorder := serve(server, "POST", "/infer", `{"Text": "zebra monky apple"}`)
+			if recorder.Code != http.StatusOK {
+				done <- fmt.Sprintf("Unexpected status %d: %s", recorder.Code, recorder.Body.String())
+			} else {
+				done <- ""
+			}
+		}()
+	}
+	for i := 0; i < kNumRequests; i++ {
+		if msg := <-done; len(msg) > 0 {
+			t.Errorf(msg)
+		}
+	}
+}
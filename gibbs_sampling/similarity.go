@@ -0,0 +1,345 @@
+package lda
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The first field of the header line of a document index file.
+const kDocumentIndexKeyword = "#document_index"
+
+// The maximum number of hyperplanes per LSH table, i.e., bits of a bucket
+// key.
+const kMaxLSHBits = 64
+
+// A document found by a DocumentIndex and its distance from the query.
+type Neighbor struct {
+	ID       string
+	Distance float64
+}
+
+// DocumentIndex stores the topic distributions of documents, e.g.,
+// inferred by Sampler.InferTopicDistribution, and finds the nearest ones
+// to a query distribution, by the distance of metric (kHellingerDistance,
+// kJensenShannonDistance or kCosineDistance).
+//
+// An exact index compares the query with every document.  An approximate
+// index also hashes documents into buckets of num_tables tables by
+// random-projection LSH, i.e., by the signs of their projections on
+// num_bits random hyperplanes per table, and compares the query only with
+// documents sharing a bucket with it in some table.  Distributions are
+// hashed as they are for kCosineDistance, and as their square roots,
+// whose cosine similarity is 1 - the squared Hellinger distance, for the
+// others.  More bits make buckets smaller and search faster; more tables
+// find more true neighbors.
+type DocumentIndex struct {
+	metric        string
+	distance      func(p, q Distribution) float64
+	ids           []string
+	distributions []Distribution
+
+	// For approximate indices only.
+	num_tables int
+	num_bits   int
+	seed       int64
+	planes     [][][]float64      // [table][bit][topic]
+	buckets    []map[uint64][]int // [table][key] -> documents
+}
+
+// Create an empty exact index.
+func NewDocumentIndex(metric string) (*DocumentIndex, os.Error) {
+	distance, _, err := topicDistance(metric)
+	if err != nil {
+		return nil, err
+	}
+	return &DocumentIndex{metric: metric, distance: distance,
+		ids: make([]string, 0), distributions: make([]Distribution, 0)}, nil
+}
+
+// Create an empty approximate index of num_tables tables of num_bits
+// hyperplanes, drawn from the random source of seed.
+func NewLSHDocumentIndex(metric string, num_tables int, num_bits int, seed int64) (
+	*DocumentIndex, os.Error) {
+	if num_tables <= 0 {
+		return nil, os.NewError("The number of LSH tables must be positive")
+	}
+	if num_bits <= 0 || num_bits > kMaxLSHBits {
+		return nil, os.NewError(fmt.Sprintf("The number of LSH bits must be in [1, %d]",
+			kMaxLSHBits))
+	}
+	index, err := NewDocumentIndex(metric)
+	if err != nil {
+		return nil, err
+	}
+	index.num_tables, index.num_bits, index.seed = num_tables, num_bits, seed
+	index.buckets = make([]map[uint64][]int, num_tables)
+	for t := range index.buckets {
+		index.buckets[t] = make(map[uint64][]int)
+	}
+	return index, nil
+}
+
+func (index *DocumentIndex) Size() int {
+	return len(index.ids)
+}
+
+// Returns the number of topics of distributions in the index, or 0 if it
+// is empty.
+func (index *DocumentIndex) NumTopics() int {
+	if len(index.distributions) == 0 {
+		return 0
+	}
+	return len(index.distributions[0])
+}
+
+// Returns an error if query has a different number of topics from the
+// distributions in the index.
+func (index *DocumentIndex) checkQuery(query Distribution) os.Error {
+	if index.Size() > 0 && len(query) != index.NumTopics() {
+		return os.NewError(fmt.Sprintf("Query has %d topics; index has %d topics",
+			len(query), index.NumTopics()))
+	}
+	return nil
+}
+
+// Returns whether the index is approximate.
+func (index *DocumentIndex) IsApproximate() bool {
+	return index.num_tables > 0
+}
+
+// Returns the bucket keys of distribution in all tables.  The hyperplanes
+// are drawn at the first call, when the number of topics is known.
+func (index *DocumentIndex) hash(distribution Distribution) []uint64 {
+	if index.planes == nil {
+		rng := rand.New(rand.NewSource(index.seed))
+		index.planes = make([][][]float64, index.num_tables)
+		for t := range index.planes {
+			index.planes[t] = make([][]float64, index.num_bits)
+			for b := range index.planes[t] {
+				index.planes[t][b] = make([]float64, len(distribution))
+				for k := range index.planes[t][b] {
+					index.planes[t][b][k] = rng.NormFloat64()
+				}
+			}
+		}
+	}
+	keys := make([]uint64, index.num_tables)
+	for t, planes := range index.planes {
+		for b, plane := range planes {
+			projection := 0.0
+			for k, p := range distribution {
+				if index.metric != kCosineDistance {
+					p = math.Sqrt(p)
+				}
+				projection += p * plane[k]
+			}
+			if projection >= 0 {
+				keys[t] |= 1 << uint(b)
+			}
+		}
+	}
+	return keys
+}
+
+// Add the topic distribution of a document identified by id.  All
+// distributions must be over the same number of topics.
+func (index *DocumentIndex) Add(id string, distribution Distribution) {
+	if len(index.distributions) > 0 && len(distribution) != len(index.distributions[0]) {
+		panic(fmt.Sprintf("distribution has (%d) topics; index has (%d) topics.",
+			len(distribution), len(index.distributions[0])))
+	}
+	d := len(index.ids)
+	index.ids = append(index.ids, id)
+	index.distributions = append(index.distributions, distribution)
+	if index.IsApproximate() {
+		for t, key := range index.hash(distribution) {
+			index.buckets[t][key] = append(index.buckets[t][key], d)
+		}
+	}
+}
+
+// Returns the num_neighbors documents nearest to query, in ascending order
+// of distance, among candidates, or among all documents if candidates is
+// nil.  query must have the number of topics of the index.
+func (index *DocumentIndex) nearest(query Distribution, num_neighbors int,
+	candidates []int) []Neighbor {
+	// neighbors is kept sorted, and at most num_neighbors long.
+	neighbors := make([]Neighbor, 0, num_neighbors+1)
+	consider := func(d int) {
+		n := Neighbor{index.ids[d], index.distance(query, index.distributions[d])}
+		// Ties are broken by IDs.
+		i := sort.Search(len(neighbors), func(i int) bool {
+			return n.Distance < neighbors[i].Distance ||
+				(n.Distance == neighbors[i].Distance && n.ID < neighbors[i].ID)
+		})
+		if i >= num_neighbors {
+			return
+		}
+		neighbors = append(neighbors, n)
+		copy(neighbors[i+1:], neighbors[i:])
+		neighbors[i] = n
+		if len(neighbors) > num_neighbors {
+			neighbors = neighbors[0:num_neighbors]
+		}
+	}
+	if candidates == nil {
+		for d := range index.distributions {
+			consider(d)
+		}
+	} else {
+		for _, d := range candidates {
+			consider(d)
+		}
+	}
+	return neighbors
+}
+
+// Returns the num_neighbors documents nearest to query by comparing it
+// with every document, in ascending order of distance, or an error if
+// query has a different number of topics from the index.
+func (index *DocumentIndex) ExactNearest(query Distribution, num_neighbors int) (
+	[]Neighbor, os.Error) {
+	if err := index.checkQuery(query); err != nil {
+		return nil, err
+	}
+	return index.nearest(query, num_neighbors, nil), nil
+}
+
+// Returns the num_neighbors documents nearest to query, in ascending order
+// of distance: approximately, among documents sharing a bucket with query,
+// for an approximate index, or exactly otherwise.  If fewer than
+// num_neighbors documents share buckets with query, the search is exact.
+// Returns an error if query has a different number of topics from the
+// index.
+func (index *DocumentIndex) Nearest(query Distribution, num_neighbors int) (
+	[]Neighbor, os.Error) {
+	if !index.IsApproximate() || index.Size() == 0 {
+		return index.ExactNearest(query, num_neighbors)
+	}
+	if err := index.checkQuery(query); err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool)
+	candidates := make([]int, 0)
+	for t, key := range index.hash(query) {
+		for _, d := range index.buckets[t][key] {
+			if !seen[d] {
+				seen[d] = true
+				candidates = append(candidates, d)
+			}
+		}
+	}
+	if len(candidates) < num_neighbors {
+		return index.nearest(query, num_neighbors, nil), nil
+	}
+	return index.nearest(query, num_neighbors, candidates), nil
+}
+
+// Load a corpus of documents with IDs, one per line in the format of
+//
+// id<TAB>text
+//
+// with phrases merged by table if not nil.
+func LoadCorpusWithIDs(filename string, num_topics int, table *PhraseTable) (
+	corpus *Corpus, ids []string, err os.Error) {
+	corpus = NewCorpus()
+	ids = make([]string, 0)
+	err = readLines(filename, func(line string) os.Error {
+		id, text, err := splitMetadata(line)
+		if err != nil {
+			return err
+		}
+		id = strings.TrimSpace(id)
+		if len(id) == 0 || len(strings.Fields(id)) != 1 {
+			return os.NewError("Invalid document ID: " + line)
+		}
+		doc, err := newPhrasedDocument(text, num_topics, table)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return corpus, ids, nil
+}
+
+// Load an index saved by SaveDocumentIndex.
+func LoadDocumentIndex(filename string) (index *DocumentIndex, err os.Error) {
+	err = readLines(filename, func(line string) os.Error {
+		fields := strings.Fields(line)
+		if index == nil {
+			if len(fields) != 5 || fields[0] != kDocumentIndexKeyword {
+				return os.NewError("Invalid document index header: " + line)
+			}
+			num_tables, err1 := strconv.Atoi(fields[2])
+			num_bits, err2 := strconv.Atoi(fields[3])
+			seed, err3 := strconv.Atoi64(fields[4])
+			if err1 != nil || err2 != nil || err3 != nil {
+				return os.NewError("Invalid document index header: " + line)
+			}
+			var err os.Error
+			if num_tables == 0 {
+				index, err = NewDocumentIndex(fields[1])
+			} else {
+				index, err = NewLSHDocumentIndex(fields[1], num_tables, num_bits, seed)
+			}
+			return err
+		}
+		if len(fields) < 2 {
+			return os.NewError("Invalid document: " + line)
+		}
+		distribution := NewDistribution(len(fields) - 1)
+		for k := range distribution {
+			p, err := strconv.Atof64(fields[k+1])
+			if err != nil {
+				return os.NewError("Invalid probability: " + line)
+			}
+			distribution[k] = p
+		}
+		if index.Size() > 0 && len(distribution) != len(index.distributions[0]) {
+			return os.NewError("Inconsistent number of topics: " + line)
+		}
+		index.Add(fields[0], distribution)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		return nil, os.NewError("Empty document index file: " + filename)
+	}
+	return index, nil
+}
+
+// Save the index in the format of
+//
+// #document_index metric num_tables num_bits seed
+// id p_0 p_1 ... p_{K-1}
+// ...
+//
+// where num_tables is 0 for an exact index.  IDs must contain no
+// whitespaces.  Buckets are not saved, but rebuilt by LoadDocumentIndex.
+func (index *DocumentIndex) SaveDocumentIndex(filename string) os.Error {
+	return writeFile(filename, func(writer *bufio.Writer) {
+		fmt.Fprintf(writer, "%s %s %d %d %d\n", kDocumentIndexKeyword, index.metric,
+			index.num_tables, index.num_bits, index.seed)
+		for d, id := range index.ids {
+			fmt.Fprint(writer, id)
+			for _, p := range index.distributions[d] {
+				fmt.Fprintf(writer, " %g", p)
+			}
+			fmt.Fprint(writer, "\n")
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package lda
+
+import (
+	"fmt"
+	"rand"
+	"testing"
+)
+
+const kTmpDocumentIndexFile = "/tmp/tmp_document_index.txt"
+const kCorpusWithIDsFile = "testdata/corpus_with_ids.txt"
+
+// Returns a random topic distribution peaked on a random topic.
+func randomTopicDistribution(rng *rand.Rand, num_topics int) Distribution {
+	d := NewDistribution(num_topics)
+	sum := 0.0
+	for k := range d {
+		d[k] = rng.Float64()
+		sum += d[k]
+	}
+	peak := rng.Intn(num_topics)
+	d[peak] += 5
+	sum += 5
+	for k := range d {
+		d[k] /= sum
+	}
+	return d
+}
+
+func TestExactDocumentIndex(t *testing.T) {
+	index, err := NewDocumentIndex(kHellingerDistance)
+	if err != nil {
+		t.Fatalf("Cannot create index: " + err.String())
+	}
+	index.Add("sports", Distribution{0.8, 0.1, 0.1})
+	index.Add("finance", Distribution{0.1, 0.8, 0.1})
+	index.Add("sports_finance", Distribution{0.45, 0.45, 0.1})
+	neighbors, err := index.Nearest(Distribution{0.7, 0.2, 0.1}, 2)
+	if err != nil || len(neighbors) != 2 || neighbors[0].ID != "sports" || neighbors[1].ID != "sports_finance" ||
+		neighbors[0].Distance > neighbors[1].Distance {
+		t.Errorf("Unexpected neighbors: %v", neighbors)
+	}
+	if n, _ := index.Nearest(Distribution{0.1, 0.8, 0.1}, 5); len(n) != 3 || n[0].Distance != 0 {
+		t.Errorf("Expecting all 3 documents, the first identical, but got: %v", n)
+	}
+
+	if _, err := index.Nearest(Distribution{0.5, 0.5}, 2); err == nil {
+		t.Errorf("Expecting an error on a query of a different number of topics")
+	}
+
+	if _, err := NewDocumentIndex("euclidean"); err == nil {
+		t.Errorf("Expecting an error on an unknown metric")
+	}
+	if _, err := NewLSHDocumentIndex(kCosineDistance, 4, 65, 1); err == nil {
+		t.Errorf("Expecting an error on too many LSH bits")
+	}
+}
+
+func TestLSHDocumentIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, metric := range []string{kHellingerDistance, kJensenShannonDistance, kCosineDistance} {
+		index, err := NewLSHDocumentIndex(metric, 8, 6, 1)
+		if err != nil {
+			t.Fatalf("Cannot create index: " + err.String())
+		}
+		for d := 0; d < 1000; d++ {
+			index.Add(fmt.Sprint(d), randomTopicDistribution(rng, 10))
+		}
+
+		found := 0
+		for q := 0; q < 20; q++ {
+			query := randomTopicDistribution(rng, 10)
+			exact, _ := index.ExactNearest(query, 5)
+			approximate, _ := index.Nearest(query, 5)
+			if len(approximate) != 5 {
+				t.Fatalf("Expecting 5 neighbors, but got: %v", approximate)
+			}
+			for _, n := range approximate {
+				for _, e := range exact {
+					if n.ID == e.ID {
+						found++
+					}
+				}
+			}
+		}
+		// The recall of the true 5 nearest neighbors.
+		if recall := float64(found) / 100; recall < 0.5 {
+			t.Errorf("Expecting a recall of %s above 0.5, but got: %f", metric, recall)
+		}
+		if _, err := index.Nearest(randomTopicDistribution(rng, 5), 5); err == nil {
+			t.Errorf("Expecting an error on a query of a different number of topics")
+		}
+	}
+}
+
+func TestSaveAndLoadDocumentIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	index, _ := NewLSHDocumentIndex(kJensenShannonDistance, 4, 8, 7)
+	for d := 0; d < 100; d++ {
+		index.Add(fmt.Sprintf("doc%d", d), randomTopicDistribution(rng, 5))
+	}
+	if err := index.SaveDocumentIndex(kTmpDocumentIndexFile); err != nil {
+		t.Fatalf("Cannot save index: " + err.String())
+	}
+	loaded, err := LoadDocumentIndex(kTmpDocumentIndexFile)
+	if err != nil {
+		t.Fatalf("Cannot load index: " + err.String())
+	}
+	if loaded.Size() != 100 || !loaded.IsApproximate() {
+		t.Fatalf("Unexpected loaded index of %d documents", loaded.Size())
+	}
+	for q := 0; q < 10; q++ {
+		query := randomTopicDistribution(rng, 5)
+		expected, _ := index.Nearest(query, 3)
+		if got, _ := loaded.Nearest(query, 3); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Expecting: %v, but got: %v", expected, got)
+		}
+	}
+}
+
+func TestLoadCorpusWithIDs(t *testing.T) {
+	corpus, ids, err := LoadCorpusWithIDs(kCorpusWithIDsFile, 2, nil)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kCorpusWithIDsFile + " : " + err.String())
+	}
+	if len(*corpus) != 2 || fmt.Sprint(ids) != "[nyc zoo]" {
+		t.Errorf("Unexpected corpus %v with IDs %v", *corpus, ids)
+	}
+
+	table := NewPhraseTable()
+	table.Add([]string{"new", "york"}, 5)
+	corpus, _, err = LoadCorpusWithIDs(kCorpusWithIDsFile, 2, table)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kCorpusWithIDsFile + " : " + err.String())
+	}
+	if s := fmt.Sprint((*corpus)[0].unique_words); s != "[dog hot new_york]" {
+		t.Errorf("Expecting words with phrases merged, but got %s", s)
+	}
+}
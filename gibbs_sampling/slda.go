@@ -0,0 +1,409 @@
+package lda
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The types of responses of supervised LDA.
+const (
+	kGaussianResponse = "gaussian" // A real number, e.g., resolution time.
+	kBinaryResponse   = "binary"   // A class label, 0 or 1.
+)
+
+// The minimum variance of Gaussian responses, so that perfectly fit
+// training responses do not freeze topic assignments.
+const kMinResponseVariance = 1e-4
+
+// The number of Newton-Raphson iterations fitting binary response weights.
+const kBinaryResponseFitIterations = 20
+
+// ResponseModel predicts the response of a document from the empirical
+// frequencies of its topics, zbar = topic_histogram / Length(), by a
+// linear regression, y ~ N(weights . zbar, variance), for Gaussian
+// responses, or a logistic regression, P(y = 1) = sigmoid(weights .
+// zbar), for binary responses.  As topic frequencies sum to 1, no
+// intercept is needed.
+type ResponseModel struct {
+	response_type string
+	weights       []float64
+	variance      float64 // Only for Gaussian responses.
+}
+
+// Create a ResponseModel of num_topics topics and response_type
+// "gaussian" or "binary", with zero weights.
+func NewResponseModel(response_type string, num_topics int) (*ResponseModel, os.Error) {
+	if response_type != kGaussianResponse && response_type != kBinaryResponse {
+		return nil, os.NewError("Unknown response type: " + response_type)
+	}
+	return &ResponseModel{response_type, make([]float64, num_topics), 1}, nil
+}
+
+func (r *ResponseModel) NumTopics() int {
+	return len(r.weights)
+}
+
+func (r *ResponseModel) Weights() []float64 {
+	return r.weights
+}
+
+// Returns an error if responses contain a value that cannot be a
+// response of r's type, i.e., a binary response other than 0 or 1.
+func (r *ResponseModel) CheckResponses(responses []float64) os.Error {
+	if r.response_type != kBinaryResponse {
+		return nil
+	}
+	for d, y := range responses {
+		if y != 0 && y != 1 {
+			return os.NewError(fmt.Sprintf(
+				"Binary response of document %d is %g, but not 0 or 1", d, y))
+		}
+	}
+	return nil
+}
+
+// Returns the predicted response given topic frequencies zbar: the mean
+// of Gaussian responses, or the probability of binary response 1.
+func (r *ResponseModel) Predict(zbar Distribution) float64 {
+	y := 0.0
+	for k, z := range zbar {
+		y += r.weights[k] * z
+	}
+	if r.response_type == kBinaryResponse {
+		y = sigmoid(y)
+	}
+	return y
+}
+
+// Returns the squared error of a Gaussian prediction, or 1 if a binary
+// prediction is wrong and 0 otherwise.
+func (r *ResponseModel) PredictionError(y float64, prediction float64) float64 {
+	if r.response_type == kBinaryResponse {
+		if (prediction >= 0.5) != (y >= 0.5) {
+			return 1
+		}
+		return 0
+	}
+	return (y - prediction) * (y - prediction)
+}
+
+// Returns the log-likelihood of response y given the linear predictor
+// weights . zbar, up to a constant.
+func (r *ResponseModel) logLikelihood(y float64, prediction float64) float64 {
+	if r.response_type == kBinaryResponse {
+		p := sigmoid(prediction)
+		return y*math.Log(p+1e-300) + (1-y)*math.Log(1-p+1e-300)
+	}
+	return -(y - prediction) * (y - prediction) / (2 * r.variance)
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Fit the weights (and variance) to responses given topic frequencies
+// zbars, with an L2 regularizer on weights.
+func (r *ResponseModel) fit(zbars []Distribution, responses []float64, regularizer float64) {
+	num_topics := len(r.weights)
+	if r.response_type == kGaussianResponse {
+		// Ridge regression: (Z'Z + regularizer I) weights = Z'y.
+		a := make([][]float64, num_topics)
+		b := make([]float64, num_topics)
+		for i := range a {
+			a[i] = make([]float64, num_topics)
+			a[i][i] = regularizer
+		}
+		for d, zbar := range zbars {
+			for i, zi := range zbar {
+				b[i] += zi * responses[d]
+				for j, zj := range zbar {
+					a[i][j] += zi * zj
+				}
+			}
+		}
+		if weights := solveLinearSystem(a, b); weights != nil {
+			r.weights = weights
+		}
+		sum_squares := 0.0
+		for d, zbar := range zbars {
+			residual := responses[d] - r.Predict(zbar)
+			sum_squares += residual * residual
+		}
+		r.variance = math.Fmax(sum_squares/float64(len(zbars)), kMinResponseVariance)
+		return
+	}
+
+	// Regularized logistic regression by Newton-Raphson.
+	for iter := 0; iter < kBinaryResponseFitIterations; iter++ {
+		hessian := make([][]float64, num_topics)
+		gradient := make([]float64, num_topics)
+		for i := range hessian {
+			hessian[i] = make([]float64, num_topics)
+			hessian[i][i] = regularizer
+			gradient[i] = -regularizer * r.weights[i]
+		}
+		for d, zbar := range zbars {
+			p := r.Predict(zbar)
+			for i, zi := range zbar {
+				gradient[i] += (responses[d] - p) * zi
+				for j, zj := range zbar {
+					hessian[i][j] += p * (1 - p) * zi * zj
+				}
+			}
+		}
+		step := solveLinearSystem(hessian, gradient)
+		if step == nil {
+			return
+		}
+		change := 0.0
+		for k := range r.weights {
+			r.weights[k] += step[k]
+			change += math.Fabs(step[k])
+		}
+		if change < 1e-8 {
+			return
+		}
+	}
+}
+
+// Solve a x = b by Gaussian elimination with partial pivoting, modifying
+// a and b.  Returns nil if a is singular.
+func solveLinearSystem(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Fabs(a[row][col]) > math.Fabs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Fabs(a[pivot][col]) < 1e-12 {
+			return nil
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for j := col; j < n; j++ {
+				a[row][j] -= f * a[col][j]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		s := b[row]
+		for j := row + 1; j < n; j++ {
+			s -= a[row][j] * x[j]
+		}
+		x[row] = s / a[row][row]
+	}
+	return x
+}
+
+// Load a response model from a text file in the format of
+//
+// type  gaussian|binary
+// variance  v
+// weights  w_0  w_1 ...
+func LoadResponseModel(filename string) (r *ResponseModel, err os.Error) {
+	fields := make(map[string][]string)
+	err = readLines(filename, func(line string) os.Error {
+		if f := strings.Fields(line); len(f) >= 2 {
+			fields[f[0]] = f[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(fields["type"]) != 1 || len(fields["variance"]) != 1 || len(fields["weights"]) < 2 {
+		return nil, os.NewError("Invalid response model file: " + filename)
+	}
+	if r, err = NewResponseModel(fields["type"][0], len(fields["weights"])); err != nil {
+		return nil, err
+	}
+	if r.variance, err = strconv.Atof64(fields["variance"][0]); err != nil {
+		return nil, os.NewError("Invalid variance in: " + filename)
+	}
+	for k, w := range fields["weights"] {
+		if r.weights[k], err = strconv.Atof64(w); err != nil {
+			return nil, os.NewError("Invalid weights in: " + filename)
+		}
+	}
+	return r, nil
+}
+
+func (r *ResponseModel) SaveResponseModel(filename string) os.Error {
+	return writeFile(filename, func(writer *bufio.Writer) {
+		fmt.Fprintf(writer, "type %s\n", r.response_type)
+		fmt.Fprintf(writer, "variance %g\n", r.variance)
+		fmt.Fprintf(writer, "weights")
+		for _, w := range r.weights {
+			fmt.Fprintf(writer, " %g", w)
+		}
+		fmt.Fprintf(writer, "\n")
+	})
+}
+
+// Load a corpus for supervised LDA, where each line has the form
+//
+// response<TAB>text
+//
+// and response is a real number.  Returns the corpus and the responses of
+// its documents.
+func LoadResponseCorpus(filename string, num_topics int) (
+	corpus *Corpus, responses []float64, err os.Error) {
+	corpus = NewCorpus()
+	responses = make([]float64, 0)
+	err = readLines(filename, func(line string) os.Error {
+		metadata, text, err := splitMetadata(line)
+		if err != nil {
+			return err
+		}
+		y, err := strconv.Atof64(strings.TrimSpace(metadata))
+		if err != nil {
+			return os.NewError("Invalid response: " + line)
+		}
+		doc, err := NewDocument(text, num_topics)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+		responses = append(responses, y)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return corpus, responses, nil
+}
+
+// Returns the empirical topic frequencies of doc, topic_histogram / Length().
+func topicFrequencies(doc *Document) Distribution {
+	zbar := NewDistribution(len(doc.topic_histogram))
+	for k, c := range doc.topic_histogram {
+		zbar[k] = float64(c) / float64(doc.Length())
+	}
+	return zbar
+}
+
+// SupervisedSampler trains supervised LDA (sLDA; Blei and McAuliffe,
+// 2007) by collapsed Gibbs sampling, where every document has a response
+// predicted by a ResponseModel from its topic frequencies.  Topics of
+// words are drawn from their LDA posterior times the likelihood of the
+// document's response, so that topics become predictive of responses.
+// The response model is refit after every sweep.
+type SupervisedSampler struct {
+	sampler     *Sampler
+	response    *ResponseModel
+	corpus      *Corpus
+	responses   []float64
+	regularizer float64 // The L2 regularizer of response weights.
+}
+
+// Create a SupervisedSampler sampling corpus, whose documents have
+// responses, with sampler and its model.  response is initially fit to
+// the current topic assignments.
+func NewSupervisedSampler(sampler *Sampler, response *ResponseModel,
+	corpus *Corpus, responses []float64, regularizer float64) *SupervisedSampler {
+	if len(*corpus) != len(responses) {
+		panic(fmt.Sprintf("%d documents, but %d responses", len(*corpus), len(responses)))
+	}
+	if response.NumTopics() != sampler.model.NumTopics() {
+		panic(fmt.Sprintf("response has (%d) topics; model has (%d) topics.",
+			response.NumTopics(), sampler.model.NumTopics()))
+	}
+	s := &SupervisedSampler{sampler, response, corpus, responses, regularizer}
+	s.FitResponse()
+	return s
+}
+
+func (s *SupervisedSampler) Response() *ResponseModel {
+	return s.response
+}
+
+// Refit the response model to the current topic frequencies of the
+// corpus.
+func (s *SupervisedSampler) FitResponse() {
+	zbars := make([]Distribution, len(*s.corpus))
+	for d, doc := range *s.corpus {
+		zbars[d] = topicFrequencies(doc)
+	}
+	s.response.fit(zbars, s.responses, s.regularizer)
+}
+
+func (s *SupervisedSampler) documentGibbsSampling(doc *Document, y float64) {
+	length := float64(doc.Length())
+	log_factors := NewDistribution(len(doc.topic_histogram))
+	for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+		distribution := s.sampler.GenerateTopicDistributionForWord(
+			doc, iter.Word(), iter.Topic(), true)
+
+		// The linear predictor with the word moved to topic k is
+		// (base + weights[k]) / length.
+		base := -s.response.weights[iter.Topic()]
+		for k, c := range doc.topic_histogram {
+			base += s.response.weights[k] * float64(c)
+		}
+		max_log_factor := math.Inf(-1)
+		for k := range distribution {
+			log_factors[k] = s.response.logLikelihood(y,
+				(base+s.response.weights[k])/length)
+			max_log_factor = math.Fmax(max_log_factor, log_factors[k])
+		}
+		for k := range distribution {
+			distribution[k] *= math.Exp(log_factors[k] - max_log_factor)
+		}
+
+		new_topic := GetAccumulativeSampleWithRand(distribution, s.sampler.rng)
+		if new_topic == -1 {
+			panic(fmt.Sprintf("Cannot sample from: %v", distribution))
+		}
+		s.sampler.model.ReassignTopic(iter.Word(), iter.Topic(), new_topic)
+		iter.SetTopic(new_topic)
+	}
+}
+
+// Run a Gibbs sampling sweep over the corpus, accumulate the model into
+// the sampler's accum_model unless burn_in, and refit the response model.
+func (s *SupervisedSampler) CorpusGibbsSampling(burn_in bool) {
+	for d, doc := range *s.corpus {
+		s.documentGibbsSampling(doc, s.responses[d])
+	}
+	if s.sampler.accum_model != nil && !burn_in {
+		s.sampler.accum_model.AccumulateModel(s.sampler.model)
+	}
+	s.FitResponse()
+}
+
+// Returns the mean squared error of predicted Gaussian responses, or the
+// error rate of predicted binary responses, on the training corpus.
+func (s *SupervisedSampler) TrainingError() float64 {
+	total := 0.0
+	for d, doc := range *s.corpus {
+		total += s.response.PredictionError(s.responses[d],
+			s.response.Predict(topicFrequencies(doc)))
+	}
+	return total / float64(len(*s.corpus))
+}
+
+// Predict the response of doc, by folding it into the sampler's model as
+// Sampler.InferTopicDistribution does, and applying r to its average
+// topic frequencies.
+func (r *ResponseModel) PredictDocument(sampler *Sampler, doc *Document,
+	burn_in_iterations int, accumulate_iterations int) float64 {
+	if accumulate_iterations <= 0 {
+		panic("accumulate_iterations must be positive")
+	}
+	zbar := sampler.averageTopicHistogram(doc, burn_in_iterations, accumulate_iterations)
+	for k := range zbar {
+		zbar[k] /= float64(doc.Length())
+	}
+	return r.Predict(zbar)
+}
@@ -0,0 +1,102 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"rand"
+	"testing"
+)
+
+const kResponseCorpusFile = "testdata/response_corpus.txt"
+const kTmpResponseModelFile = "/tmp/response_model.txt"
+
+func TestSolveLinearSystem(t *testing.T) {
+	a := [][]float64{{0, 2}, {1, 1}}
+	b := []float64{4, 3}
+	if x := solveLinearSystem(a, b); fmt.Sprintf("%v", x) != "[1 2]" {
+		t.Errorf("Expecting [1 2], but got %v", x)
+	}
+	if x := solveLinearSystem([][]float64{{1, 1}, {1, 1}}, []float64{1, 1}); x != nil {
+		t.Errorf("Expecting nil for a singular system, but got %v", x)
+	}
+}
+
+func TestLoadResponseCorpus(t *testing.T) {
+	corpus, responses, err := LoadResponseCorpus(kResponseCorpusFile, 2)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kResponseCorpusFile + " : " + err.String())
+	}
+	if len(*corpus) != 2 || fmt.Sprintf("%v", responses) != "[1.5 -2]" {
+		t.Errorf("Unexpected corpus %v with responses %v", *corpus, responses)
+	}
+}
+
+func TestCheckResponses(t *testing.T) {
+	gaussian, _ := NewResponseModel("gaussian", 2)
+	if err := gaussian.CheckResponses([]float64{1.5, -2}); err != nil {
+		t.Errorf("Unexpected error of gaussian responses: " + err.String())
+	}
+	binary, _ := NewResponseModel("binary", 2)
+	if err := binary.CheckResponses([]float64{1, 0, 1}); err != nil {
+		t.Errorf("Unexpected error of binary responses: " + err.String())
+	}
+	if err := binary.CheckResponses([]float64{1, 0.5}); err == nil {
+		t.Errorf("Expecting an error for binary response 0.5")
+	}
+}
+
+func TestSaveLoadResponseModel(t *testing.T) {
+	if _, err := NewResponseModel("poisson", 2); err == nil {
+		t.Errorf("Expecting an error for an unknown response type")
+	}
+	r, _ := NewResponseModel("gaussian", 3)
+	r.weights = []float64{1.5, -2, 0.25}
+	r.variance = 0.5
+	if err := r.SaveResponseModel(kTmpResponseModelFile); err != nil {
+		t.Fatalf("Cannot write to: " + kTmpResponseModelFile + " due to " + err.String())
+	}
+	loaded, err := LoadResponseModel(kTmpResponseModelFile)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kTmpResponseModelFile + " : " + err.String())
+	}
+	if fmt.Sprintf("%v", *loaded) != fmt.Sprintf("%v", *r) {
+		t.Errorf("Expecting %v, but got %v", *r, *loaded)
+	}
+}
+
+// Trains sLDA on fruit and animal documents, whose responses are 1 and 0,
+// and checks predictions of new documents.
+func checkSupervisedSampler(t *testing.T, response_type string) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 40, 2)
+	responses := make([]float64, len(*corpus))
+	for d, doc := range *corpus {
+		doc.RandomizeTopics(rng)
+		responses[d] = float64(1 - d%2)
+	}
+	model := CreateModel(2, corpus)
+	sampler := NewSampler(0.1, 0.01, model, nil)
+	sampler.SetRand(rng)
+	response, _ := NewResponseModel(response_type, 2)
+	supervised := NewSupervisedSampler(sampler, response, corpus, responses, 0.01)
+	for iter := 0; iter < 30; iter++ {
+		supervised.CorpusGibbsSampling(true)
+	}
+
+	checkTwoTopicModel(t, model)
+	if e := supervised.TrainingError(); e > 0.05 {
+		t.Errorf("Training error of %s responses is too large: %f", response_type, e)
+	}
+	for i, text := range []string{"apple orange banana", "zebra lion tiger"} {
+		doc, _ := NewDocument(text, 2)
+		y := response.PredictDocument(sampler, doc, 10, 10)
+		if math.Fabs(y-float64(1-i)) > 0.25 {
+			t.Errorf("Expecting %s response %d of %s, but got %f", response_type, 1-i, text, y)
+		}
+	}
+}
+
+func TestSupervisedSampler(t *testing.T) {
+	checkSupervisedSampler(t, "gaussian")
+	checkSupervisedSampler(t, "binary")
+}
@@ -0,0 +1,102 @@
+package lda
+
+import (
+	"math"
+	"rand"
+)
+
+// The digamma function, i.e., the derivative of log Gamma(x), for x > 0.
+// It uses the recurrence digamma(x) = digamma(x+1) - 1/x to shift x to
+// at least 6, where the asymptotic expansion is accurate.
+func digamma(x float64) float64 {
+	result := 0.0
+	for ; x < 6; x++ {
+		result -= 1 / x
+	}
+	f := 1 / (x * x)
+	result += math.Log(x) - 0.5/x -
+		f*(1.0/12-f*(1.0/120-f*(1.0/252-f*(1.0/240-f/132))))
+	return result
+}
+
+// Draw from Gamma(shape, 1) using the method of Marsaglia and Tsang.  A
+// nil rng uses the global random source.
+func randGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return randGamma(rng, shape+1) * math.Pow(randFloat64(rng), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	v := 0.0
+	for accepted := false; !accepted; {
+		x := randNormFloat64(rng)
+		v = 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := randFloat64(rng)
+		accepted = u < 1-0.0331*x*x*x*x ||
+			math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v))
+	}
+	return d * v
+}
+
+// Returns E[log theta_k] for theta ~ Dirichlet(alpha), i.e.,
+// digamma(alpha_k) - digamma(sum of alpha).
+func dirichletExpectation(alpha []float64) []float64 {
+	sum := 0.0
+	for _, a := range alpha {
+		sum += a
+	}
+	digamma_sum := digamma(sum)
+	expectation := make([]float64, len(alpha))
+	for k, a := range alpha {
+		expectation[k] = digamma(a) - digamma_sum
+	}
+	return expectation
+}
+
+// Returns exp(E[log theta_k]) for theta ~ Dirichlet(alpha).
+func expDirichletExpectation(alpha []float64) []float64 {
+	expectation := dirichletExpectation(alpha)
+	for k, e := range expectation {
+		expectation[k] = math.Exp(e)
+	}
+	return expectation
+}
+
+// The trigamma function, i.e., the derivative of digamma, for x > 0.
+func trigamma(x float64) float64 {
+	result := 0.0
+	for ; x < 6; x++ {
+		result += 1 / (x * x)
+	}
+	f := 1 / (x * x)
+	result += 1/x + f/2 + f/x*(1.0/6-f*(1.0/30-f*(1.0/42-f/30)))
+	return result
+}
+
+// Returns log(sum_i exp(v_i)), computed without overflow.
+func logSumExp(v []float64) float64 {
+	max := math.Inf(-1)
+	for _, x := range v {
+		if x > max {
+			max = x
+		}
+	}
+	if math.IsInf(max, -1) {
+		return max
+	}
+	sum := 0.0
+	for _, x := range v {
+		sum += math.Exp(x - max)
+	}
+	return max + math.Log(sum)
+}
+
+// Returns log Gamma(x), for x > 0.
+func logGamma(x float64) float64 {
+	lgamma, _ := math.Lgamma(x)
+	return lgamma
+}
@@ -0,0 +1,65 @@
+package lda
+
+import (
+	"rand"
+	"testing"
+)
+
+// Helpers shared by the tests of trainers and tools.
+
+var kFruits = []string{"apple", "orange", "banana", "grape"}
+var kAnimals = []string{"zebra", "monky", "lion", "tiger"}
+
+// Returns a corpus of num_docs documents, half made of fruits and half
+// of animals.
+func newTwoTopicCorpus(rng *rand.Rand, num_docs int, num_topics int) *Corpus {
+	corpus := NewCorpus()
+	for d := 0; d < num_docs; d++ {
+		vocabulary := kFruits
+		if d%2 == 1 {
+			vocabulary = kAnimals
+		}
+		text := ""
+		for i := 0; i < 8; i++ {
+			text += vocabulary[rng.Intn(len(vocabulary))] + " "
+		}
+		doc, err := NewDocument(text, num_topics)
+		if err != nil {
+			panic("Cannot create document: " + err.String())
+		}
+		*corpus = append(*corpus, doc)
+	}
+	return corpus
+}
+
+// Returns the topic in which word has the largest count.
+func dominantTopic(model *Model, word string) int {
+	hist := model.GetWordTopicHistogram(word)
+	best := 0
+	for k, c := range hist {
+		if c > hist[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+// Checks that model puts all fruits in one topic, and all animals in
+// another.
+func checkTwoTopicModel(t *testing.T, model *Model) {
+	fruit_topic := dominantTopic(model, kFruits[0])
+	animal_topic := dominantTopic(model, kAnimals[0])
+	if fruit_topic == animal_topic {
+		t.Errorf("Fruits and animals share topic %d: %v", fruit_topic, *model)
+	}
+	for _, w := range kFruits {
+		if dominantTopic(model, w) != fruit_topic {
+			t.Errorf("Expecting %s in topic %d: %v", w, fruit_topic, *model)
+		}
+	}
+	for _, w := range kAnimals {
+		if dominantTopic(model, w) != animal_topic {
+			t.Errorf("Expecting %s in topic %d: %v", w, animal_topic, *model)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package lda
+
+import (
+	"fmt"
+	"os"
+	"rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Load a corpus of time-stamped documents, for training a dynamic topic
+// model, where each line has the form
+//
+// time<TAB>text
+//
+// If slice_seconds is 0, time is the index (>= 0) of the time slice of
+// the document; otherwise, it is a Unix timestamp, and the time slices
+// are slice_seconds long, starting from the earliest timestamp.  Returns
+// the non-empty time slices in chronological order, and the time of
+// each: its index, or the timestamp at which it starts.
+func LoadTimeSlicedCorpus(filename string, num_topics int, slice_seconds int64) (
+	slices []*Corpus, times []int64, err os.Error) {
+	if slice_seconds < 0 {
+		return nil, nil, os.NewError("slice_seconds must be non-negative")
+	}
+	docs := NewCorpus()
+	doc_times := make([]int64, 0)
+	err = readLines(filename, func(line string) os.Error {
+		metadata, text, err := splitMetadata(line)
+		if err != nil {
+			return err
+		}
+		t, err := strconv.Atoi64(strings.TrimSpace(metadata))
+		if err != nil || (slice_seconds == 0 && t < 0) {
+			return os.NewError("Invalid time: " + line)
+		}
+		doc, err := NewDocument(text, num_topics)
+		if err != nil {
+			return os.NewError("Cannot create document from: " + line +
+				" due to " + err.String())
+		}
+		*docs = append(*docs, doc)
+		doc_times = append(doc_times, t)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(*docs) == 0 {
+		return nil, nil, os.NewError("Empty corpus: " + filename)
+	}
+
+	start := int64(0)
+	if slice_seconds > 0 {
+		start = doc_times[0]
+		for _, t := range doc_times {
+			if t < start {
+				start = t
+			}
+		}
+	}
+	slice_of_time := make(map[int64]*Corpus)
+	for i, doc := range *docs {
+		t := doc_times[i]
+		if slice_seconds > 0 {
+			t = start + (t-start)/slice_seconds*slice_seconds
+		}
+		slice, present := slice_of_time[t]
+		if !present {
+			slice = NewCorpus()
+			slice_of_time[t] = slice
+			times = append(times, t)
+		}
+		*slice = append(*slice, doc)
+	}
+	sort.Sort(int64Array(times))
+	slices = make([]*Corpus, len(times))
+	for i, t := range times {
+		slices[i] = slice_of_time[t]
+	}
+	return slices, times, nil
+}
+
+type int64Array []int64
+
+func (a int64Array) Len() int           { return len(a) }
+func (a int64Array) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a int64Array) Less(i, j int) bool { return a[i] < a[j] }
+
+// Assign every word occurrence in corpus a topic drawn from P(topic|word)
+// estimated from previous with the symmetric Dirichlet prior word_prior,
+// so that the topics of a time slice start aligned with those of the
+// previous slice.  Words not in previous get random topics.  A nil rng
+// uses the global random source.
+func InitializeTopicsFromModel(corpus *Corpus, previous *Model, word_prior float64,
+	rng *rand.Rand) {
+	if word_prior <= 0 {
+		panic(fmt.Sprintf("word_prior (%f) must be positive", word_prior))
+	}
+	distribution := NewDistribution(previous.NumTopics())
+	for _, doc := range *corpus {
+		if len(doc.topic_histogram) != previous.NumTopics() {
+			panic(fmt.Sprintf("doc has (%d) topics; previous has (%d) topics.",
+				len(doc.topic_histogram), previous.NumTopics()))
+		}
+		for iter, _ := NewWordIterator(doc); !iter.Done(); iter.Next() {
+			hist := previous.GetWordTopicHistogram(iter.Word())
+			for k, c := range hist {
+				distribution[k] = float64(c) + word_prior
+			}
+			iter.SetTopic(GetAccumulativeSampleWithRand(distribution, rng))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"rand"
+	"testing"
+)
+
+const kTimeSlicedCorpusFile = "testdata/timesliced_corpus.txt"
+
+func TestLoadTimeSlicedCorpus(t *testing.T) {
+	slices, times, err := LoadTimeSlicedCorpus(kTimeSlicedCorpusFile, 2, 86400)
+	if err != nil {
+		t.Fatalf("Error in loading: " + kTimeSlicedCorpusFile + " : " + err.String())
+	}
+	if s := fmt.Sprintf("%v", times); s != "[1300000000 1300086400 1300172800]" {
+		t.Errorf("Unexpected times: %s", s)
+	}
+	if len(slices) != 3 || len(*slices[0]) != 2 || len(*slices[1]) != 1 || len(*slices[2]) != 1 {
+		t.Errorf("Unexpected slices: %v", slices)
+	}
+	if words := (*slices[2])[0].unique_words; words[0] != "monky" {
+		t.Errorf("Unexpected document in the last slice: %v", words)
+	}
+
+	if slices, _, _ := LoadTimeSlicedCorpus(kTimeSlicedCorpusFile, 2, 0); len(slices) != 4 {
+		t.Errorf("Expecting 4 slices of distinct times, but got %d", len(slices))
+	}
+}
+
+func TestWordPriorFromModel(t *testing.T) {
+	model := NewModel(2)
+	model.IncrementTopic("apple", 0, 3)
+	model.IncrementTopic("orange", 0, 1)
+	model.IncrementTopic("zebra", 1, 2)
+	prior := NewWordPriorFromModel(0.01, 10, model)
+	if math.Fabs(prior.Get("apple", 0)-7.51) > 1e-9 || prior.Get("apple", 1) != 0.01 ||
+		math.Fabs(prior.Get("zebra", 1)-10.01) > 1e-9 {
+		t.Errorf("Unexpected word prior: %v", *prior)
+	}
+	if sum := prior.Sum(0, model); math.Fabs(sum-10.03) > 1e-9 {
+		t.Errorf("Expecting prior sum 10.03 of topic 0, but got %f", sum)
+	}
+
+	// In the next slice without orange, its boost is not counted.
+	next := NewModel(2)
+	next.IncrementTopic("apple", 0, 1)
+	next.IncrementTopic("kiwi", 0, 1)
+	if sum := prior.Sum(0, next); math.Fabs(sum-7.52) > 1e-9 {
+		t.Errorf("Expecting prior sum 7.52 of topic 0, but got %f", sum)
+	}
+	prior.SetNumWords(100)
+	if sum := prior.Sum(0, next); math.Fabs(sum-8.5) > 1e-9 {
+		t.Errorf("Expecting prior sum 8.5 of topic 0 over 100 words, but got %f", sum)
+	}
+}
+
+func TestTimeSlicedTopicsStayAligned(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var previous *Model
+	fruit_topic := -1
+	for slice := 0; slice < 3; slice++ {
+		corpus := newTwoTopicCorpus(rng, 20, 2)
+		if previous == nil {
+			for _, doc := range *corpus {
+				doc.RandomizeTopics(rng)
+			}
+		} else {
+			InitializeTopicsFromModel(corpus, previous, 0.01, rng)
+		}
+		model := CreateModel(2, corpus)
+		sampler := NewSampler(0.1, 0.01, model, nil)
+		sampler.SetRand(rng)
+		if previous != nil {
+			sampler.SetWordPrior(NewWordPriorFromModel(0.01, 100, previous))
+		}
+		for iter := 0; iter < 20; iter++ {
+			sampler.CorpusGibbsSampling(corpus, true, true)
+		}
+		checkTwoTopicModel(t, model)
+		if topic := dominantTopic(model, kFruits[0]); fruit_topic < 0 {
+			fruit_topic = topic
+		} else if topic != fruit_topic {
+			t.Errorf("Fruits move from topic %d to %d in slice %d", fruit_topic, topic, slice)
+		}
+		previous = model
+	}
+}
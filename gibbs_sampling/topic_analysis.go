@@ -0,0 +1,268 @@
+package lda
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Distances between topics, i.e., between their word distributions.
+const (
+	kJensenShannonDistance = "jsd"       // Jensen-Shannon divergence, in [0, log 2].
+	kHellingerDistance     = "hellinger" // In [0, 1].
+	kCosineDistance        = "cosine"    // 1 - cosine similarity, in [0, 1].
+)
+
+// Linkages of agglomerative clustering, i.e., the distance between two
+// clusters of topics in terms of distances between their topics.
+const (
+	kAverageLinkage  = "average"  // The mean distance.
+	kCompleteLinkage = "complete" // The maximum distance.
+	kSingleLinkage   = "single"   // The minimum distance.
+)
+
+// Returns the word distribution of every topic, P(word|topic) smoothed by
+// the symmetric word_prior, over the words of model sorted.
+func (model *Model) TopicWordDistributions(word_prior float64) (
+	words []string, distributions []Distribution) {
+	words = model.Words()
+	return words, model.topicWordDistributions(words, word_prior)
+}
+
+// Returns the word distribution of every topic as TopicWordDistributions
+// does, over words, which may include words not in model.
+func (model *Model) topicWordDistributions(words []string, word_prior float64) []Distribution {
+	global_histogram := model.GetGlobalTopicHistogram()
+	v := float64(len(words))
+	distributions := make([]Distribution, model.NumTopics())
+	for k := range distributions {
+		distributions[k] = NewDistribution(len(words))
+		for i, w := range words {
+			count := 0
+			if hist, present := model.topic_histograms[w]; present {
+				count = hist[k]
+			}
+			distributions[k][i] = (float64(count) + word_prior) /
+				(float64(global_histogram[k]) + v*word_prior)
+		}
+	}
+	return distributions
+}
+
+// Returns the distance function of metric (kJensenShannonDistance,
+// kHellingerDistance or kCosineDistance) and its maximum.
+func topicDistance(metric string) (distance func(p, q Distribution) float64,
+	max_distance float64, err os.Error) {
+	switch metric {
+	case kJensenShannonDistance:
+		return JensenShannonDivergence, math.Log(2), nil
+	case kHellingerDistance:
+		return HellingerDistance, 1, nil
+	case kCosineDistance:
+		return func(p, q Distribution) float64 { return 1 - CosineSimilarity(p, q) }, 1, nil
+	}
+	return nil, 0, os.NewError("Unknown topic distance: " + metric)
+}
+
+// Returns the pairwise distances between topics of model by metric
+// (kJensenShannonDistance, kHellingerDistance or kCosineDistance), with
+// topic word distributions smoothed by word_prior.
+func TopicDistances(model *Model, word_prior float64, metric string) ([][]float64, os.Error) {
+	distance, _, err := topicDistance(metric)
+	if err != nil {
+		return nil, err
+	}
+	_, phi := model.TopicWordDistributions(word_prior)
+	distances := make([][]float64, len(phi))
+	for k := range distances {
+		distances[k] = make([]float64, len(phi))
+		for j := 0; j < k; j++ {
+			distances[k][j] = distance(phi[k], phi[j])
+			distances[j][k] = distances[k][j]
+		}
+	}
+	return distances, nil
+}
+
+// A pair of topics and the distance between them.
+type TopicPair struct {
+	Topic1   int
+	Topic2   int
+	Distance float64
+}
+
+type topicPairArray []TopicPair
+
+func (a topicPairArray) Len() int      { return len(a) }
+func (a topicPairArray) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a topicPairArray) Less(i, j int) bool {
+	if a[i].Distance != a[j].Distance {
+		return a[i].Distance < a[j].Distance
+	}
+	if a[i].Topic1 != a[j].Topic1 {
+		return a[i].Topic1 < a[j].Topic1
+	}
+	return a[i].Topic2 < a[j].Topic2
+}
+
+// Returns the pairs of topics closer than threshold, i.e., likely
+// duplicates, in ascending order of distance.  Topic1 < Topic2.
+func DuplicateTopics(distances [][]float64, threshold float64) []TopicPair {
+	pairs := make(topicPairArray, 0)
+	for k := range distances {
+		for j := k + 1; j < len(distances); j++ {
+			if distances[k][j] < threshold {
+				pairs = append(pairs, TopicPair{k, j, distances[k][j]})
+			}
+		}
+	}
+	sort.Sort(pairs)
+	return pairs
+}
+
+// A merge of agglomerative clustering of K topics.  Clusters 0 to K-1
+// are the topics, and the i-th merge creates cluster K+i, as in the
+// linkage matrix of SciPy.  Cluster1 < Cluster2.
+type TopicMerge struct {
+	Cluster1 int
+	Cluster2 int
+	Distance float64
+	Size     int // The number of topics in the merged cluster.
+}
+
+// Returns the K-1 merges of agglomerative clustering of topics by their
+// distances, merging the closest clusters by linkage (kAverageLinkage,
+// kCompleteLinkage or kSingleLinkage) first.
+func ClusterTopics(distances [][]float64, linkage string) ([]TopicMerge, os.Error) {
+	if linkage != kAverageLinkage && linkage != kCompleteLinkage && linkage != kSingleLinkage {
+		return nil, os.NewError("Unknown linkage: " + linkage)
+	}
+	n := len(distances)
+	// d[i][j] is the distance between active clusters at positions i and
+	// j; ids and sizes are the cluster IDs and sizes at every position.
+	d := make([][]float64, n)
+	for i := range d {
+		d[i] = make([]float64, n)
+		copy(d[i], distances[i])
+	}
+	ids := make([]int, n)
+	sizes := make([]int, n)
+	active := make([]bool, n)
+	for i := range ids {
+		ids[i], sizes[i], active[i] = i, 1, true
+	}
+
+	merges := make([]TopicMerge, 0)
+	for m := 0; m < n-1; m++ {
+		a, b := -1, -1
+		for i := 0; i < n; i++ {
+			for j := i + 1; active[i] && j < n; j++ {
+				if active[j] && (a < 0 || d[i][j] < d[a][b]) {
+					a, b = i, j
+				}
+			}
+		}
+		c1, c2 := ids[a], ids[b]
+		if c1 > c2 {
+			c1, c2 = c2, c1
+		}
+		merges = append(merges, TopicMerge{c1, c2, d[a][b], sizes[a] + sizes[b]})
+
+		// The merged cluster takes position a, by the Lance-Williams
+		// update of distances.
+		for k := 0; k < n; k++ {
+			if !active[k] || k == a || k == b {
+				continue
+			}
+			switch linkage {
+			case kAverageLinkage:
+				d[a][k] = (float64(sizes[a])*d[a][k] + float64(sizes[b])*d[b][k]) /
+					float64(sizes[a]+sizes[b])
+			case kCompleteLinkage:
+				d[a][k] = math.Fmax(d[a][k], d[b][k])
+			case kSingleLinkage:
+				d[a][k] = math.Fmin(d[a][k], d[b][k])
+			}
+			d[k][a] = d[a][k]
+		}
+		ids[a] = n + m
+		sizes[a] += sizes[b]
+		active[b] = false
+	}
+	return merges, nil
+}
+
+// Returns the clusters of num_topics topics formed by merges closer than
+// max_distance, i.e., the cut of the hierarchy at max_distance, as sorted
+// topics, in ascending order of their first topics.  Single topics are
+// clusters too.
+func CutTopicHierarchy(merges []TopicMerge, num_topics int, max_distance float64) [][]int {
+	clusters := make(map[int][]int)
+	for k := 0; k < num_topics; k++ {
+		clusters[k] = []int{k}
+	}
+	for i, m := range merges {
+		if m.Distance >= max_distance {
+			continue
+		}
+		c1, present1 := clusters[m.Cluster1]
+		c2, present2 := clusters[m.Cluster2]
+		if !present1 || !present2 {
+			continue // A part of the merge is not merged below max_distance.
+		}
+		clusters[m.Cluster1] = nil, false
+		clusters[m.Cluster2] = nil, false
+		clusters[num_topics+i] = append(append([]int{}, c1...), c2...)
+	}
+
+	result := make([][]int, 0, len(clusters))
+	for _, c := range clusters {
+		sort.SortInts(c)
+		result = append(result, c)
+	}
+	sort.Sort(topicClusterArray(result))
+	return result
+}
+
+type topicClusterArray [][]int
+
+func (a topicClusterArray) Len() int           { return len(a) }
+func (a topicClusterArray) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a topicClusterArray) Less(i, j int) bool { return a[i][0] < a[j][0] }
+
+// Returns the merge tree of topics as text, one cluster per line,
+// indented under the cluster it is merged into, e.g.,
+//
+// cluster 4 (distance 0.600000)
+//   topic 1
+//   cluster 3 (distance 0.100000)
+//     topic 0
+//     topic 2
+//
+// label, if not nil, returns the text of a topic.
+func FormatTopicHierarchy(merges []TopicMerge, num_topics int, label func(topic int) string) string {
+	if label == nil {
+		label = func(topic int) string { return fmt.Sprintf("topic %d", topic) }
+	}
+	result := ""
+	var format func(cluster int, indent string)
+	format = func(cluster int, indent string) {
+		if cluster < num_topics {
+			result += indent + label(cluster) + "\n"
+			return
+		}
+		m := merges[cluster-num_topics]
+		result += fmt.Sprintf("%scluster %d (distance %f)\n", indent, cluster, m.Distance)
+		format(m.Cluster1, indent+"  ")
+		format(m.Cluster2, indent+"  ")
+	}
+	if len(merges) == 0 {
+		for k := 0; k < num_topics; k++ {
+			format(k, "")
+		}
+	} else {
+		format(num_topics+len(merges)-1, "")
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package lda
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Returns a model of 4 topics, where topics 0 and 2 are both about
+// fruits, 1 is about animals, and 3 about both.
+func newDuplicateTopicModel() *Model {
+	model := NewModel(4)
+	for i, w := range kFruits {
+		model.IncrementTopic(w, 0, 10+i)
+		model.IncrementTopic(w, 2, 11+i)
+		model.IncrementTopic(w, 3, 5)
+	}
+	for i, w := range kAnimals {
+		model.IncrementTopic(w, 1, 10+i)
+		model.IncrementTopic(w, 3, 5)
+	}
+	return model
+}
+
+func TestTopicDistancesAndDuplicates(t *testing.T) {
+	model := newDuplicateTopicModel()
+	for _, metric := range []string{kJensenShannonDistance, kHellingerDistance, kCosineDistance} {
+		distances, err := TopicDistances(model, 0.01, metric)
+		if err != nil {
+			t.Fatalf("Cannot compute distances: " + err.String())
+		}
+		if distances[0][2] != distances[2][0] || distances[1][1] != 0 {
+			t.Errorf("%s: distances are not symmetric: %v", metric, distances)
+		}
+		if !(distances[0][2] < distances[0][3] && distances[0][3] < distances[0][1]) {
+			t.Errorf("%s: unexpected distances: %v", metric, distances[0])
+		}
+		duplicates := DuplicateTopics(distances, (distances[0][2]+distances[0][3])/2)
+		if len(duplicates) != 1 || duplicates[0].Topic1 != 0 || duplicates[0].Topic2 != 2 {
+			t.Errorf("%s: expecting duplicates 0 and 2, but got %v", metric, duplicates)
+		}
+	}
+	if _, err := TopicDistances(model, 0.01, "euclidean"); err == nil {
+		t.Errorf("Expecting an error on an unknown distance")
+	}
+}
+
+func TestClusterTopics(t *testing.T) {
+	model := newDuplicateTopicModel()
+	distances, _ := TopicDistances(model, 0.01, kJensenShannonDistance)
+	for _, linkage := range []string{kAverageLinkage, kCompleteLinkage, kSingleLinkage} {
+		merges, err := ClusterTopics(distances, linkage)
+		if err != nil {
+			t.Fatalf("Cannot cluster topics: " + err.String())
+		}
+		if len(merges) != 3 || merges[0].Cluster1 != 0 || merges[0].Cluster2 != 2 ||
+			merges[2].Size != 4 {
+			t.Errorf("%s: unexpected merges %v", linkage, merges)
+		}
+		for i := 1; i < len(merges); i++ {
+			if merges[i].Distance < merges[i-1].Distance {
+				t.Errorf("%s: merges are not monotonic: %v", linkage, merges)
+			}
+		}
+
+		clusters := CutTopicHierarchy(merges, 4, merges[0].Distance+1e-9)
+		if s := fmt.Sprint(clusters); s != "[[0 2] [1] [3]]" {
+			t.Errorf("%s: unexpected clusters %s", linkage, s)
+		}
+		if s := fmt.Sprint(CutTopicHierarchy(merges, 4, 0)); s != "[[0] [1] [2] [3]]" {
+			t.Errorf("%s: unexpected clusters %s", linkage, s)
+		}
+	}
+
+	merges, _ := ClusterTopics([][]float64{{0, 0.1, 0.6}, {0.1, 0, 0.6}, {0.6, 0.6, 0}},
+		kAverageLinkage)
+	const kHierarchy = "cluster 4 (distance 0.600000)\n" +
+		"  topic 2\n" +
+		"  cluster 3 (distance 0.100000)\n" +
+		"    topic 0\n" +
+		"    topic 1\n"
+	if s := FormatTopicHierarchy(merges, 3, nil); s != kHierarchy {
+		t.Errorf("Expecting:\n" + kHierarchy + "but got:\n" + s)
+	}
+	if _, err := ClusterTopics(distances, "ward"); err == nil {
+		t.Errorf("Expecting an error on an unknown linkage")
+	}
+}
@@ -0,0 +1,295 @@
+package lda
+
+import (
+	"math"
+	"rand"
+)
+
+// The maximum number of E-step iterations for a document, and the
+// relative change of its likelihood bound at which they stop.
+const kVEMMaxDocumentIterations = 20
+const kVEMDocumentConvergence = 1e-6
+
+// The maximum number of Newton-Raphson iterations when estimating alpha,
+// and the absolute gradient at which they stop.
+const kVEMMaxAlphaIterations = 1000
+const kVEMAlphaConvergence = 1e-5
+
+// Alpha is held fixed in the first iterations, while topics are still
+// close to their random initialization; estimating it from such topics
+// tends to inflate alpha and trap EM in a solution of mixed topics.
+const kVEMAlphaBurnInIterations = 5
+
+// Log probabilities of words never assigned to a topic.
+const kVEMMinLogBeta = -100.0
+
+// VEMTrainer trains LDA by the batch variational EM algorithm of Blei,
+// Ng and Jordan ("Latent Dirichlet Allocation", 2003), as implemented in
+// LDA-C.  Each iteration runs an E-step, fitting the variational
+// parameters gamma (topic proportions) and phi (topic assignments of
+// words) of every document by coordinate ascent, and an M-step, updating
+// the topics beta from the expected counts, and optionally the symmetric
+// Dirichlet parameter alpha by Newton-Raphson.
+//
+// Unlike the Gibbs Sampler, it needs the whole corpus in every
+// iteration; see OnlineVBTrainer for a trainer using mini-batches.
+type VEMTrainer struct {
+	num_topics     int
+	alpha          float64
+	estimate_alpha bool
+	num_iterations int
+	log_beta       map[string][]float64 // log_beta[word][topic] = log P(word|topic)
+	class_word     map[string][]float64 // Expected counts of the last E-step.
+	rng            *rand.Rand           // nil means the global random source
+}
+
+func NewVEMTrainer(num_topics int, alpha float64, estimate_alpha bool) *VEMTrainer {
+	if num_topics <= 1 {
+		panic("num_topics must be >= 2")
+	}
+	if alpha <= 0 {
+		panic("alpha must be positive")
+	}
+	return &VEMTrainer{
+		num_topics:     num_topics,
+		alpha:          alpha,
+		estimate_alpha: estimate_alpha,
+	}
+}
+
+func (trainer *VEMTrainer) SetRand(rng *rand.Rand) {
+	trainer.rng = rng
+}
+
+func (trainer *VEMTrainer) Alpha() float64 {
+	return trainer.alpha
+}
+
+// Run one EM iteration over corpus, and returns the evidence lower bound
+// (ELBO) of the corpus computed in the E-step.  The first call
+// initializes the topics randomly over the vocabulary of corpus.
+func (trainer *VEMTrainer) Iterate(corpus *Corpus) float64 {
+	if trainer.log_beta == nil {
+		trainer.initialize(corpus)
+	}
+	num_topics := trainer.num_topics
+
+	// E-step.
+	class_word := make(map[string][]float64)
+	for word := range trainer.log_beta {
+		class_word[word] = make([]float64, num_topics)
+	}
+	elbo := 0.0
+	alpha_suffstats := 0.0
+	for _, doc := range *corpus {
+		gamma, likelihood := trainer.inferDocument(doc, class_word)
+		elbo += likelihood
+		digamma_sum := digamma(gamma.Sum())
+		for _, g := range gamma {
+			alpha_suffstats += digamma(g) - digamma_sum
+		}
+	}
+
+	// M-step.
+	trainer.class_word = class_word
+	trainer.updateBeta()
+	if trainer.estimate_alpha && trainer.num_iterations >= kVEMAlphaBurnInIterations {
+		trainer.alpha = optimizeAlpha(trainer.alpha, len(*corpus), num_topics, alpha_suffstats)
+	}
+	trainer.num_iterations++
+	return elbo
+}
+
+// Infer the topic distribution of doc, i.e., its normalized gamma.  Words
+// not seen in training are ignored.
+func (trainer *VEMTrainer) InferTopicDistribution(doc *Document) Distribution {
+	gamma, _ := trainer.inferDocument(doc, nil)
+	s := gamma.Sum()
+	for k := range gamma {
+		gamma[k] /= s
+	}
+	return gamma
+}
+
+// Export the trained topics as a Model, whose counts are the expected
+// numbers of times each word is assigned each topic in the last E-step,
+// rounded to integers.
+func (trainer *VEMTrainer) Model() *Model {
+	model := NewModel(trainer.num_topics)
+	for word, counts := range trainer.class_word {
+		for k, c := range counts {
+			if count := int(c + 0.5); count > 0 {
+				model.IncrementTopic(word, k, count)
+			}
+		}
+	}
+	return model
+}
+
+// Initialize every topic to a random distribution close to uniform over
+// the vocabulary of corpus, as LDA-C does with "random" initialization.
+func (trainer *VEMTrainer) initialize(corpus *Corpus) {
+	trainer.class_word = make(map[string][]float64)
+	for _, doc := range *corpus {
+		for _, word := range doc.unique_words {
+			if _, present := trainer.class_word[word]; !present {
+				trainer.class_word[word] = make([]float64, trainer.num_topics)
+			}
+		}
+	}
+	num_words := float64(len(trainer.class_word))
+	for _, counts := range trainer.class_word {
+		for k := range counts {
+			counts[k] = 1/num_words + randFloat64(trainer.rng)
+		}
+	}
+	trainer.updateBeta()
+}
+
+func (trainer *VEMTrainer) updateBeta() {
+	class_total := make([]float64, trainer.num_topics)
+	for _, counts := range trainer.class_word {
+		for k, c := range counts {
+			class_total[k] += c
+		}
+	}
+	trainer.log_beta = make(map[string][]float64)
+	for word, counts := range trainer.class_word {
+		l := make([]float64, trainer.num_topics)
+		for k, c := range counts {
+			if c > 0 {
+				l[k] = math.Log(c) - math.Log(class_total[k])
+			} else {
+				l[k] = kVEMMinLogBeta
+			}
+		}
+		trainer.log_beta[word] = l
+	}
+}
+
+// The E-step of a document.  Adds phi weighted by word counts into
+// class_word, if not nil, and returns gamma and the likelihood bound of
+// the document.
+func (trainer *VEMTrainer) inferDocument(doc *Document,
+	class_word map[string][]float64) (gamma Distribution, likelihood float64) {
+	num_topics := trainer.num_topics
+
+	log_betas := make([][]float64, 0, len(doc.unique_words))
+	counts := make([]float64, 0, len(doc.unique_words))
+	words := make([]string, 0, len(doc.unique_words))
+	total := 0.0
+	for i, word := range doc.unique_words {
+		if l, present := trainer.log_beta[word]; present {
+			log_betas = append(log_betas, l)
+			counts = append(counts, float64(doc.uniqueWordCount(i)))
+			words = append(words, word)
+			total += counts[len(counts)-1]
+		}
+	}
+
+	gamma = NewDistribution(num_topics)
+	phi := make([][]float64, len(words))
+	for k := range gamma {
+		gamma[k] = trainer.alpha + total/float64(num_topics)
+	}
+	for i := range phi {
+		phi[i] = make([]float64, num_topics)
+		for k := range phi[i] {
+			phi[i][k] = 1 / float64(num_topics)
+		}
+	}
+
+	digamma_gamma := make([]float64, num_topics)
+	for k, g := range gamma {
+		digamma_gamma[k] = digamma(g)
+	}
+	log_phi := make([]float64, num_topics)
+	previous := 0.0
+	for iter := 0; iter < kVEMMaxDocumentIterations; iter++ {
+		new_gamma := NewDistribution(num_topics)
+		for k := range new_gamma {
+			new_gamma[k] = trainer.alpha
+		}
+		for i := range phi {
+			for k := range log_phi {
+				log_phi[k] = log_betas[i][k] + digamma_gamma[k]
+			}
+			normalizer := logSumExp(log_phi)
+			for k := range log_phi {
+				phi[i][k] = math.Exp(log_phi[k] - normalizer)
+				new_gamma[k] += counts[i] * phi[i][k]
+			}
+		}
+		gamma = new_gamma
+		for k, g := range gamma {
+			digamma_gamma[k] = digamma(g)
+		}
+
+		likelihood = trainer.documentLikelihood(gamma, phi, counts, log_betas)
+		if iter > 0 && math.Fabs((previous-likelihood)/previous) < kVEMDocumentConvergence {
+			break
+		}
+		previous = likelihood
+	}
+
+	if class_word != nil {
+		for i, word := range words {
+			c := class_word[word]
+			for k := range c {
+				c[k] += counts[i] * phi[i][k]
+			}
+		}
+	}
+	return gamma, likelihood
+}
+
+// The variational lower bound of the log likelihood of a document.
+func (trainer *VEMTrainer) documentLikelihood(gamma Distribution, phi [][]float64,
+	counts []float64, log_betas [][]float64) float64 {
+	num_topics := float64(trainer.num_topics)
+	digamma_sum := digamma(gamma.Sum())
+	likelihood := logGamma(trainer.alpha*num_topics) -
+		num_topics*logGamma(trainer.alpha) - logGamma(gamma.Sum())
+	for k, g := range gamma {
+		elog_theta := digamma(g) - digamma_sum
+		likelihood += (trainer.alpha-1)*elog_theta + logGamma(g) - (g-1)*elog_theta
+		for i := range phi {
+			if phi[i][k] > 0 {
+				likelihood += counts[i] * phi[i][k] *
+					(elog_theta - math.Log(phi[i][k]) + log_betas[i][k])
+			}
+		}
+	}
+	return likelihood
+}
+
+// Estimate the symmetric Dirichlet parameter alpha maximizing
+//
+//   num_docs * (log Gamma(K alpha) - K log Gamma(alpha)) + (alpha - 1) * suffstats
+//
+// by Newton-Raphson on log(alpha), where suffstats is the sum of
+// E[log theta_dk] over documents d and topics k.
+func optimizeAlpha(alpha float64, num_docs int, num_topics int, suffstats float64) float64 {
+	d := float64(num_docs)
+	k := float64(num_topics)
+	init_alpha := 100.0
+	log_alpha := math.Log(init_alpha)
+	for iter := 0; iter < kVEMMaxAlphaIterations; iter++ {
+		a := math.Exp(log_alpha)
+		if math.IsNaN(a) || math.IsInf(a, 0) {
+			init_alpha *= 10
+			a = init_alpha
+			log_alpha = math.Log(a)
+		}
+		df := d*(k*digamma(k*a)-k*digamma(a)) + suffstats
+		d2f := d * (k*k*trigamma(k*a) - k*trigamma(a))
+		log_alpha -= df / (d2f*a + df)
+		if math.Fabs(df) <= kVEMAlphaConvergence {
+			break
+		}
+	}
+	if a := math.Exp(log_alpha); !math.IsNaN(a) && !math.IsInf(a, 0) && a > 0 {
+		return a
+	}
+	return alpha
+}
@@ -0,0 +1,60 @@
+package lda
+
+import (
+	"math"
+	"rand"
+	"testing"
+)
+
+func TestVEMTrainer(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	corpus := newTwoTopicCorpus(rng, 60, 2)
+	trainer := NewVEMTrainer(2, 1.0, true)
+	trainer.SetRand(rng)
+
+	previous_elbo := math.Inf(-1)
+	for iter := 0; iter < 30; iter++ {
+		elbo := trainer.Iterate(corpus)
+		// The ELBO is non-decreasing, up to the tolerance of the
+		// E-step convergence.
+		if elbo < previous_elbo-1e-3*math.Fabs(previous_elbo) {
+			t.Errorf("ELBO decreased from %f to %f in iteration %d",
+				previous_elbo, elbo, iter)
+		}
+		previous_elbo = elbo
+	}
+
+	if alpha := trainer.Alpha(); alpha <= 0 || alpha >= 1.0 || math.IsNaN(alpha) {
+		// Every document is about one topic, so alpha should be small.
+		t.Errorf("Unexpected estimated alpha: %f", alpha)
+	}
+
+	model := trainer.Model()
+	checkTwoTopicModel(t, model)
+	total := 0
+	for _, c := range model.GetGlobalTopicHistogram() {
+		total += c
+	}
+	if total < 60*8-8 || total > 60*8+8 {
+		t.Errorf("Expecting about %d words in model, but got %d", 60*8, total)
+	}
+
+	doc, _ := NewDocument("zebra lion tiger", 2)
+	distribution := trainer.InferTopicDistribution(doc)
+	if !distribution.IsValid() || distribution[dominantTopic(model, "zebra")] < 0.8 {
+		t.Errorf("Unexpected topic distribution of animals: %v", distribution)
+	}
+}
+
+func TestOptimizeAlpha(t *testing.T) {
+	// With sufficient statistics of documents drawn from Dirichlet(a),
+	// the estimate should be close to a.
+	const kNumDocs = 1000
+	const kNumTopics = 5
+	for _, a := range []float64{0.1, 1.0, 5.0} {
+		suffstats := float64(kNumDocs*kNumTopics) * (digamma(a) - digamma(kNumTopics*a))
+		if estimate := optimizeAlpha(1.0, kNumDocs, kNumTopics, suffstats); math.Fabs(estimate-a) > 1e-3*a {
+			t.Errorf("Expecting alpha %f, but got %f", a, estimate)
+		}
+	}
+}
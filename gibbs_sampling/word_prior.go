@@ -0,0 +1,113 @@
+package lda
+
+import "fmt"
+
+// WordPrior is the parameter beta of the Dirichlet priors on the word
+// distributions of topics.  beta[topic][word] is a base value shared by
+// all topics and words, plus an optional boost of the word in the topic,
+// e.g., for seed words in guided LDA.  A WordPrior without boosts is the
+// usual symmetric prior.  WordPrior caches the sums of boosts over the
+// vocabulary of a model, and is not safe for concurrent use.
+type WordPrior struct {
+	base       float64
+	boosts     map[string]Distribution // boosts[word][topic]
+	boost_sums Distribution            // Sums of boosts over words, by topic.
+	num_words  int                     // If positive, the words counted by Sum.
+
+	// The sums of boosts over the words of vocabulary_model, by topic,
+	// when it had vocabulary_size words.
+	vocabulary_model      *Model
+	vocabulary_size       int
+	vocabulary_boost_sums Distribution
+}
+
+func NewWordPrior(base float64, num_topics int) *WordPrior {
+	return &WordPrior{base: base, boosts: make(map[string]Distribution),
+		boost_sums: NewDistribution(num_topics)}
+}
+
+// Increase the prior of word in topic by amount.
+func (prior *WordPrior) Boost(word string, topic int, amount float64) {
+	if topic < 0 || topic >= len(prior.boost_sums) {
+		panic(fmt.Sprintf("topic (%d) out of range [0, %d)", topic, len(prior.boost_sums)))
+	}
+	boosts, present := prior.boosts[word]
+	if !present {
+		boosts = NewDistribution(len(prior.boost_sums))
+		prior.boosts[word] = boosts
+	}
+	boosts[topic] += amount
+	prior.boost_sums[topic] += amount
+	prior.vocabulary_model = nil
+}
+
+func (prior *WordPrior) NumTopics() int {
+	return len(prior.boost_sums)
+}
+
+// Returns beta[topic][word].
+func (prior *WordPrior) Get(word string, topic int) float64 {
+	if boosts, present := prior.boosts[word]; present {
+		return prior.base + boosts[topic]
+	}
+	return prior.base
+}
+
+// Make Sum count num_words words of the base value, in place of the words
+// of the model, e.g., to smooth a pruned model over its vocabulary before
+// pruning.  num_words of 0 restores the words of the model.
+func (prior *WordPrior) SetNumWords(num_words int) {
+	if num_words < 0 {
+		panic(fmt.Sprintf("num_words (%d) is negative", num_words))
+	}
+	prior.num_words = num_words
+}
+
+// Returns the sum of beta[topic][word] over the words of model, or over
+// num_words words if set by SetNumWords.  Boosts of words not in model,
+// e.g., seed words missing from the corpus, are not counted.  The sums of
+// boosts are recomputed when the number of words of model changes.
+func (prior *WordPrior) Sum(topic int, model *Model) float64 {
+	num_words := model.NumWords()
+	if prior.num_words > 0 {
+		num_words = prior.num_words
+	}
+	if len(prior.boosts) == 0 {
+		return float64(num_words) * prior.base
+	}
+	if prior.vocabulary_model != model || prior.vocabulary_size != model.NumWords() {
+		prior.vocabulary_boost_sums = NewDistribution(len(prior.boost_sums))
+		for word, boosts := range prior.boosts {
+			if _, present := model.topic_histograms[word]; present {
+				for k, b := range boosts {
+					prior.vocabulary_boost_sums[k] += b
+				}
+			}
+		}
+		prior.vocabulary_model, prior.vocabulary_size = model, model.NumWords()
+	}
+	return float64(num_words)*prior.base + prior.vocabulary_boost_sums[topic]
+}
+
+// Returns the boosts of word by topic, or nil if word is not boosted.
+// The returned Distribution must not be modified.
+func (prior *WordPrior) wordBoosts(word string) Distribution {
+	return prior.boosts[word]
+}
+
+// Returns a word prior that is base for all words, plus strength *
+// P(word|topic) estimated from previous, so that topics stay close to
+// those of previous, e.g., the model of the previous time slice in a
+// dynamic topic model.  strength is the total boost of each topic.
+func NewWordPriorFromModel(base float64, strength float64, previous *Model) *WordPrior {
+	prior := NewWordPrior(base, previous.NumTopics())
+	global := previous.GetGlobalTopicHistogram()
+	for word, hist := range previous.topic_histograms {
+		for k, c := range hist {
+			if c > 0 {
+				prior.Boost(word, k, strength*float64(c)/float64(global[k]))
+			}
+		}
+	}
+	return prior
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"strings"
+)
+
+var (
+	old_model_file = flag.String("old_model_file", "", "The old model file saved by train-lda")
+	new_model_file = flag.String("new_model_file", "", "The new model file saved by train-lda")
+	json_file = flag.String("json_file", "", "If specified, the (output) difference in JSON")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	metric = flag.String("metric", "jsd",
+		"The distance between topics: jsd (Jensen-Shannon divergence), hellinger or cosine " +
+		"(1 - cosine similarity)")
+	min_similarity = flag.Float64("min_similarity", 0.5,
+		"Topics less similar than this, i.e., 1 - distance / max distance, are not matched")
+	num_top_words = flag.Int("num_top_words", 10, "The number of top words compared per topic")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*old_model_file) == 0 || len(*new_model_file) == 0 {
+		fmt.Println("old_model_file and new_model_file must be specified")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *metric != "jsd" && *metric != "hellinger" && *metric != "cosine" {
+		fmt.Println("metric must be jsd, hellinger or cosine")
+		valid = false
+	}
+	if *num_top_words <= 0 {
+		fmt.Println("num_top_words must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Returns the text of topic of model, with its name if any.
+func topicLabel(model *lda.Model, topic int) string {
+	if model.TopicNames() != nil {
+		return fmt.Sprintf("%d %s", topic, model.TopicNames()[topic])
+	}
+	return fmt.Sprintf("%d", topic)
+}
+
+func formatTopics(model *lda.Model, topics []int) string {
+	labels := make([]string, len(topics))
+	for i, k := range topics {
+		labels[i] = topicLabel(model, k)
+	}
+	return strings.Join(labels, ", ")
+}
+
+// Output the difference from old_model_file to new_model_file, as
+//
+// matched
+//	old_topic	new_topic	similarity
+//	  + entered_word_1 entered_word_2 ...
+//	  - left_word_1 left_word_2 ...
+//	...
+// unmatched_old	topic_1, topic_2, ...
+// unmatched_new	topic_1, topic_2, ...
+// added_words	word_1 word_2 ...
+// removed_words	word_1 word_2 ...
+//
+// and save it in JSON into json_file.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+
+	old_model, err := lda.LoadModel(*old_model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *old_model_file + ", due to " + err.String())
+		return
+	}
+	new_model, err := lda.LoadModel(*new_model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *new_model_file + ", due to " + err.String())
+		return
+	}
+	diff, err := lda.DiffModels(old_model, new_model, *word_prior, *metric, *num_top_words,
+		*min_similarity)
+	if err != nil {
+		fmt.Printf("Cannot diff models due to " + err.String())
+		return
+	}
+
+	fmt.Printf("matched\n")
+	for _, d := range diff.Matched {
+		fmt.Printf("\t%s\t%s\t%f\n", topicLabel(old_model, d.OldTopic),
+			topicLabel(new_model, d.NewTopic), d.Similarity)
+		if len(d.Entered) > 0 {
+			fmt.Printf("\t  + %s\n", strings.Join(d.Entered, " "))
+		}
+		if len(d.Left) > 0 {
+			fmt.Printf("\t  - %s\n", strings.Join(d.Left, " "))
+		}
+	}
+	fmt.Printf("unmatched_old\t%s\n", formatTopics(old_model, diff.UnmatchedOld))
+	fmt.Printf("unmatched_new\t%s\n", formatTopics(new_model, diff.UnmatchedNew))
+	fmt.Printf("added_words\t%s\n", strings.Join(diff.AddedWords, " "))
+	fmt.Printf("removed_words\t%s\n", strings.Join(diff.RemovedWords, " "))
+
+	if len(*json_file) > 0 {
+		payload, err := diff.JSON()
+		if err != nil {
+			fmt.Printf("Cannot encode JSON due to " + err.String())
+			return
+		}
+		if err := lda.SaveFile(*json_file, string(payload)); err != nil {
+			fmt.Printf("Cannot save JSON due to " + err.String())
+		}
+	}
+}
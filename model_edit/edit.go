@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"lda"
+	"os"
+	"rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file to edit")
+	output_model_file = flag.String("output_model_file", "", "The file to save the edited model")
+	script_file = flag.String("script_file", "",
+		"The file of edit commands, one per line, run before those of --commands")
+	commands = flag.String("commands", "", "Edit commands separated by semicolons")
+	corpus_file = flag.String("corpus_file", "", "The corpus whose tokens are re-sampled by split")
+	phrase_file = flag.String("phrase_file", "",
+		"If specified, the phrase table saved by train-lda, whose phrases are merged in the " +
+		"documents of corpus_file")
+	topic_prior = flag.Float64("topic_prior", 0.1,
+		"The parameter of symmetric Dirichlet on topics used by split")
+	word_prior = flag.Float64("word_prior", 0.01,
+		"The parameter of symmetric Dirichlet on words used by split")
+	split_iterations = flag.Int("split_iterations", 50, "The number of Gibbs sampling iterations of split")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 {
+		fmt.Println("model_file must be specified")
+		valid = false
+	}
+	if len(*output_model_file) == 0 {
+		fmt.Println("output_model_file must be specified")
+		valid = false
+	}
+	if len(*script_file) == 0 && len(*commands) == 0 {
+		fmt.Println("script_file or commands must be specified")
+		valid = false
+	}
+	if *topic_prior <= 0 || *word_prior <= 0 {
+		fmt.Println("topic_prior and word_prior must be positive")
+		valid = false
+	}
+	if *split_iterations <= 0 {
+		fmt.Println("split_iterations must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Returns the current indices of the topics in fields, which are named
+// as by tracker.
+func parseTopics(fields []string, tracker *lda.TopicTracker) ([]int, os.Error) {
+	topics := make([]int, len(fields))
+	for i, f := range fields {
+		k, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, os.NewError("Invalid topic: " + f)
+		}
+		topics[i] = k
+	}
+	return tracker.Translate(topics)
+}
+
+// Run an edit command on model, and returns the new index of every old
+// topic.  Topics of commands are named as by tracker, which follows the
+// edit.
+func runCommand(model *lda.Model, command string, tracker *lda.TopicTracker) ([]int, os.Error) {
+	fields := strings.Fields(command)
+	mapping := make([]int, model.NumTopics())
+	for k := range mapping {
+		mapping[k] = k
+	}
+	switch fields[0] {
+	case "merge", "delete", "reorder":
+		topics, err := parseTopics(fields[1:], tracker)
+		if err != nil {
+			return nil, err
+		}
+		switch fields[0] {
+		case "merge":
+			mapping, err = model.MergeTopics(topics)
+		case "delete":
+			mapping, err = model.DeleteTopics(topics)
+		default:
+			mapping, err = model.ReorderTopics(topics)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tracker.Update(mapping)
+	case "name":
+		if len(fields) != 3 {
+			return nil, os.NewError("Usage: name topic name")
+		}
+		topics, err := parseTopics(fields[1:2], tracker)
+		if err != nil {
+			return nil, err
+		}
+		if err := model.SetTopicName(topics[0], fields[2]); err != nil {
+			return nil, err
+		}
+	case "split":
+		if len(fields) != 2 {
+			return nil, os.NewError("Usage: split topic")
+		}
+		topics, err := parseTopics(fields[1:], tracker)
+		if err != nil {
+			return nil, err
+		}
+		if len(*corpus_file) == 0 {
+			return nil, os.NewError("corpus_file must be specified to split")
+		}
+		var corpus *lda.Corpus
+		if len(*phrase_file) > 0 {
+			table, err := lda.LoadPhraseTable(*phrase_file)
+			if err != nil {
+				return nil, os.NewError("Error in loading: " + *phrase_file + ", due to " + err.String())
+			}
+			corpus, err = lda.LoadPhrasedCorpus(*corpus_file, model.NumTopics(), table)
+		} else {
+			corpus, err = lda.LoadCorpus(*corpus_file, model.NumTopics())
+		}
+		if err != nil {
+			return nil, os.NewError("Error in loading: " + *corpus_file + ", due to " + err.String())
+		}
+		new_topic, err := model.SplitTopic(topics[0], corpus, *topic_prior, *word_prior,
+			*split_iterations, nil)
+		if err != nil {
+			return nil, err
+		}
+		// The old topics keep their indices.
+		fmt.Printf("split topic %s is named %d\n", fields[1], tracker.AddTopic(new_topic))
+	default:
+		return nil, os.NewError("Unknown command: " + fields[0])
+	}
+	return mapping, nil
+}
+
+// Edit the model of model_file by commands, from script_file and then
+// from --commands, and save it to output_model_file.  Commands are
+//
+// merge topic_1 topic_2 ...   (as output by topic-analysis)
+// delete topic_1 ...
+// reorder topic_0 topic_1 ... (topic_i becomes topic i)
+// name topic name
+// split topic                 (appends a new topic; needs corpus_file)
+//
+// Topics are indices in model_file, however the preceding commands
+// renumbered them, so that all merge lines output by topic-analysis for
+// model_file can be run in sequence.  A merged topic may be named by any
+// of its topics.  The topic appended by the i-th split (from 0) is named
+// by the number of topics of model_file plus i.  Blank lines and lines
+// starting with '#' are ignored.  After each command, the new index of
+// every old topic of the edited model is printed, -1 for deleted.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+
+	lines := make([]string, 0)
+	if len(*script_file) > 0 {
+		script, err := ioutil.ReadFile(*script_file)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *script_file + ", due to " + err.String())
+			return
+		}
+		lines = append(lines, strings.Split(string(script), "\n", -1)...)
+	}
+	if len(*commands) > 0 {
+		lines = append(lines, strings.Split(*commands, ";", -1)...)
+	}
+
+	rand.Seed(time.Nanoseconds())
+	tracker := lda.NewTopicTracker(model.NumTopics())
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		mapping, err := runCommand(model, line, tracker)
+		if err != nil {
+			fmt.Printf("Cannot run: " + line + ", due to " + err.String() + "\n")
+			return
+		}
+		fmt.Printf("%s\t%v\n", line, mapping)
+	}
+
+	if err := model.SaveModel(*output_model_file); err != nil {
+		fmt.Printf("Error in saving: " + *output_model_file + ", due to " + err.String())
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"rand"
+	"time"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file to prune")
+	output_model_file = flag.String("output_model_file", "", "The file to save the pruned model")
+	min_word_count = flag.Int("min_word_count", 0,
+		"If positive, words whose total counts are less than this are dropped")
+	top_words_per_topic = flag.Int("top_words_per_topic", 0,
+		"If positive, only the counts of this number of most frequent words per topic are kept")
+	min_topic_probability = flag.Float64("min_topic_probability", 0,
+		"If positive, counts less than this proportion of their topics are zeroed")
+	heldout_corpus_file = flag.String("heldout_corpus_file", "",
+		"If specified, the held-out corpus whose log-likelihood is reported before and after pruning")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	inference_iterations = flag.Int("inference_iterations", 30,
+		"The number of Gibbs sampling iterations for inferring the topics of held-out documents")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 {
+		fmt.Println("model_file must be specified")
+		valid = false
+	}
+	if len(*output_model_file) == 0 {
+		fmt.Println("output_model_file must be specified")
+		valid = false
+	}
+	if *min_word_count <= 0 && *top_words_per_topic <= 0 && *min_topic_probability <= 0 {
+		fmt.Println("min_word_count, top_words_per_topic or min_topic_probability must be positive")
+		valid = false
+	}
+	if *topic_prior <= 0 || *word_prior <= 0 {
+		fmt.Println("topic_prior and word_prior must be positive")
+		valid = false
+	}
+	if *inference_iterations <= 0 {
+		fmt.Println("inference_iterations must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Returns the number of non-zero counts and the total count of model.
+func countModel(model *lda.Model) (non_zero int, total int) {
+	for _, w := range model.Words() {
+		for _, c := range model.GetWordTopicHistogram(w) {
+			if c > 0 {
+				non_zero++
+				total += c
+			}
+		}
+	}
+	return
+}
+
+// Prune the model of model_file by all the specified options, in the
+// order of min_word_count, top_words_per_topic and min_topic_probability,
+// save it to output_model_file, and output the effect as
+//
+// words	before	after
+// non_zero_counts	before	after
+// total_count	before	after
+// heldout_log_likelihood	before	after
+//
+// where the held-out log-likelihood is per token, with P(word|topic)
+// smoothed over the vocabulary of the model before pruning in both cases.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+	rand.Seed(time.Nanoseconds())
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+	var heldout *lda.Corpus
+	if len(*heldout_corpus_file) > 0 {
+		heldout, err = lda.LoadCorpus(*heldout_corpus_file, model.NumTopics())
+		if err != nil {
+			fmt.Printf("Error in loading: " + *heldout_corpus_file + ", due to " + err.String())
+			return
+		}
+	}
+
+	num_words := model.NumWords()
+	non_zero, total := countModel(model)
+	log_likelihood := 0.0
+	if heldout != nil {
+		log_likelihood = lda.HeldOutLogLikelihood(model, heldout, *topic_prior, *word_prior,
+			num_words, *inference_iterations, nil)
+	}
+
+	if *min_word_count > 0 {
+		model.PruneRareWords(*min_word_count)
+	}
+	if *top_words_per_topic > 0 {
+		model.PruneTopWords(*top_words_per_topic)
+	}
+	if *min_topic_probability > 0 {
+		model.PruneCounts(*min_topic_probability)
+	}
+
+	pruned_non_zero, pruned_total := countModel(model)
+	fmt.Printf("words\t%d\t%d\n", num_words, model.NumWords())
+	fmt.Printf("non_zero_counts\t%d\t%d\n", non_zero, pruned_non_zero)
+	fmt.Printf("total_count\t%d\t%d\n", total, pruned_total)
+	if heldout != nil {
+		fmt.Printf("heldout_log_likelihood\t%f\t%f\n", log_likelihood,
+			lda.HeldOutLogLikelihood(model, heldout, *topic_prior, *word_prior,
+				num_words, *inference_iterations, nil))
+	}
+
+	if err := model.SaveModel(*output_model_file); err != nil {
+		fmt.Printf("Error in saving: " + *output_model_file + ", due to " + err.String())
+	}
+}
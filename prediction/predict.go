@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"os"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file saved by train-lda --response_corpus")
+	response_model_file = flag.String("response_model_file", "",
+		"The response model file saved by train-lda --response_corpus")
+	corpus_file = flag.String("corpus_file", "",
+		"The documents to predict responses of, one per line")
+	with_responses = flag.Bool("with_responses", false,
+		"Whether each line of corpus_file is a true response, a tab, and the text; if so, the " +
+		"error of predictions is also output")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	burn_in_iterations = flag.Int("burn_in_iterations", 20,
+		"The number of Gibbs sampling iterations for burning in the inference of each document")
+	accumulate_iterations = flag.Int("accumulate_iterations", 10,
+		"The number of Gibbs sampling iterations for accumulating the topic frequencies")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 || len(*response_model_file) == 0 {
+		fmt.Println("model_file and response_model_file must be specified")
+		valid = false
+	}
+	if len(*corpus_file) == 0 {
+		fmt.Println("corpus_file must be specified")
+		valid = false
+	}
+	if *topic_prior <= 0 {
+		fmt.Println("topic_prior must be positive")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *burn_in_iterations < 0 {
+		fmt.Println("burn_in_iterations must be non-negative")
+		valid = false
+	}
+	if *accumulate_iterations <= 0 {
+		fmt.Println("accumulate_iterations must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Output the predicted response of every document in corpus_file, one per
+// line: the mean of a gaussian response, or the probability of a binary
+// response being 1.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop prediction due to invalid flag setting.\n")
+		return
+	}
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+	response, err := lda.LoadResponseModel(*response_model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *response_model_file + ", due to " + err.String())
+		return
+	}
+	if response.NumTopics() != model.NumTopics() {
+		fmt.Printf("model_file has %d topics, but response_model_file has %d topics\n",
+			model.NumTopics(), response.NumTopics())
+		return
+	}
+
+	var corpus *lda.Corpus
+	var responses []float64
+	if *with_responses {
+		corpus, responses, err = lda.LoadResponseCorpus(*corpus_file, model.NumTopics())
+		if err == nil {
+			err = response.CheckResponses(responses)
+		}
+	} else {
+		corpus, err = lda.LoadCorpus(*corpus_file, model.NumTopics())
+	}
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return
+	}
+
+	sampler := lda.NewSampler(*topic_prior, *word_prior, model, nil)
+	total_error := 0.0
+	for i, doc := range *corpus {
+		prediction := response.PredictDocument(sampler, doc,
+			*burn_in_iterations, *accumulate_iterations)
+		fmt.Printf("%g\n", prediction)
+		if responses != nil {
+			total_error += response.PredictionError(responses[i], prediction)
+		}
+	}
+	if responses != nil && len(responses) > 0 {
+		fmt.Fprintf(os.Stderr, "Prediction error: %g\n", total_error/float64(len(responses)))
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"http"
+	"lda"
+	"strings"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file to serve as model \"default\"")
+	models = flag.String("models", "",
+		"Additional models to serve, as comma separated name=model_file pairs.  Without " +
+		"model_file, the first of them is also served as model \"default\"")
+	address = flag.String("address", ":8080", "The address to listen on")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	burn_in_iterations = flag.Int("burn_in_iterations", 20,
+		"The number of Gibbs sampling iterations for burning in the inference of each document")
+	accumulate_iterations = flag.Int("accumulate_iterations", 10,
+		"The number of Gibbs sampling iterations for accumulating the inferred topic distribution")
+	batch_parallelism = flag.Int("batch_parallelism", 4,
+		"The number of documents of a batch request inferred in parallel")
+	model_type = flag.String("model_type", "lda",
+		"How the models were trained, which decides how topics are inferred: lda (fold-in Gibbs " +
+		"sampling), for every algorithm of train-lda but btm, or btm (biterm topic model)")
+	biterm_window = flag.Int("biterm_window", 15,
+		"With model_type btm, the maximum distance between the words of a biterm, as at " +
+		"training; 0 pairs all words of a text")
+	phrase_file = flag.String("phrase_file", "",
+		"The phrase table saved by train-lda, if models were trained with phrases merged")
+	reload_interval_seconds = flag.Int("reload_interval_seconds", 10,
+		"How often to check model files for changes and reload them; 0 disables reloading.  A " +
+		"changed file is reloaded once unchanged for an interval; replace files by renaming")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 && len(*models) == 0 {
+		fmt.Println("model_file or models must be specified")
+		valid = false
+	}
+	if *topic_prior <= 0 {
+		fmt.Println("topic_prior must be positive")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *burn_in_iterations < 0 {
+		fmt.Println("burn_in_iterations must be non-negative")
+		valid = false
+	}
+	if *accumulate_iterations <= 0 {
+		fmt.Println("accumulate_iterations must be positive")
+		valid = false
+	}
+	if *batch_parallelism <= 0 {
+		fmt.Println("batch_parallelism must be positive")
+		valid = false
+	}
+	if *model_type != "lda" && *model_type != "btm" {
+		fmt.Println("model_type must be lda or btm")
+		valid = false
+	}
+	if *biterm_window < 0 {
+		fmt.Println("biterm_window must be non-negative")
+		valid = false
+	}
+	if *reload_interval_seconds < 0 {
+		fmt.Println("reload_interval_seconds must be non-negative")
+		valid = false
+	}
+	return valid
+}
+
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop serving due to invalid flag setting.\n")
+		return
+	}
+
+	registry := lda.NewModelRegistry()
+	if len(*model_file) > 0 {
+		if _, err := registry.Register("default", *model_file); err != nil {
+			fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+			return
+		}
+	}
+	if len(*models) > 0 {
+		for i, pair := range strings.Split(*models, ",", -1) {
+			name_and_file := strings.Split(pair, "=", 2)
+			if len(name_and_file) != 2 {
+				fmt.Printf("Invalid name=model_file pair: %s\n", pair)
+				return
+			}
+			if _, err := registry.Register(name_and_file[0], name_and_file[1]); err != nil {
+				fmt.Printf("Error in loading: " + name_and_file[1] + ", due to " + err.String())
+				return
+			}
+			if i == 0 && len(*model_file) == 0 {
+				if _, err := registry.Register("default", name_and_file[1]); err != nil {
+					fmt.Printf("Error in loading: " + name_and_file[1] + ", due to " + err.String())
+					return
+				}
+			}
+		}
+	}
+	if *reload_interval_seconds > 0 {
+		registry.Watch(int64(*reload_interval_seconds) * 1e9)
+	}
+
+	server := lda.NewServer(registry, *topic_prior, *word_prior,
+		*burn_in_iterations, *accumulate_iterations)
+	server.SetBatchParallelism(*batch_parallelism)
+	if *model_type == "btm" {
+		server.SetBitermInference(*biterm_window)
+	}
+	if len(*phrase_file) > 0 {
+		table, err := lda.LoadPhraseTable(*phrase_file)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *phrase_file + ", due to " + err.String())
+			return
+		}
+		server.SetPhraseTable(table)
+	}
+	for _, m := range registry.List() {
+		fmt.Printf("Serving model %s from %s\n", m.Name, m.Filename)
+	}
+	fmt.Printf("Listening on %s\n", *address)
+	if err := http.ListenAndServe(*address, server); err != nil {
+		fmt.Printf("Cannot serve due to " + err.String())
+	}
+	return
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"strconv"
+	"time"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file saved by train-lda")
+	corpus_file = flag.String("corpus_file", "",
+		"The documents to index, one per line; unless with_ids, their IDs are their positions")
+	with_ids = flag.Bool("with_ids", false,
+		"Whether each line of corpus_file is an ID, a tab, and the text")
+	index_file = flag.String("index_file", "",
+		"The index saved by --output_index_file, used instead of corpus_file")
+	output_index_file = flag.String("output_index_file", "",
+		"If specified, the index built from corpus_file is saved into this file")
+	phrase_file = flag.String("phrase_file", "",
+		"If specified, the phrase table saved by train-lda, whose phrases are merged in the " +
+		"documents of corpus_file and query")
+	query = flag.String("query", "", "The query text, words separated by whitespaces")
+	num_neighbors = flag.Int("num_neighbors", 10, "The number of similar documents output")
+	metric = flag.String("metric", "hellinger",
+		"The distance between documents: hellinger, jsd (Jensen-Shannon divergence) or cosine " +
+		"(1 - cosine similarity)")
+	lsh_tables = flag.Int("lsh_tables", 0,
+		"If positive, the number of LSH tables of an approximate index; otherwise search is exact")
+	lsh_bits = flag.Int("lsh_bits", 12, "The number of random hyperplanes per LSH table")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	burn_in_iterations = flag.Int("burn_in_iterations", 20,
+		"The number of Gibbs sampling iterations for burning in the inference of each document")
+	accumulate_iterations = flag.Int("accumulate_iterations", 10,
+		"The number of Gibbs sampling iterations for accumulating the inferred topic distribution")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 {
+		fmt.Println("model_file must be specified")
+		valid = false
+	}
+	if (len(*corpus_file) == 0) == (len(*index_file) == 0) {
+		fmt.Println("Either corpus_file or index_file must be specified")
+		valid = false
+	}
+	if len(*query) == 0 && len(*output_index_file) == 0 {
+		fmt.Println("query or output_index_file must be specified")
+		valid = false
+	}
+	if *num_neighbors <= 0 {
+		fmt.Println("num_neighbors must be positive")
+		valid = false
+	}
+	if *metric != "hellinger" && *metric != "jsd" && *metric != "cosine" {
+		fmt.Println("metric must be hellinger, jsd or cosine")
+		valid = false
+	}
+	if *lsh_tables < 0 || *lsh_bits <= 0 || *lsh_bits > 64 {
+		fmt.Println("lsh_tables must be non-negative, and lsh_bits in [1, 64]")
+		valid = false
+	}
+	if *topic_prior <= 0 {
+		fmt.Println("topic_prior must be positive")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *burn_in_iterations < 0 {
+		fmt.Println("burn_in_iterations must be non-negative")
+		valid = false
+	}
+	if *accumulate_iterations <= 0 {
+		fmt.Println("accumulate_iterations must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Index the topic distributions of documents in corpus_file inferred by
+// model_file, or load the index of index_file, and output the documents
+// most similar to query, one per line, as
+//
+// id<TAB>distance
+//
+// in ascending order of distance.  metric and the LSH flags apply to new
+// indices only; a loaded index keeps those it was built with.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+	sampler := lda.NewSampler(*topic_prior, *word_prior, model, nil)
+	var table *lda.PhraseTable
+	if len(*phrase_file) > 0 {
+		if table, err = lda.LoadPhraseTable(*phrase_file); err != nil {
+			fmt.Printf("Error in loading: " + *phrase_file + ", due to " + err.String())
+			return
+		}
+	}
+
+	var index *lda.DocumentIndex
+	if len(*index_file) > 0 {
+		index, err = lda.LoadDocumentIndex(*index_file)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *index_file + ", due to " + err.String())
+			return
+		}
+		if index.Size() > 0 && index.NumTopics() != model.NumTopics() {
+			fmt.Printf("The index has %d topics; the model has %d topics.\n",
+				index.NumTopics(), model.NumTopics())
+			return
+		}
+	} else {
+		if *lsh_tables > 0 {
+			index, err = lda.NewLSHDocumentIndex(*metric, *lsh_tables, *lsh_bits, time.Nanoseconds())
+		} else {
+			index, err = lda.NewDocumentIndex(*metric)
+		}
+		if err != nil {
+			fmt.Printf("Cannot create index due to " + err.String())
+			return
+		}
+
+		var corpus *lda.Corpus
+		var ids []string
+		if *with_ids {
+			corpus, ids, err = lda.LoadCorpusWithIDs(*corpus_file, model.NumTopics(), table)
+		} else if table != nil {
+			corpus, err = lda.LoadPhrasedCorpus(*corpus_file, model.NumTopics(), table)
+		} else {
+			corpus, err = lda.LoadCorpus(*corpus_file, model.NumTopics())
+		}
+		if err != nil {
+			fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+			return
+		}
+		for d, doc := range *corpus {
+			id := strconv.Itoa(d)
+			if ids != nil {
+				id = ids[d]
+			}
+			index.Add(id, sampler.InferTopicDistribution(doc,
+				*burn_in_iterations, *accumulate_iterations))
+		}
+		if len(*output_index_file) > 0 {
+			if err := index.SaveDocumentIndex(*output_index_file); err != nil {
+				fmt.Printf("Error in saving: " + *output_index_file + ", due to " + err.String())
+				return
+			}
+		}
+	}
+
+	if len(*query) > 0 {
+		var doc *lda.Document
+		if table != nil {
+			doc, err = table.NewDocument(*query, model.NumTopics())
+		} else {
+			doc, err = lda.NewDocument(*query, model.NumTopics())
+		}
+		if err != nil {
+			fmt.Printf("Cannot create document from query due to " + err.String())
+			return
+		}
+		distribution := sampler.InferTopicDistribution(doc,
+			*burn_in_iterations, *accumulate_iterations)
+		neighbors, err := index.Nearest(distribution, *num_neighbors)
+		if err != nil {
+			fmt.Printf("Cannot search the index due to " + err.String())
+			return
+		}
+		for _, n := range neighbors {
+			fmt.Printf("%s\t%f\n", n.ID, n.Distance)
+		}
+	}
+}
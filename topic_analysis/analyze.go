@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"strings"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file saved by train-lda")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	metric = flag.String("metric", "jsd",
+		"The distance between topics: jsd (Jensen-Shannon divergence), hellinger or cosine " +
+		"(1 - cosine similarity)")
+	linkage = flag.String("linkage", "average",
+		"The linkage of agglomerative clustering of topics: average, complete or single")
+	duplicate_threshold = flag.Float64("duplicate_threshold", 0.1,
+		"Topics closer than this distance are reported as duplicates, and suggested to merge")
+	num_top_words = flag.Int("num_top_words", 5, "The number of top words shown per topic")
+	show_tree = flag.Bool("show_tree", true, "Whether to print the merge tree of all topics")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 {
+		fmt.Println("model_file must be specified")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *metric != "jsd" && *metric != "hellinger" && *metric != "cosine" {
+		fmt.Println("metric must be jsd, hellinger or cosine")
+		valid = false
+	}
+	if *linkage != "average" && *linkage != "complete" && *linkage != "single" {
+		fmt.Println("linkage must be average, complete or single")
+		valid = false
+	}
+	if *num_top_words < 0 {
+		fmt.Println("num_top_words must be non-negative")
+		valid = false
+	}
+	return valid
+}
+
+// Output the duplicate topics of model_file, the merge tree of its topics
+// and the suggested merges, i.e., the clusters of topics merged below
+// duplicate_threshold, as
+//
+// duplicates
+//	topic_1	topic_2	distance
+//	...
+// tree
+//	cluster ... (distance ...)
+//	  topic ...: top_word_1 top_word_2 ...
+//	...
+// merge topic_1 topic_2 ...
+// ...
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+	distances, err := lda.TopicDistances(model, *word_prior, *metric)
+	if err != nil {
+		fmt.Printf("Cannot compute topic distances due to " + err.String())
+		return
+	}
+	merges, err := lda.ClusterTopics(distances, *linkage)
+	if err != nil {
+		fmt.Printf("Cannot cluster topics due to " + err.String())
+		return
+	}
+
+	fmt.Printf("duplicates\n")
+	for _, pair := range lda.DuplicateTopics(distances, *duplicate_threshold) {
+		fmt.Printf("\t%d\t%d\t%f\n", pair.Topic1, pair.Topic2, pair.Distance)
+	}
+
+	if *show_tree {
+		label := func(topic int) string {
+			text := fmt.Sprintf("topic %d", topic)
+			if model.TopicNames() != nil {
+				text += " " + model.TopicNames()[topic]
+			}
+			if *num_top_words > 0 {
+				words := make([]string, 0, *num_top_words)
+				for _, w := range model.TopWords(topic, *num_top_words) {
+					words = append(words, w.Word)
+				}
+				text += ": " + strings.Join(words, " ")
+			}
+			return text
+		}
+		fmt.Printf("tree\n")
+		tree := lda.FormatTopicHierarchy(merges, model.NumTopics(), label)
+		for _, line := range strings.Split(strings.TrimRight(tree, "\n"), "\n", -1) {
+			fmt.Printf("\t%s\n", line)
+		}
+	}
+
+	for _, cluster := range lda.CutTopicHierarchy(merges, model.NumTopics(), *duplicate_threshold) {
+		if len(cluster) > 1 {
+			fmt.Printf("merge")
+			for _, k := range cluster {
+				fmt.Printf(" %d", k)
+			}
+			fmt.Printf("\n")
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"lda"
+	"os"
+)
+
+// Save the diagnostics of topics of model into diagnostics_file in
+// diagnostics_format, named by topic_names if not nil.  model and the
+// topics of words of corpus must be the final state of training.
+func SaveTrainingDiagnostics(model *lda.Model, corpus *lda.Corpus, topic_names []string) os.Error {
+	diagnostics := lda.ComputeTopicDiagnostics(model, corpus, *word_prior, *diagnostics_top_words)
+	text, err := lda.FormatTopicDiagnostics(diagnostics, *diagnostics_format, topic_names)
+	if err != nil {
+		return err
+	}
+
+	return lda.SaveFile(*diagnostics_file, text)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"lda"
+	"os"
+)
+
+// Train a DMR topic model by collapsed Gibbs sampling, optimizing the
+// feature weights every dmr_optimize_interval iterations, and save the
+// covariate report.  Returns the average of the models accumulated after
+// burn-in, or nil on errors.
+func TrainDMR() *lda.Model {
+	corpus, covariates, features, err := lda.LoadDMRCorpus(*corpus_file, *num_topics)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(nil)
+	}
+
+	model := lda.CreateModel(*num_topics, corpus)
+	accum_model := lda.NewModel(*num_topics)
+	sampler := lda.NewSampler(*topic_prior, *word_prior, model, accum_model)
+	if len(*seed_file) > 0 {
+		seeds, err := lda.LoadSeedWords(*seed_file, *num_topics)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *seed_file + ", due to " + err.String())
+			return nil
+		}
+		sampler.SetWordPrior(seeds.WordPrior(*word_prior, *seed_boost, *num_topics))
+	}
+	dmr := lda.NewDMRModel(features, *num_topics, *topic_prior, *dmr_variance)
+
+	for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
+		fmt.Printf("Iteration %d ... ", iter)
+		if (*compute_loglikelihood) {
+			fmt.Printf("log-likelihood: %f", sampler.CorpusLogLikelihood(corpus))
+		}
+		sampler.CorpusGibbsSampling(corpus, true, iter < *burn_in_iterations)
+		if (iter+1) % *dmr_optimize_interval == 0 {
+			fmt.Printf(", feature weights log-posterior: %f", dmr.Optimize(corpus, covariates))
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(*covariate_report_file) > 0 {
+		if err := SaveCovariateReport(*covariate_report_file, dmr); err != nil {
+			fmt.Printf("Cannot save covariate report due to " + err.String())
+			return nil
+		}
+	}
+	averageAccumulatedModel(accum_model)
+	return accum_model
+}
+
+// Save the weights of features by topic, in descending order, in the
+// format of
+//
+// topic 0
+//	feature_1	weight_1
+//	feature_2	weight_2
+// topic 1
+// ...
+//
+// A positive weight means that the feature makes the topic more likely.
+func SaveCovariateReport(filename string, dmr *lda.DMRModel) os.Error {
+	report := ""
+	for k := 0; k < dmr.NumTopics(); k++ {
+		report += fmt.Sprintf("topic %d\n", k)
+		for _, w := range dmr.FeatureWeights(k) {
+			report += fmt.Sprintf("\t%s\t%f\n", w.Feature, w.Weight)
+		}
+	}
+	return lda.SaveFile(filename, report)
+}
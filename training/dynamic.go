@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"lda"
+	"os"
+	"strconv"
+)
+
+// Train a dynamic topic model, i.e., a model per time slice of the
+// corpus, by collapsed Gibbs sampling.  The topics of each slice start
+// from, and are drawn towards, those of the previous slice, so that
+// topic IDs are aligned across slices.  Saves the model of each slice
+// and the topic report, and returns the model of the last slice, or nil
+// on errors.
+func TrainDynamic() *lda.Model {
+	slices, times, err := lda.LoadTimeSlicedCorpus(*corpus_file, *num_topics, *slice_seconds)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+
+	models := make([]*lda.Model, len(slices))
+	var previous *lda.Model
+	for i, corpus := range slices {
+		fmt.Printf("Time slice %d: %d documents\n", times[i], len(*corpus))
+		if previous == nil {
+			for _, doc := range *corpus {
+				doc.RandomizeTopics(nil)
+			}
+		} else {
+			lda.InitializeTopicsFromModel(corpus, previous, *word_prior, nil)
+		}
+		model := lda.CreateModel(*num_topics, corpus)
+		accum_model := lda.NewModel(*num_topics)
+		sampler := lda.NewSampler(*topic_prior, *word_prior, model, accum_model)
+		if previous != nil {
+			sampler.SetWordPrior(
+				lda.NewWordPriorFromModel(*word_prior, *slice_prior_strength, previous))
+		}
+
+		for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
+			fmt.Printf("Iteration %d ... ", iter)
+			if (*compute_loglikelihood) {
+				fmt.Printf("log-likelihood: %f\n", sampler.CorpusLogLikelihood(corpus))
+			} else {
+				fmt.Printf("\n")
+			}
+			sampler.CorpusGibbsSampling(corpus, true, iter < *burn_in_iterations)
+		}
+
+		// Always averaged, as slice_prior_strength is relative to counts on
+		// the scale of the training data of the next slice.
+		accum_model.Scale(1.0 / float64(*accumulate_iterations))
+		slice_model_file := *model_file + "." + strconv.Itoa64(times[i])
+		if err := accum_model.SaveModel(slice_model_file); err != nil {
+			fmt.Printf("Cannot save model due to " + err.String())
+			return nil
+		}
+		models[i] = accum_model
+		previous = accum_model
+	}
+
+	if len(*topic_report_file) > 0 {
+		labels := make([]string, len(times))
+		for i, t := range times {
+			labels[i] = strconv.Itoa64(t)
+		}
+		if err := SaveTopicReport(*topic_report_file, labels, models); err != nil {
+			fmt.Printf("Cannot save topic report due to " + err.String())
+			return nil
+		}
+	}
+	return previous
+}
+
+// Save the top words of every topic in models labeled by labels, e.g.,
+// over time or by language, in the format of
+//
+// topic 0
+//	label_0	top_word_1 top_word_2 ...
+//	label_1	top_word_1 top_word_2 ...
+// topic 1
+// ...
+func SaveTopicReport(filename string, labels []string, models []*lda.Model) os.Error {
+	report := ""
+	for k := 0; k < *num_topics; k++ {
+		report += fmt.Sprintf("topic %d\n", k)
+		for i, model := range models {
+			report += fmt.Sprintf("\t%s\t", labels[i])
+			for j, w := range model.TopWords(k, *num_report_words) {
+				if j > 0 {
+					report += " "
+				}
+				report += w.Word
+			}
+			report += "\n"
+		}
+	}
+	return lda.SaveFile(filename, report)
+}
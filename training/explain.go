@@ -0,0 +1,23 @@
+package main
+
+import (
+	"lda"
+	"os"
+)
+
+// Save the final topics of words of the documents in corpus, in their
+// original order, with their posteriors, into explanation_file in
+// explanation_format.  sampler must hold the final state of training,
+// i.e., its model counts include corpus.
+func SaveTrainingExplanations(sampler *lda.Sampler, corpus *lda.Corpus) os.Error {
+	explanations := make([][]lda.TokenTopic, len(*corpus))
+	for d, doc := range *corpus {
+		explanations[d] = sampler.TokenTopics(doc, true)
+	}
+	text, err := lda.FormatExplanations(explanations, *explanation_format, nil)
+	if err != nil {
+		return err
+	}
+
+	return lda.SaveFile(*explanation_file, text)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"lda"
+	"os"
+)
+
+// Load corpus_file.  If phrase_file is specified, detect phrases in it,
+// save them into phrase_file, and merge them in the documents.
+func LoadTrainingCorpus() (*lda.Corpus, os.Error) {
+	if len(*phrase_file) == 0 {
+		return lda.LoadCorpus(*corpus_file, *num_topics)
+	}
+	texts, err := lda.LoadShortTexts(*corpus_file)
+	if err != nil {
+		return nil, err
+	}
+	table, err := lda.DetectPhrases(texts, *phrase_score, *phrase_min_count, *phrase_threshold)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("%d phrases detected\n", table.NumPhrases())
+	if err := table.SavePhraseTable(*phrase_file); err != nil {
+		return nil, err
+	}
+	return lda.LoadPhrasedCorpus(*corpus_file, *num_topics, table)
+}
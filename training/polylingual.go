@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"lda"
+	"strings"
+)
+
+// Train a polylingual topic model by collapsed Gibbs sampling.  Saves the
+// model of each language and the topic report, and returns the model of
+// the first language, or nil on errors.
+func TrainPolylingual() *lda.Model {
+	language_names := strings.Split(*languages, ",", -1)
+	for i, name := range language_names {
+		language_names[i] = strings.TrimSpace(name)
+		if len(language_names[i]) == 0 {
+			fmt.Printf("Empty language name in: %s\n", *languages)
+			return nil
+		}
+	}
+	corpus, err := lda.LoadPolylingualCorpus(*corpus_file, len(language_names), *num_topics)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(nil)
+	}
+
+	models := make([]*lda.Model, len(language_names))
+	accum_models := make([]*lda.Model, len(language_names))
+	for l := range language_names {
+		models[l] = lda.CreateModel(*num_topics, corpus.LanguageCorpus(l))
+		accum_models[l] = lda.NewModel(*num_topics)
+	}
+	sampler := lda.NewPolylingualSampler(*topic_prior, *word_prior, models, accum_models)
+
+	for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
+		fmt.Printf("Iteration %d ... ", iter)
+		if (*compute_loglikelihood) {
+			fmt.Printf("log-likelihood: %f\n", sampler.CorpusLogLikelihood(corpus))
+		} else {
+			fmt.Printf("\n")
+		}
+		sampler.CorpusGibbsSampling(corpus, iter < *burn_in_iterations)
+	}
+
+	for l, name := range language_names {
+		averageAccumulatedModel(accum_models[l])
+		if err := accum_models[l].SaveModel(*model_file + "." + name); err != nil {
+			fmt.Printf("Cannot save model due to " + err.String())
+			return nil
+		}
+	}
+
+	if len(*topic_report_file) > 0 {
+		if err := SaveTopicReport(*topic_report_file, language_names, accum_models); err != nil {
+			fmt.Printf("Cannot save topic report due to " + err.String())
+			return nil
+		}
+	}
+	return accum_models[0]
+}
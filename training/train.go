@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"lda"
+	"math"
+	"os"
 	"rand"
 	"time"
 )
@@ -20,6 +22,134 @@ var (
 		"The number of Gibbs sampling iterations for accumulating the sampling results")
         compute_loglikelihood = flag.Bool("compute_loglikelihood", true,
 		"Whether to compute and output the likelihood after each Gibbs sampling iteration")
+	algorithm = flag.String("algorithm", "gibbs",
+		"The training algorithm: gibbs (collapsed Gibbs sampling), online_vb " +
+		"(online variational Bayes), vem (batch variational EM), hdp (Hierarchical " +
+		"Dirichlet Process, inferring the number of topics from the initial num_topics) or " +
+		"btm (biterm topic model, for short texts).  Topics of texts of a btm model are " +
+		"inferred from their biterms, e.g., by lda-server --model_type=btm, not by fold-in " +
+		"Gibbs sampling as by other commands")
+	batch_size = flag.Int("batch_size", 256,
+		"The number of documents in a mini-batch of online_vb")
+	tau0 = flag.Float64("tau0", 1.0,
+		"The online_vb parameter down-weighting early mini-batches")
+	kappa = flag.Float64("kappa", 0.7,
+		"The online_vb forgetting rate of old mini-batches, in (0.5, 1]")
+	passes = flag.Int("passes", 1, "The number of online_vb passes over the corpus")
+	em_iterations = flag.Int("em_iterations", 100, "The maximum number of vem iterations")
+	em_convergence = flag.Float64("em_convergence", 1e-4,
+		"vem stops when the relative change of the ELBO falls below this threshold")
+	estimate_alpha = flag.Bool("estimate_alpha", true,
+		"Whether vem estimates the topic prior, starting from topic_prior")
+	hdp_alpha = flag.Float64("hdp_alpha", 1.0,
+		"The hdp concentration of topic distributions of documents around the global one")
+	hdp_gamma = flag.Float64("hdp_gamma", 1.0,
+		"The hdp concentration of the global topic distribution; larger values create more topics")
+	biterm_window = flag.Int("biterm_window", 15,
+		"The btm maximum distance between the words of a biterm; 0 pairs all words of a text")
+	phrase_file = flag.String("phrase_file", "",
+		"If specified, detect phrases (collocations of 2 or 3 words) in corpus_file, merge them " +
+		"into single tokens like new_york, and save the phrase table into this (output) file " +
+		"for inference.  Requires algorithm gibbs, online_vb, vem or hdp on an unlabeled corpus")
+	phrase_score = flag.String("phrase_score", "pmi",
+		"The score of phrases: pmi (pointwise mutual information) or llr (log-likelihood ratio)")
+	phrase_min_count = flag.Int("phrase_min_count", 5,
+		"The minimum number of occurrences of a phrase")
+	phrase_threshold = flag.Float64("phrase_threshold", 3.0,
+		"The minimum phrase_score of a phrase, e.g., 3 for pmi or 10.83 (p < 0.001) for llr")
+	explanation_file = flag.String("explanation_file", "",
+		"If specified, the (output) final topic of every token of corpus_file, in its original " +
+		"order, with its posterior distribution over topics.  Requires algorithm gibbs on an " +
+		"unlabeled corpus")
+	explanation_format = flag.String("explanation_format", "json",
+		"The format of explanation_file: json (an array of tokens per document), ansi (words " +
+		"colored by topics) or html (words highlighted by topics)")
+	diagnostics_file = flag.String("diagnostics_file", "",
+		"If specified, the (output) diagnostics of every topic, e.g., tokens, document entropy, " +
+		"coherence and exclusivity, computed from the final state of training.  Requires " +
+		"algorithm gibbs without a corpus with metadata other than labels")
+	diagnostics_format = flag.String("diagnostics_format", "xml",
+		"The format of diagnostics_file: xml (as by MALLET) or json (an object per topic)")
+	diagnostics_top_words = flag.Int("diagnostics_top_words", 20,
+		"The number of top words per topic in diagnostics_file, over which word length, " +
+		"coherence and exclusivity are computed")
+	labeled_corpus = flag.Bool("labeled_corpus", false,
+		"Whether corpus_file is labeled for Labeled LDA, i.e., each line is a comma separated " +
+		"list of labels, a tab, and the text.  Each label is a topic, and num_topics is ignored.  " +
+		"Topics are named by labels in model_file.topic_names")
+	num_latent_topics = flag.Int("num_latent_topics", 0,
+		"With labeled_corpus, the number of latent topics shared by all documents")
+	init_model_file = flag.String("init_model_file", "",
+		"If specified, incrementally update this (input) model with the documents in corpus_file, " +
+		"instead of training from scratch.  num_topics is taken from this model")
+	decay = flag.Float64("decay", 1.0,
+		"In incremental training, the factor in (0, 1] by which counts in init_model_file are " +
+		"scaled before adding the new documents, so that old topics can drift")
+	old_corpus_file = flag.String("old_corpus_file", "",
+		"In incremental training, the training data of init_model_file, sampled by " +
+		"old_corpus_sample_rate and re-sampled along with the new documents.  The sampled " +
+		"documents take the place of their counts in the (decayed) model, and reinforce old " +
+		"topics as they are not decayed")
+	old_corpus_sample_rate = flag.Float64("old_corpus_sample_rate", 0.0,
+		"In incremental training, the fraction of documents in old_corpus_file to re-sample")
+	seed_file = flag.String("seed_file", "",
+		"If specified, guide topics by seed words in this file, each line of which is a topic " +
+		"id followed by its seed words.  Requires algorithm gibbs")
+	seed_boost = flag.Float64("seed_boost", 1.0,
+		"The amount added to word_prior for seed words in their topics")
+	seed_initialize = flag.Bool("seed_initialize", true,
+		"Whether occurrences of seed words start in their seed topics")
+	author_corpus = flag.Bool("author_corpus", false,
+		"Whether to train the author-topic model, where each line of corpus_file is a comma " +
+		"separated list of author IDs, a tab, and the text.  Requires algorithm gibbs")
+	author_model_file = flag.String("author_model_file", "",
+		"With author_corpus, the (output) author-topic model file, in the format of model_file " +
+		"with author IDs in place of words")
+	response_corpus = flag.Bool("response_corpus", false,
+		"Whether to train supervised LDA, where each line of corpus_file is a response, a tab, " +
+		"and the text.  Requires algorithm gibbs")
+	response_type = flag.String("response_type", "gaussian",
+		"With response_corpus, the type of responses: gaussian (real numbers) or binary (0 or 1)")
+	response_model_file = flag.String("response_model_file", "",
+		"With response_corpus, the (output) file of the model predicting responses from topics")
+	response_regularizer = flag.Float64("response_regularizer", 0.01,
+		"With response_corpus, the L2 regularizer of the weights of the response model")
+	dmr_corpus = flag.Bool("dmr_corpus", false,
+		"Whether to train a Dirichlet-multinomial regression (DMR) model, where each line of " +
+		"corpus_file is a comma separated list of features (name or name:value), a tab, and the " +
+		"text, and the features of a document shift its topic prior.  Requires algorithm gibbs")
+	dmr_variance = flag.Float64("dmr_variance", 1.0,
+		"With dmr_corpus, the variance of the Gaussian prior on feature weights")
+	dmr_optimize_interval = flag.Int("dmr_optimize_interval", 10,
+		"With dmr_corpus, the number of Gibbs sampling iterations between optimizations of " +
+		"feature weights")
+	covariate_report_file = flag.String("covariate_report_file", "",
+		"With dmr_corpus, the (output) file reporting the weights of features by topic")
+	polylingual_corpus = flag.Bool("polylingual_corpus", false,
+		"Whether to train a polylingual topic model, where each line of corpus_file has the " +
+		"aligned texts of a document in languages, separated by tabs; an empty text means the " +
+		"document is missing in that language.  A model per language is saved into " +
+		"model_file.<language>; model_file gets the model of the first language.  Requires " +
+		"algorithm gibbs")
+	languages = flag.String("languages", "",
+		"With polylingual_corpus, the comma separated names of languages, in the order of texts " +
+		"in corpus_file")
+	time_sliced_corpus = flag.Bool("time_sliced_corpus", false,
+		"Whether to train a dynamic topic model, where each line of corpus_file is a time, a tab, " +
+		"and the text.  A model is trained per time slice, with its word prior drawn from the " +
+		"model of the previous slice, and saved into model_file.<time>; model_file gets the " +
+		"model of the last slice.  Requires algorithm gibbs")
+	slice_seconds = flag.Int64("slice_seconds", 0,
+		"With time_sliced_corpus, the length of time slices if times are Unix timestamps, or 0 " +
+		"if times are indices of time slices")
+	slice_prior_strength = flag.Float64("slice_prior_strength", 100,
+		"With time_sliced_corpus, the total word prior of a topic drawn from the previous slice; " +
+		"larger values make topics drift slower")
+	topic_report_file = flag.String("topic_report_file", "",
+		"With time_sliced_corpus or polylingual_corpus, the (output) file reporting top words of " +
+		"topics over time or by language")
+	num_report_words = flag.Int("num_report_words", 10,
+		"The number of top words per topic and time slice or language in topic_report_file")
 )
 
 func CheckFlagsValid() bool {
@@ -52,6 +182,189 @@ func CheckFlagsValid() bool {
 		fmt.Println("accumulate_iterations must be positive")
 		valid = false
 	}
+	if *algorithm != "gibbs" && *algorithm != "online_vb" && *algorithm != "vem" &&
+		*algorithm != "hdp" && *algorithm != "btm" {
+		fmt.Println("algorithm must be gibbs, online_vb, vem, hdp or btm")
+		valid = false
+	}
+	if *biterm_window < 0 {
+		fmt.Println("biterm_window must be non-negative")
+		valid = false
+	}
+	if *hdp_alpha <= 0 || *hdp_gamma <= 0 {
+		fmt.Println("hdp_alpha and hdp_gamma must be positive")
+		valid = false
+	}
+	if *batch_size <= 0 {
+		fmt.Println("batch_size must be positive")
+		valid = false
+	}
+	if *tau0 < 0 {
+		fmt.Println("tau0 must be non-negative")
+		valid = false
+	}
+	if *kappa <= 0.5 || *kappa > 1 {
+		fmt.Println("kappa must be in (0.5, 1]")
+		valid = false
+	}
+	if *passes <= 0 {
+		fmt.Println("passes must be positive")
+		valid = false
+	}
+	if *em_iterations <= 0 {
+		fmt.Println("em_iterations must be positive")
+		valid = false
+	}
+	if *em_convergence < 0 {
+		fmt.Println("em_convergence must be non-negative")
+		valid = false
+	}
+	if *decay <= 0 || *decay > 1 {
+		fmt.Println("decay must be in (0, 1]")
+		valid = false
+	}
+	if *old_corpus_sample_rate < 0 || *old_corpus_sample_rate > 1 {
+		fmt.Println("old_corpus_sample_rate must be in [0, 1]")
+		valid = false
+	}
+	if len(*init_model_file) == 0 &&
+		(*decay != 1.0 || len(*old_corpus_file) > 0) {
+		fmt.Println("decay and old_corpus_file require init_model_file")
+		valid = false
+	}
+	if len(*init_model_file) > 0 && *algorithm != "gibbs" {
+		fmt.Println("init_model_file requires algorithm gibbs")
+		valid = false
+	}
+	if *labeled_corpus && (*algorithm != "gibbs" || len(*init_model_file) > 0) {
+		fmt.Println("labeled_corpus requires algorithm gibbs without init_model_file")
+		valid = false
+	}
+	if len(*phrase_file) > 0 && (*algorithm == "btm" || len(*init_model_file) > 0 ||
+		*labeled_corpus || *author_corpus || *response_corpus || *dmr_corpus ||
+		*polylingual_corpus || *time_sliced_corpus) {
+		fmt.Println("phrase_file requires algorithm gibbs, online_vb, vem or hdp without " +
+			"init_model_file or a corpus with metadata")
+		valid = false
+	}
+	if *phrase_score != "pmi" && *phrase_score != "llr" {
+		fmt.Println("phrase_score must be pmi or llr")
+		valid = false
+	}
+	if *phrase_min_count <= 0 {
+		fmt.Println("phrase_min_count must be positive")
+		valid = false
+	}
+	if len(*explanation_file) > 0 && (*algorithm != "gibbs" || *labeled_corpus ||
+		*author_corpus || *response_corpus || *dmr_corpus || *polylingual_corpus ||
+		*time_sliced_corpus) {
+		fmt.Println("explanation_file requires algorithm gibbs without a corpus with metadata")
+		valid = false
+	}
+	if *explanation_format != "json" && *explanation_format != "ansi" &&
+		*explanation_format != "html" {
+		fmt.Println("explanation_format must be json, ansi or html")
+		valid = false
+	}
+	if len(*diagnostics_file) > 0 && (*algorithm != "gibbs" || *author_corpus ||
+		*response_corpus || *dmr_corpus || *polylingual_corpus || *time_sliced_corpus) {
+		fmt.Println("diagnostics_file requires algorithm gibbs without a corpus with metadata " +
+			"other than labels")
+		valid = false
+	}
+	if *diagnostics_format != "xml" && *diagnostics_format != "json" {
+		fmt.Println("diagnostics_format must be xml or json")
+		valid = false
+	}
+	if *diagnostics_top_words <= 0 {
+		fmt.Println("diagnostics_top_words must be positive")
+		valid = false
+	}
+	if *num_latent_topics < 0 {
+		fmt.Println("num_latent_topics must be non-negative")
+		valid = false
+	}
+	if len(*seed_file) > 0 && *algorithm != "gibbs" {
+		fmt.Println("seed_file requires algorithm gibbs")
+		valid = false
+	}
+	if *author_corpus && (*algorithm != "gibbs" || len(*init_model_file) > 0 ||
+		*labeled_corpus || len(*seed_file) > 0) {
+		fmt.Println("author_corpus requires algorithm gibbs without init_model_file, " +
+			"labeled_corpus or seed_file")
+		valid = false
+	}
+	if *author_corpus != (len(*author_model_file) > 0) {
+		fmt.Println("author_corpus and author_model_file must be specified together")
+		valid = false
+	}
+	if *response_corpus && (*algorithm != "gibbs" || len(*init_model_file) > 0 ||
+		*labeled_corpus || *author_corpus) {
+		fmt.Println("response_corpus requires algorithm gibbs without init_model_file, " +
+			"labeled_corpus or author_corpus")
+		valid = false
+	}
+	if *response_corpus != (len(*response_model_file) > 0) {
+		fmt.Println("response_corpus and response_model_file must be specified together")
+		valid = false
+	}
+	if *response_type != "gaussian" && *response_type != "binary" {
+		fmt.Println("response_type must be gaussian or binary")
+		valid = false
+	}
+	if *response_regularizer <= 0 {
+		fmt.Println("response_regularizer must be positive")
+		valid = false
+	}
+	if *dmr_corpus && (*algorithm != "gibbs" || len(*init_model_file) > 0 ||
+		*labeled_corpus || *author_corpus || *response_corpus) {
+		fmt.Println("dmr_corpus requires algorithm gibbs without init_model_file, " +
+			"labeled_corpus, author_corpus or response_corpus")
+		valid = false
+	}
+	if *dmr_variance <= 0 {
+		fmt.Println("dmr_variance must be positive")
+		valid = false
+	}
+	if *dmr_optimize_interval <= 0 {
+		fmt.Println("dmr_optimize_interval must be positive")
+		valid = false
+	}
+	if *polylingual_corpus && (*algorithm != "gibbs" || len(*init_model_file) > 0 ||
+		*labeled_corpus || len(*seed_file) > 0 || *author_corpus || *response_corpus ||
+		*dmr_corpus) {
+		fmt.Println("polylingual_corpus requires algorithm gibbs without init_model_file, " +
+			"labeled_corpus, seed_file, author_corpus, response_corpus or dmr_corpus")
+		valid = false
+	}
+	if *polylingual_corpus != (len(*languages) > 0) {
+		fmt.Println("polylingual_corpus and languages must be specified together")
+		valid = false
+	}
+	if *time_sliced_corpus && (*algorithm != "gibbs" || len(*init_model_file) > 0 ||
+		*labeled_corpus || len(*seed_file) > 0 || *author_corpus || *response_corpus ||
+		*dmr_corpus || *polylingual_corpus) {
+		fmt.Println("time_sliced_corpus requires algorithm gibbs without init_model_file, " +
+			"labeled_corpus, seed_file, author_corpus, response_corpus, dmr_corpus or " +
+			"polylingual_corpus")
+		valid = false
+	}
+	if *slice_seconds < 0 {
+		fmt.Println("slice_seconds must be non-negative")
+		valid = false
+	}
+	if *slice_prior_strength < 0 {
+		fmt.Println("slice_prior_strength must be non-negative")
+		valid = false
+	}
+	if *num_report_words <= 0 {
+		fmt.Println("num_report_words must be positive")
+		valid = false
+	}
+	if *seed_boost < 0 {
+		fmt.Println("seed_boost must be non-negative")
+		valid = false
+	}
 	return valid
 }
 
@@ -64,15 +377,129 @@ func main() {
 
 	rand.Seed(time.Nanoseconds())
 
-	corpus, err := lda.LoadCorpus(*corpus_file, 2)
+	var model *lda.Model
+	switch {
+	case *author_corpus:
+		model = TrainAuthorTopic()
+	case *time_sliced_corpus:
+		model = TrainDynamic()
+	case *response_corpus:
+		model = TrainSupervised()
+	case *dmr_corpus:
+		model = TrainDMR()
+	case *polylingual_corpus:
+		model = TrainPolylingual()
+	case *algorithm == "gibbs":
+		model = TrainGibbs()
+	case *algorithm == "online_vb":
+		model = TrainOnlineVB()
+	case *algorithm == "vem":
+		model = TrainVEM()
+	case *algorithm == "hdp":
+		model = TrainHDP()
+	case *algorithm == "btm":
+		model = TrainBTM()
+	}
+	if model == nil {
+		return
+	}
+
+	if err := model.SaveModel(*model_file); err != nil {
+		fmt.Printf("Cannot save model due to " + err.String())
+	}
+
+	return
+}
+
+// Average the counts of accum_model, accumulated over
+// accumulate_iterations, so that saved models are on the scale of their
+// training data, and any of them can be updated incrementally as
+// init_model_file.
+func averageAccumulatedModel(accum_model *lda.Model) {
+	accum_model.Scale(1.0 / float64(*accumulate_iterations))
+}
+
+// Train a model by collapsed Gibbs sampling, from scratch or
+// incrementally from init_model_file.  Returns nil on errors.
+func TrainGibbs() *lda.Model {
+	var model *lda.Model
+	if len(*init_model_file) > 0 {
+		var err os.Error
+		if model, err = lda.LoadModel(*init_model_file); err != nil {
+			fmt.Printf("Error in loading: " + *init_model_file + ", due to " + err.String())
+			return nil
+		}
+		*num_topics = model.NumTopics()
+		model.Decay(*decay, nil)
+	}
+
+	var corpus *lda.Corpus
+	var topic_names []string
+	var err os.Error
+	if *labeled_corpus {
+		corpus, topic_names, err = lda.LoadLabeledCorpus(*corpus_file, *num_latent_topics)
+		*num_topics = len(topic_names)
+	} else {
+		corpus, err = LoadTrainingCorpus()
+	}
 	if err != nil {
 		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
-		return
+		return nil
 	}
+	num_docs := len(*corpus) // Not counting documents of old_corpus_file.
 
-	model := lda.CreateModel(*num_topics, corpus)
+	var seeds lda.SeedWords
+	if len(*seed_file) > 0 {
+		if seeds, err = lda.LoadSeedWords(*seed_file, *num_topics); err != nil {
+			fmt.Printf("Error in loading: " + *seed_file + ", due to " + err.String())
+			return nil
+		}
+	}
+
+	if model == nil {
+		if seeds != nil && *seed_initialize {
+			seeds.InitializeTopics(corpus, nil)
+		}
+		model = lda.CreateModel(*num_topics, corpus)
+	} else {
+		// Only the new documents (and a sample of the old ones) are
+		// sampled; they are added into the model counts with random
+		// topics, and the Gibbs sampler moves them to where they fit.
+		// The old ones are first removed from the counts, which already
+		// include them.
+		if len(*old_corpus_file) > 0 && *old_corpus_sample_rate > 0 {
+			old_corpus, err := lda.LoadCorpus(*old_corpus_file, *num_topics)
+			if err != nil {
+				fmt.Printf("Error in loading: " + *old_corpus_file + ", due to " + err.String())
+				return nil
+			}
+			for _, doc := range *old_corpus {
+				if rand.Float64() < *old_corpus_sample_rate {
+					model.RemoveDocument(doc, nil)
+					*corpus = append(*corpus, doc)
+				}
+			}
+		}
+		for _, doc := range *corpus {
+			doc.RandomizeTopics(nil)
+		}
+		if seeds != nil && *seed_initialize {
+			seeds.InitializeTopics(corpus, nil)
+		}
+		model.AddCorpus(corpus)
+	}
+	if topic_names == nil {
+		// Topics of init_model_file keep their names.
+		topic_names = model.TopicNames()
+	}
 	accum_model := lda.NewModel(*num_topics)
+	if topic_names != nil {
+		accum_model.SetTopicNames(topic_names)
+	}
 	sampler := lda.NewSampler(*topic_prior, *word_prior, model, accum_model)
+	if seeds != nil {
+		sampler.SetWordPrior(seeds.WordPrior(*word_prior, *seed_boost, *num_topics))
+	}
 
 	for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
 		fmt.Printf("Iteration %d ... ", iter)
@@ -83,11 +510,220 @@ func main() {
 		}
 		sampler.CorpusGibbsSampling(corpus, true, iter < *burn_in_iterations)
 	}
+	if len(*explanation_file) > 0 {
+		docs := (*corpus)[0:num_docs]
+		if err := SaveTrainingExplanations(sampler, &docs); err != nil {
+			fmt.Printf("Cannot save explanations due to " + err.String())
+			return nil
+		}
+	}
+	if len(*diagnostics_file) > 0 {
+		if err := SaveTrainingDiagnostics(model, corpus, topic_names); err != nil {
+			fmt.Printf("Cannot save diagnostics due to " + err.String())
+			return nil
+		}
+	}
 
-	if err := accum_model.SaveModel(*model_file); err != nil {
-		fmt.Printf("Cannot save model due to " + err.String())
+	averageAccumulatedModel(accum_model)
+	return accum_model
+}
+
+// Train the author-topic model by collapsed Gibbs sampling, and save the
+// author-topic counts into author_model_file.  Returns the word-topic
+// model, or nil on errors.
+func TrainAuthorTopic() *lda.Model {
+	corpus, err := lda.LoadAuthorCorpus(*corpus_file, *num_topics)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+	for _, doc := range *corpus {
+		doc.RandomizeAssignments(nil)
 	}
 
-	return
+	model := lda.CreateModel(*num_topics, corpus.Documents())
+	author_model := lda.CreateAuthorModel(*num_topics, corpus)
+	accum_model := lda.NewModel(*num_topics)
+	accum_author_model := lda.NewModel(*num_topics)
+	sampler := lda.NewAuthorTopicSampler(*topic_prior, *word_prior,
+		model, author_model, accum_model, accum_author_model)
+
+	for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
+		fmt.Printf("Iteration %d ... ", iter)
+		if (*compute_loglikelihood) {
+			fmt.Printf("log-likelihood: %f\n", sampler.CorpusLogLikelihood(corpus))
+		} else {
+			fmt.Printf("\n")
+		}
+		sampler.CorpusGibbsSampling(corpus, iter < *burn_in_iterations)
+	}
+
+	averageAccumulatedModel(accum_model)
+	averageAccumulatedModel(accum_author_model)
+	if err := accum_author_model.SaveModel(*author_model_file); err != nil {
+		fmt.Printf("Cannot save author model due to " + err.String())
+		return nil
+	}
+	return accum_model
 }
 
+// Train supervised LDA by collapsed Gibbs sampling, with the response
+// model refit after every iteration, and save the final response model
+// into response_model_file.  Returns the average of the models
+// accumulated after burn-in, or nil on errors.
+func TrainSupervised() *lda.Model {
+	corpus, responses, err := lda.LoadResponseCorpus(*corpus_file, *num_topics)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(nil)
+	}
+
+	model := lda.CreateModel(*num_topics, corpus)
+	accum_model := lda.NewModel(*num_topics)
+	sampler := lda.NewSampler(*topic_prior, *word_prior, model, accum_model)
+	if len(*seed_file) > 0 {
+		seeds, err := lda.LoadSeedWords(*seed_file, *num_topics)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *seed_file + ", due to " + err.String())
+			return nil
+		}
+		sampler.SetWordPrior(seeds.WordPrior(*word_prior, *seed_boost, *num_topics))
+	}
+	response, err := lda.NewResponseModel(*response_type, *num_topics)
+	if err != nil {
+		fmt.Printf("Cannot create response model due to " + err.String())
+		return nil
+	}
+	if err := response.CheckResponses(responses); err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+	supervised := lda.NewSupervisedSampler(sampler, response, corpus, responses,
+		*response_regularizer)
+
+	for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
+		fmt.Printf("Iteration %d ... ", iter)
+		if (*compute_loglikelihood) {
+			fmt.Printf("log-likelihood: %f, ", sampler.CorpusLogLikelihood(corpus))
+		}
+		supervised.CorpusGibbsSampling(iter < *burn_in_iterations)
+		fmt.Printf("training error: %f\n", supervised.TrainingError())
+	}
+
+	if err := response.SaveResponseModel(*response_model_file); err != nil {
+		fmt.Printf("Cannot save response model due to " + err.String())
+		return nil
+	}
+	averageAccumulatedModel(accum_model)
+	return accum_model
+}
+
+// Train a model by online variational Bayes, processing the corpus in
+// mini-batches of batch_size documents, in a random order in each pass.
+// Returns nil on errors.
+func TrainOnlineVB() *lda.Model {
+	corpus, err := LoadTrainingCorpus()
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+
+	trainer := lda.NewOnlineVBTrainer(*num_topics, *topic_prior, *word_prior,
+		*tau0, *kappa, len(*corpus))
+	for pass := 0; pass < *passes; pass++ {
+		order := rand.Perm(len(*corpus))
+		for start := 0; start < len(order); start += *batch_size {
+			end := start + *batch_size
+			if end > len(order) {
+				end = len(order)
+			}
+			batch := make([]*lda.Document, end-start)
+			for i := range batch {
+				batch[i] = (*corpus)[order[start+i]]
+			}
+			rho := trainer.Update(batch)
+			fmt.Printf("Pass %d, documents %d-%d ... rho: %f\n", pass, start, end-1, rho)
+		}
+	}
+	return trainer.Model()
+}
+
+// Train a model by batch variational EM, until the relative change of
+// the ELBO falls below em_convergence or after em_iterations
+// iterations.  Returns nil on errors.
+func TrainVEM() *lda.Model {
+	corpus, err := LoadTrainingCorpus()
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+
+	trainer := lda.NewVEMTrainer(*num_topics, *topic_prior, *estimate_alpha)
+	previous_elbo := 0.0
+	for iter := 0; iter < *em_iterations; iter++ {
+		elbo := trainer.Iterate(corpus)
+		fmt.Printf("Iteration %d ... ELBO: %f, alpha: %f\n", iter, elbo, trainer.Alpha())
+		if iter > 0 && math.Fabs((previous_elbo-elbo)/previous_elbo) < *em_convergence {
+			break
+		}
+		previous_elbo = elbo
+	}
+	return trainer.Model()
+}
+
+// Train an HDP topic model by direct assignment Gibbs sampling, starting
+// from num_topics random topics.  As the number of topics changes during
+// sampling, samples are not accumulated; returns the model of the last
+// sample, or nil on errors.
+func TrainHDP() *lda.Model {
+	corpus, err := LoadTrainingCorpus()
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+	for _, doc := range *corpus {
+		doc.RandomizeTopics(nil)
+	}
+
+	sampler := lda.NewHDPSampler(*hdp_alpha, *hdp_gamma, *word_prior, corpus)
+	for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
+		fmt.Printf("Iteration %d ... ", iter)
+		if (*compute_loglikelihood) {
+			fmt.Printf("log-likelihood: %f, ", sampler.CorpusLogLikelihood())
+		}
+		sampler.Iterate()
+		fmt.Printf("num_topics: %d\n", sampler.NumTopics())
+	}
+	return sampler.Model()
+}
+
+// Train a biterm topic model of the short texts in corpus_file by
+// collapsed Gibbs sampling.  Returns the average of the models
+// accumulated after burn-in, or nil on errors.
+func TrainBTM() *lda.Model {
+	texts, err := lda.LoadShortTexts(*corpus_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return nil
+	}
+
+	accum_model := lda.NewModel(*num_topics)
+	sampler := lda.NewBitermSampler(*num_topics, *topic_prior, *word_prior,
+		texts, *biterm_window, accum_model, nil)
+	fmt.Printf("%d biterms in %d texts\n", sampler.NumBiterms(), len(texts))
+	for iter := 0; iter < *burn_in_iterations + *accumulate_iterations; iter++ {
+		fmt.Printf("Iteration %d ... ", iter)
+		if (*compute_loglikelihood) {
+			fmt.Printf("log-likelihood: %f\n", sampler.BitermLogLikelihood())
+		} else {
+			fmt.Printf("\n")
+		}
+		sampler.Iterate(iter < *burn_in_iterations)
+	}
+
+	averageAccumulatedModel(accum_model)
+	return accum_model
+}
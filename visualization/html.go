@@ -0,0 +1,143 @@
+package main
+
+// Replaced by the title and by the LDAvis payload in kHTMLTemplate.
+const (
+	kHTMLTitlePlaceholder = "{{TITLE}}"
+	kHTMLDataPlaceholder  = "{{DATA}}"
+)
+
+// A self-contained HTML page rendering the LDAvis payload with plain SVG,
+// so that it opens offline.  Topics are circles at their MDS positions,
+// sized by their frequency; clicking a topic shows its terms ranked by
+// relevance, lambda log P(term|topic) + (1 - lambda) log lift, with
+// lambda set by the slider.  Without a selected topic, the most salient
+// terms are shown.
+const kHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{TITLE}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+#main { display: flex; }
+svg { border: 1px solid #ddd; margin-right: 20px; }
+.topic { fill: #1f77b4; fill-opacity: 0.4; stroke: #1f77b4; cursor: pointer; }
+.topic.selected { fill: #d62728; stroke: #d62728; }
+.label { font-size: 12px; text-anchor: middle; pointer-events: none; }
+.axis { stroke: #ccc; }
+.term { font-size: 12px; text-anchor: end; }
+.total { fill: #aec7e8; }
+.freq { fill: #d62728; }
+</style>
+</head>
+<body>
+<h2>{{TITLE}}</h2>
+<p>Selected topic: <span id="topic"></span>
+&nbsp; &lambda; = <input type="range" id="lambda" min="0" max="1" step="0.01" value="1">
+<span id="lambda-value">1.00</span></p>
+<div id="main">
+<svg id="topics" width="500" height="500"></svg>
+<svg id="terms" width="520" height="500"></svg>
+</div>
+<script>
+var data = {{DATA}};
+(function() {
+  var SVG = "http://www.w3.org/2000/svg";
+  var mds = data.mdsDat, tinfo = data.tinfo, R = data.R;
+  var selected = 0, lambda = 1, circles = [];
+
+  function element(name, attributes, parent) {
+    var e = document.createElementNS(SVG, name);
+    for (var a in attributes) {
+      e.setAttribute(a, attributes[a]);
+    }
+    parent.appendChild(e);
+    return e;
+  }
+  function range(values) {
+    var low = Math.min.apply(null, values), high = Math.max.apply(null, values);
+    if (high - low < 1e-12) {
+      low -= 1;
+      high += 1;
+    }
+    return [low, high];
+  }
+
+  var topics = document.getElementById("topics");
+  var size = 500, margin = 50;
+  var x_range = range(mds.x), y_range = range(mds.y);
+  function scaleX(x) { return margin + (x - x_range[0]) / (x_range[1] - x_range[0]) * (size - 2 * margin); }
+  function scaleY(y) { return size - margin - (y - y_range[0]) / (y_range[1] - y_range[0]) * (size - 2 * margin); }
+  element("line", {x1: 0, y1: size / 2, x2: size, y2: size / 2, "class": "axis"}, topics);
+  element("line", {x1: size / 2, y1: 0, x2: size / 2, y2: size, "class": "axis"}, topics);
+  element("text", {x: size - 5, y: size / 2 - 5, "class": "term"}, topics).textContent = data["plot.opts"].xlab;
+  element("text", {x: size / 2 + 30, y: 15, "class": "term"}, topics).textContent = data["plot.opts"].ylab;
+  var max_freq = Math.max.apply(null, mds.Freq);
+  for (var i = 0; i < mds.topics.length; i++) {
+    var circle = element("circle", {cx: scaleX(mds.x[i]), cy: scaleY(mds.y[i]),
+        r: 5 + 35 * Math.sqrt(mds.Freq[i] / max_freq), "class": "topic"}, topics);
+    element("text", {x: scaleX(mds.x[i]), y: scaleY(mds.y[i]) + 4, "class": "label"}, topics)
+        .textContent = mds.topics[i];
+    circle.onclick = (function(topic) {
+      return function() { select(selected == topic ? 0 : topic); };
+    })(mds.topics[i]);
+    circles.push(circle);
+  }
+
+  function relevance(i) {
+    return lambda * tinfo.logprob[i] + (1 - lambda) * tinfo.loglift[i];
+  }
+  function drawTerms() {
+    var svg = document.getElementById("terms");
+    while (svg.firstChild) {
+      svg.removeChild(svg.firstChild);
+    }
+    var category = selected == 0 ? "Default" : "Topic" + selected;
+    var rows = [];
+    for (var i = 0; i < tinfo.Term.length; i++) {
+      if (tinfo.Category[i] == category) {
+        rows.push(i);
+      }
+    }
+    if (selected != 0) {
+      rows.sort(function(a, b) { return relevance(b) - relevance(a); });
+    }
+    rows = rows.slice(0, R);
+    var max_total = 0;
+    for (var j = 0; j < rows.length; j++) {
+      max_total = Math.max(max_total, tinfo.Total[rows[j]]);
+    }
+    var bar = 16, left = 140, width = 360;
+    svg.setAttribute("height", Math.max(500, rows.length * bar + 20));
+    for (var j = 0; j < rows.length; j++) {
+      var row = rows[j], y = 10 + j * bar;
+      element("text", {x: left - 5, y: y + 11, "class": "term"}, svg).textContent = tinfo.Term[row];
+      element("rect", {x: left, y: y, width: width * tinfo.Total[row] / max_total,
+          height: bar - 3, "class": "total"}, svg);
+      if (selected != 0) {
+        element("rect", {x: left, y: y, width: width * tinfo.Freq[row] / max_total,
+            height: bar - 3, "class": "freq"}, svg);
+      }
+    }
+  }
+  function select(topic) {
+    selected = topic;
+    for (var i = 0; i < circles.length; i++) {
+      circles[i].setAttribute("class", i + 1 == topic ? "topic selected" : "topic");
+    }
+    document.getElementById("topic").textContent = topic == 0 ? "none (most salient terms)" :
+        topic + " (model topic " + (data["topic.order"][topic - 1] - 1) + ", " +
+        mds.Freq[topic - 1].toFixed(1) + "% of tokens)";
+    drawTerms();
+  }
+  document.getElementById("lambda").oninput = function() {
+    lambda = parseFloat(this.value);
+    document.getElementById("lambda-value").textContent = lambda.toFixed(2);
+    drawTerms();
+  };
+  select(0);
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lda"
+	"strings"
+)
+
+var (
+	model_file = flag.String("model_file", "", "The model file saved by train-lda")
+	corpus_file = flag.String("corpus_file", "", "The training data of model_file")
+	phrase_file = flag.String("phrase_file", "",
+		"The phrase table saved by train-lda, if model_file was trained with phrases merged")
+	html_file = flag.String("html_file", "",
+		"The (output) HTML report, which embeds the visualization and opens offline")
+	json_file = flag.String("json_file", "", "If specified, the (output) LDAvis JSON payload")
+	topic_prior = flag.Float64("topic_prior", 0.1, "The parameter of symmetric Dirichlet on topics")
+	word_prior = flag.Float64("word_prior", 0.01, "The parameter of symmetric Dirichlet on words")
+	burn_in_iterations = flag.Int("burn_in_iterations", 20,
+		"The number of Gibbs sampling iterations for burning in the inference of each document")
+	accumulate_iterations = flag.Int("accumulate_iterations", 10,
+		"The number of Gibbs sampling iterations for accumulating the inferred topic distribution")
+	num_terms = flag.Int("num_terms", 30, "The number of terms shown per topic")
+)
+
+func CheckFlagsValid() bool {
+	valid := true
+	if len(*model_file) == 0 {
+		fmt.Println("model_file must be specified")
+		valid = false
+	}
+	if len(*corpus_file) == 0 {
+		fmt.Println("corpus_file must be specified")
+		valid = false
+	}
+	if len(*html_file) == 0 && len(*json_file) == 0 {
+		fmt.Println("html_file or json_file must be specified")
+		valid = false
+	}
+	if *topic_prior <= 0 {
+		fmt.Println("topic_prior must be positive")
+		valid = false
+	}
+	if *word_prior <= 0 {
+		fmt.Println("word_prior must be positive")
+		valid = false
+	}
+	if *burn_in_iterations < 0 {
+		fmt.Println("burn_in_iterations must be non-negative")
+		valid = false
+	}
+	if *accumulate_iterations <= 0 {
+		fmt.Println("accumulate_iterations must be positive")
+		valid = false
+	}
+	if *num_terms <= 0 {
+		fmt.Println("num_terms must be positive")
+		valid = false
+	}
+	return valid
+}
+
+// Infer the topic distributions of documents in corpus_file by model_file,
+// and save the LDAvis payload into json_file and the HTML report into
+// html_file.
+func main() {
+	flag.Parse()
+	if !CheckFlagsValid() {
+		fmt.Printf("Stop due to invalid flag setting.\n")
+		return
+	}
+
+	model, err := lda.LoadModel(*model_file)
+	if err != nil {
+		fmt.Printf("Error in loading: " + *model_file + ", due to " + err.String())
+		return
+	}
+	var corpus *lda.Corpus
+	if len(*phrase_file) > 0 {
+		table, err := lda.LoadPhraseTable(*phrase_file)
+		if err != nil {
+			fmt.Printf("Error in loading: " + *phrase_file + ", due to " + err.String())
+			return
+		}
+		corpus, err = lda.LoadPhrasedCorpus(*corpus_file, model.NumTopics(), table)
+	} else {
+		corpus, err = lda.LoadCorpus(*corpus_file, model.NumTopics())
+	}
+	if err != nil {
+		fmt.Printf("Error in loading: " + *corpus_file + ", due to " + err.String())
+		return
+	}
+
+	sampler := lda.NewSampler(*topic_prior, *word_prior, model, nil)
+	doc_topic_dists := make([]lda.Distribution, len(*corpus))
+	for d, doc := range *corpus {
+		doc_topic_dists[d] = sampler.InferTopicDistribution(doc,
+			*burn_in_iterations, *accumulate_iterations)
+	}
+	payload, err := lda.NewLDAvis(model, corpus, doc_topic_dists, *word_prior, *num_terms).JSON()
+	if err != nil {
+		fmt.Printf("Cannot encode the visualization due to " + err.String())
+		return
+	}
+
+	if len(*json_file) > 0 {
+		if err := lda.SaveFile(*json_file, string(payload)); err != nil {
+			fmt.Printf("Cannot save JSON due to " + err.String())
+			return
+		}
+	}
+	if len(*html_file) > 0 {
+		// Prevent the payload from closing the script element.
+		data := strings.Replace(string(payload), "</", "<\\/", -1)
+		title := "Topics of " + *model_file
+		html := strings.Replace(kHTMLTemplate, kHTMLTitlePlaceholder, lda.EscapeHTML(title), -1)
+		html = strings.Replace(html, kHTMLDataPlaceholder, data, 1)
+		if err := lda.SaveFile(*html_file, html); err != nil {
+			fmt.Printf("Cannot save HTML report due to " + err.String())
+			return
+		}
+	}
+}